@@ -0,0 +1,134 @@
+// Package cache stores the summaries of files on disk, so that the language server does not need to parse the files
+// that have not changed since it last ran.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/tliron/commonlog"
+)
+
+// formatVersion is incremented whenever the format of the entries changes, so that old entries are ignored.
+const formatVersion = "v3"
+
+// Disk is a cache in a directory. Every file has a single entry, named after the hash of its path, which is replaced
+// when the file changes. It implements types.Cache.
+type Disk struct {
+	dir     string
+	version string
+	log     commonlog.Logger
+}
+
+// entry is the contents of the file of a cached file.
+type entry struct {
+	Version string // The version of luapls that wrote the entry
+	Path    string
+	Hash    string // The hash of the contents of the file
+	Summary *types.Summary
+}
+
+// Dir returns the default directory of the cache: `luapls` in the user cache directory.
+func Dir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "luapls"), nil
+}
+
+// Version returns the version of luapls from the information embedded in the executable. Development builds include
+// the revision that they were built from, so that the cache is invalidated when the analysis changes.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version += "+" + setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				version += "-dirty"
+			}
+		}
+	}
+	return version
+}
+
+// Open returns the cache in the given directory for the given version of luapls. Entries that were written by other
+// versions are ignored.
+func Open(dir string, version string) *Disk {
+	return &Disk{
+		dir:     filepath.Join(dir, formatVersion),
+		version: version,
+		log:     commonlog.GetLogger("luapls.cache"),
+	}
+}
+
+// Clean removes the cache directory and everything in it.
+func Clean(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+func (d *Disk) entryPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(d.dir, name[:2], name+".json")
+}
+
+// Load returns the summary of the file at the given path, or nil if the cache does not have one for its contents.
+func (d *Disk) Load(path string, hash string) *types.Summary {
+	data, err := os.ReadFile(d.entryPath(path))
+	if err != nil {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		d.log.Debugf("Ignoring invalid cache entry for %s: %s", path, err)
+		return nil
+	}
+	if e.Version != d.version || e.Path != path || e.Hash != hash || e.Summary == nil {
+		return nil
+	}
+	return e.Summary
+}
+
+// Store replaces the summary of the file at the given path. Errors are logged rather than returned, since the cache
+// only makes starting up faster.
+func (d *Disk) Store(path string, hash string, summary *types.Summary) {
+	data, err := json.Marshal(entry{Version: d.version, Path: path, Hash: hash, Summary: summary})
+	if err != nil {
+		d.log.Warningf("Failed to encode cache entry for %s: %s", path, err)
+		return
+	}
+	target := d.entryPath(path)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		d.log.Warningf("Failed to create cache directory: %s", err)
+		return
+	}
+	// The entry is written to a temporary file first so that other servers never read a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		d.log.Warningf("Failed to write cache entry for %s: %s", path, err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		d.log.Warningf("Failed to write cache entry for %s: %s", path, err)
+	}
+}
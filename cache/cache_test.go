@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"os"
+	"testing"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	summary := &types.Summary{
+		LineBreaks:  token.LineBreaks{4, 9},
+		Globals:     []string{"helper"},
+		Diagnostics: []ast.Diagnostic{{Message: "Undefined global 'x'", Range: token.Range{Start: 1, End: 2}, Code: "undefined-global"}},
+		Fingerprint: "fingerprint",
+	}
+	disk := Open(dir, "1.0.0")
+	assert.Nil(t, disk.Load("/a.lua", "hash"))
+	disk.Store("/a.lua", "hash", summary)
+	assert.Equal(t, summary, disk.Load("/a.lua", "hash"))
+
+	tests := []struct {
+		label string
+		cache *Disk
+		path  string
+		hash  string
+	}{
+		{"changed contents", disk, "/a.lua", "other"},
+		{"other path", disk, "/b.lua", "hash"},
+		{"other version", Open(dir, "1.0.1"), "/a.lua", "hash"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			assert.Nil(t, test.cache.Load(test.path, test.hash))
+		})
+	}
+
+	// Storing the summary of new contents replaces the entry
+	disk.Store("/a.lua", "new", &types.Summary{})
+	assert.Nil(t, disk.Load("/a.lua", "hash"))
+	assert.NotNil(t, disk.Load("/a.lua", "new"))
+
+	require.NoError(t, Clean(dir))
+	assert.Nil(t, disk.Load("/a.lua", "new"))
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskInvalidEntry(t *testing.T) {
+	disk := Open(t.TempDir(), "1.0.0")
+	disk.Store("/a.lua", "hash", &types.Summary{})
+	path := disk.entryPath("/a.lua")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	assert.Nil(t, disk.Load("/a.lua", "hash"))
+}
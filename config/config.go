@@ -0,0 +1,240 @@
+// Package config reads the project configuration and applies it to an environment. The same configuration is used
+// by the language server, where it may also come from the editor, and by the command line.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/raiguard/luapls/lua/lint"
+	"github.com/raiguard/luapls/lua/stdlib"
+	"github.com/raiguard/luapls/lua/types"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// FileNames are the names of the project configuration files, in order of preference. `.luarc.json` files use the
+// schema of lua-language-server.
+var FileNames = []string{".luapls.json", ".luarc.json"}
+
+// Config holds every setting. Settings that are nil were not configured, so that configurations can be merged.
+type Config struct {
+	Roots       *[]string `json:"roots"`
+	PackagePath *[]string `json:"packagePath"` // Templates for resolving `require`, such as `?.lua` and `?/init.lua`
+	LuaVersion  *string   `json:"luaVersion"`  // One of 5.1, 5.2, 5.3, 5.4 or LuaJIT
+	Library     *[]string `json:"library"`     // Directories of definition files that never report diagnostics
+	Ignore      *[]string `json:"ignore"`      // Glob patterns of files and directories to skip
+
+	Globals       *[]string `json:"globals"`       // Globals that are defined outside of the workspace
+	StrictGlobals *bool     `json:"strictGlobals"` // Warn when assigning to globals that are not in Globals
+
+	Severity *map[string]string `json:"severity"` // Diagnostic code or lint rule -> error, warning, information, hint or off
+
+	Hints *map[string]bool `json:"hints"` // Inlay hint -> whether it is shown: parameterNames, localTypes, implicitSelf or obvious
+}
+
+// Find returns the path of the project configuration file in the directory, or an empty string if there is none.
+func Find(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads the configuration file at the given path. Files named `.luarc.json` are read with the schema of
+// lua-language-server, and every other file with the schema of Config.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if filepath.Base(path) == ".luarc.json" {
+		return ParseLuarc(data)
+	}
+	return Parse(data)
+}
+
+// AddGlobal adds the global to the allowed globals in the project configuration file of the directory, creating a
+// `.luapls.json` file if there is none, and returns the path of the file. Other settings in the file are kept, though
+// their formatting is not.
+func AddGlobal(dir string, name string) (string, error) {
+	path := Find(dir)
+	data := []byte("{}")
+	if path == "" {
+		path = filepath.Join(dir, FileNames[0])
+	} else {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return "", err
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var settings map[string]any
+	if err := decoder.Decode(&settings); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if settings == nil {
+		settings = map[string]any{}
+	}
+
+	// Settings of `.luarc.json` may be nested or use dotted keys, and the existing style is kept
+	object, key := settings, "globals"
+	if filepath.Base(path) == ".luarc.json" {
+		key = "diagnostics.globals"
+		if _, dotted := settings[key]; !dotted {
+			diagnostics, ok := settings["diagnostics"].(map[string]any)
+			if !ok && settings["diagnostics"] == nil {
+				diagnostics, ok = map[string]any{}, true
+				settings["diagnostics"] = diagnostics
+			}
+			if ok {
+				object, key = diagnostics, "globals"
+			}
+		}
+	}
+	globals, _ := object[key].([]any)
+	for _, global := range globals {
+		if global == name {
+			return path, nil
+		}
+	}
+	object[key] = append(globals, name)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "\t")
+	if err := encoder.Encode(settings); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Parse reads a configuration in the schema of Config.
+func Parse(data []byte) (Config, error) {
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Merge returns a copy of the configuration with every setting that is configured in other replacing its own.
+func (c Config) Merge(other Config) Config {
+	merge(&c.Roots, other.Roots)
+	merge(&c.PackagePath, other.PackagePath)
+	merge(&c.LuaVersion, other.LuaVersion)
+	merge(&c.Library, other.Library)
+	merge(&c.Ignore, other.Ignore)
+	merge(&c.Globals, other.Globals)
+	merge(&c.StrictGlobals, other.StrictGlobals)
+	merge(&c.Severity, other.Severity)
+	merge(&c.Hints, other.Hints)
+	return c
+}
+
+func merge[T any](dst **T, src *T) {
+	if src != nil {
+		*dst = src
+	}
+}
+
+// Apply passes the configuration on to the environment, and returns a warning for every invalid setting. Settings
+// that are not configured are reset to their defaults.
+func (c Config) Apply(env *types.Environment) []string {
+	warnings := []string{}
+	severities := parseSeverities(c.Severity, &warnings)
+	for code := range severities {
+		if !isCode(code) {
+			warnings = append(warnings, fmt.Sprintf("Unknown diagnostic code '%s'", code))
+		}
+	}
+	for name := range value(c.Hints) {
+		if hintOption(&types.HintOptions{}, name) == nil {
+			warnings = append(warnings, fmt.Sprintf("Unknown inlay hint '%s'", name))
+		}
+	}
+	env.Configure(func() {
+		env.Roots = value(c.Roots)
+		env.PackagePath = value(c.PackagePath)
+		env.Library = value(c.Library)
+		env.Ignore = value(c.Ignore)
+		env.AllowedGlobals = value(c.Globals)
+		env.StrictGlobals = c.StrictGlobals != nil && *c.StrictGlobals
+		env.Severities = severities
+		env.Linter = &lint.Linter{}
+	})
+
+	version := types.DefaultLuaVersion
+	if c.LuaVersion != nil {
+		if stdlib.IsVersion(*c.LuaVersion) {
+			version = *c.LuaVersion
+		} else {
+			warnings = append(warnings, fmt.Sprintf("Unknown Lua version '%s', using %s", *c.LuaVersion, version))
+		}
+	}
+	env.SetLuaVersion(version)
+	return warnings
+}
+
+// isCode returns whether luapls reports diagnostics with the given code. Every lint rule has its own code.
+func isCode(code string) bool {
+	return lint.Lookup(code) != nil || code == types.CodeUnusedSuppression
+}
+
+// HintOptions returns which inlay hints are shown. Hints that are not configured keep their defaults.
+func (c Config) HintOptions() types.HintOptions {
+	opts := types.DefaultHintOptions
+	for name, enabled := range value(c.Hints) {
+		if option := hintOption(&opts, name); option != nil {
+			*option = enabled
+		}
+	}
+	return opts
+}
+
+// hintOption returns the option of the inlay hint with the given name, or nil if there is no such hint.
+func hintOption(opts *types.HintOptions, name string) *bool {
+	switch name {
+	case "parameterNames":
+		return &opts.ParameterNames
+	case "localTypes":
+		return &opts.LocalTypes
+	case "implicitSelf":
+		return &opts.ImplicitSelf
+	case "obvious":
+		return &opts.Obvious
+	}
+	return nil
+}
+
+// value returns the value that the pointer points to, or the zero value if it is nil.
+func value[T any](ptr *T) T {
+	var zero T
+	if ptr == nil {
+		return zero
+	}
+	return *ptr
+}
+
+// parseSeverities converts a map of diagnostic codes to severity names, skipping unknown severities.
+func parseSeverities(names *map[string]string, warnings *[]string) map[string]protocol.DiagnosticSeverity {
+	severities := map[string]protocol.DiagnosticSeverity{}
+	if names == nil {
+		return severities
+	}
+	for code, name := range *names {
+		if severity, ok := types.ParseSeverity(name); ok {
+			severities[code] = severity
+		} else {
+			*warnings = append(*warnings, fmt.Sprintf("Unknown severity '%s' for '%s'", name, code))
+		}
+	}
+	return severities
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/raiguard/luapls/lua/lint"
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/raiguard/luapls/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestParse(t *testing.T) {
+	config, err := Parse([]byte(`{"luaVersion": "5.1", "globals": ["love"], "severity": {"duplicate-key": "error"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, util.Ptr("5.1"), config.LuaVersion)
+	assert.Equal(t, &[]string{"love"}, config.Globals)
+	assert.Equal(t, &map[string]string{"duplicate-key": "error"}, config.Severity)
+	assert.Nil(t, config.Roots)
+}
+
+func TestParseLuarc(t *testing.T) {
+	tests := []struct {
+		label string
+		input string
+	}{
+		{"nested", `{
+			"runtime": {"version": "Lua 5.3", "path": ["?.lua", "?/init.lua"]},
+			"workspace": {"library": ["types"], "ignoreDir": ["vendor"]},
+			"diagnostics": {
+				"globals": ["vim"],
+				"disable": ["unused-local", "shadowed-global", "lowercase-global"],
+				"severity": {"undefined-global": "Error!", "duplicate-key": "Hint"}
+			},
+			"format": {"defaultConfig": {"indent_style": "space", "indent_size": 2}},
+			"hint": {"enable": true, "paramName": "Disable", "setType": true}
+		}`},
+		{"dotted", `{
+			"$schema": "https://raw.githubusercontent.com/LuaLS/vscode-lua/master/setting/schema.json",
+			"runtime.version": "Lua 5.3",
+			"runtime.path": ["?.lua", "?/init.lua"],
+			"workspace.library": ["types"],
+			"workspace.ignoreDir": ["vendor"],
+			"diagnostics.globals": ["vim"],
+			"diagnostics.disable": ["unused-local", "shadowed-global", "lowercase-global"],
+			"diagnostics.severity": {"undefined-global": "Error!", "duplicate-key": "Hint"},
+			"format.defaultConfig": {"indent_style": "space", "indent_size": 2},
+			"hint.paramName": "Disable",
+			"hint.setType": true
+		}`},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			config, err := ParseLuarc([]byte(test.input))
+			require.NoError(t, err)
+			assert.Equal(t, util.Ptr("5.3"), config.LuaVersion)
+			assert.Equal(t, &[]string{"?.lua", "?/init.lua"}, config.PackagePath)
+			assert.Equal(t, &[]string{"types"}, config.Library)
+			assert.Equal(t, &[]string{"vendor"}, config.Ignore)
+			assert.Equal(t, &[]string{"vim"}, config.Globals)
+			severity := map[string]string{"undefined-global": "error", "unused-local": "off", "duplicate-key": "hint", "shadowed-global": "off"}
+			assert.Equal(t, &severity, config.Severity)
+			assert.Equal(t, &map[string]bool{"parameterNames": false, "localTypes": true}, config.Hints)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := Config{LuaVersion: util.Ptr("5.1"), Globals: &[]string{"a"}}
+	merged := base.Merge(Config{Globals: &[]string{"b"}, StrictGlobals: util.Ptr(true)})
+	assert.Equal(t, util.Ptr("5.1"), merged.LuaVersion)
+	assert.Equal(t, &[]string{"b"}, merged.Globals)
+	assert.Equal(t, util.Ptr(true), merged.StrictGlobals)
+	assert.Equal(t, &[]string{"a"}, base.Globals)
+}
+
+func TestApply(t *testing.T) {
+	env := types.NewEnvironment()
+	config := Config{
+		LuaVersion: util.Ptr("5.9"),
+		Globals:    &[]string{"love"},
+		Ignore:     &[]string{"vendor"},
+		Severity:   &map[string]string{"undefined-global": "error", "unused-local": "loud", "duplicate-key": "off", "no-such-rule": "warning"},
+		Hints:      &map[string]bool{"obvious": true, "everything": true},
+	}
+	warnings := config.Apply(env)
+	assert.ElementsMatch(t, []string{
+		"Unknown severity 'loud' for 'unused-local'",
+		"Unknown diagnostic code 'no-such-rule'",
+		"Unknown inlay hint 'everything'",
+		"Unknown Lua version '5.9', using " + types.DefaultLuaVersion,
+	}, warnings)
+	assert.Equal(t, types.DefaultLuaVersion, env.LuaVersion)
+	assert.Equal(t, []string{"love"}, env.AllowedGlobals)
+	assert.Equal(t, []string{"vendor"}, env.Ignore)
+	assert.Equal(t, map[string]protocol.DiagnosticSeverity{
+		"undefined-global": protocol.DiagnosticSeverityError,
+		"duplicate-key":    types.SeverityOff,
+		"no-such-rule":     protocol.DiagnosticSeverityWarning,
+	}, env.Severities)
+	assert.IsType(t, &lint.Linter{}, env.Linter)
+
+	// Settings that are no longer configured are reset
+	assert.Empty(t, Config{}.Apply(env))
+	assert.Nil(t, env.AllowedGlobals)
+	assert.Empty(t, env.Severities)
+}
+
+func TestHintOptions(t *testing.T) {
+	assert.Equal(t, types.DefaultHintOptions, Config{}.HintOptions())
+	config := Config{Hints: &map[string]bool{"localTypes": false, "obvious": true, "everything": false}}
+	assert.Equal(t, types.HintOptions{ParameterNames: true, ImplicitSelf: true, Obvious: true}, config.HintOptions())
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, "", Find(dir))
+	luarc := filepath.Join(dir, ".luarc.json")
+	require.NoError(t, os.WriteFile(luarc, []byte(`{"diagnostics.globals": ["vim"]}`), 0644))
+	assert.Equal(t, luarc, Find(dir))
+	config, err := Load(luarc)
+	require.NoError(t, err)
+	assert.Equal(t, &[]string{"vim"}, config.Globals)
+
+	luapls := filepath.Join(dir, ".luapls.json")
+	require.NoError(t, os.WriteFile(luapls, []byte(`{"globals": ["love"]}`), 0644))
+	assert.Equal(t, luapls, Find(dir))
+	config, err = Load(luapls)
+	require.NoError(t, err)
+	assert.Equal(t, &[]string{"love"}, config.Globals)
+}
+
+func TestAddGlobal(t *testing.T) {
+	tests := []struct {
+		label    string
+		name     string // The name of the existing configuration file, or empty if there is none
+		input    string
+		expected string
+	}{
+		{"no file", "", "", `{"globals":["love"]}`},
+		{"luapls", ".luapls.json", `{"luaVersion": "5.1", "globals": ["vim"]}`, `{"globals":["vim","love"],"luaVersion":"5.1"}`},
+		{"already allowed", ".luapls.json", `{"globals": ["love"]}`, `{"globals":["love"]}`},
+		{"nested luarc", ".luarc.json", `{"diagnostics": {"disable": ["unused-local"]}}`, `{"diagnostics":{"disable":["unused-local"],"globals":["love"]}}`},
+		{"dotted luarc", ".luarc.json", `{"diagnostics.globals": ["vim"], "runtime.version": "Lua 5.1"}`, `{"diagnostics.globals":["vim","love"],"runtime.version":"Lua 5.1"}`},
+		{"empty luarc", ".luarc.json", `{"workspace.library": ["types"]}`, `{"diagnostics":{"globals":["love"]},"workspace.library":["types"]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			dir := t.TempDir()
+			expectedPath := filepath.Join(dir, ".luapls.json")
+			if test.name != "" {
+				expectedPath = filepath.Join(dir, test.name)
+				require.NoError(t, os.WriteFile(expectedPath, []byte(test.input), 0644))
+			}
+			path, err := AddGlobal(dir, "love")
+			require.NoError(t, err)
+			assert.Equal(t, expectedPath, path)
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(data))
+			config, err := Load(path)
+			require.NoError(t, err)
+			assert.Contains(t, value(config.Globals), "love")
+		})
+	}
+}
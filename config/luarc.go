@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ParseLuarc reads a `.luarc.json` configuration in the schema of lua-language-server. Its settings may be nested,
+// such as `{"runtime": {"version": "Lua 5.4"}}`, or use dotted keys, such as `{"runtime.version": "Lua 5.4"}`.
+// Settings that luapls does not support are ignored.
+func ParseLuarc(data []byte) (Config, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Config{}, err
+	}
+	settings := map[string]any{}
+	flatten("", raw, settings)
+
+	config := Config{}
+	if version, ok := settings["runtime.version"].(string); ok {
+		version = strings.TrimPrefix(version, "Lua ")
+		config.LuaVersion = &version
+	}
+	config.PackagePath = stringList(settings["runtime.path"])
+	config.Library = stringList(settings["workspace.library"])
+	config.Ignore = stringList(settings["workspace.ignoreDir"])
+	config.Globals = stringList(settings["diagnostics.globals"])
+
+	severity := map[string]string{}
+	// lua-language-server reports many diagnostics that luapls does not, which are left out
+	set := func(code string, name string) {
+		if isCode(code) {
+			severity[code] = name
+		}
+	}
+	if severities, ok := settings["diagnostics.severity"].(map[string]any); ok {
+		for code, name := range severities {
+			if name, ok := name.(string); ok {
+				// lua-language-server uses a trailing `!` to apply the severity to files that are not open, which luapls always does
+				set(code, strings.ToLower(strings.TrimSuffix(name, "!")))
+			}
+		}
+	}
+	if disabled := stringList(settings["diagnostics.disable"]); disabled != nil {
+		for _, code := range *disabled {
+			set(code, "off")
+		}
+	}
+	if len(severity) > 0 {
+		config.Severity = &severity
+	}
+
+	hints := map[string]bool{}
+	if paramName, ok := settings["hint.paramName"].(string); ok {
+		hints["parameterNames"] = paramName != "Disable"
+	}
+	if setType, ok := settings["hint.setType"].(bool); ok {
+		hints["localTypes"] = setType
+	}
+	// Disabling hints turns off every hint, whatever the other hint settings are
+	if enable, ok := settings["hint.enable"].(bool); ok && !enable {
+		hints = map[string]bool{"parameterNames": false, "localTypes": false, "implicitSelf": false}
+	}
+	if len(hints) > 0 {
+		config.Hints = &hints
+	}
+	return config, nil
+}
+
+// flatten adds every setting of the object to settings, joining the keys of nested objects with dots. Objects are
+// also added under their own key, so that settings whose values are objects can be read.
+func flatten(prefix string, object map[string]any, settings map[string]any) {
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		settings[key] = value
+		if nested, ok := value.(map[string]any); ok {
+			flatten(key, nested, settings)
+		}
+	}
+}
+
+// stringList returns the value as a list of strings, or nil if it is not a list. Elements that are not strings are
+// skipped.
+func stringList(value any) *[]string {
+	list, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+	result := []string{}
+	for _, element := range list {
+		if s, ok := element.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return &result
+}
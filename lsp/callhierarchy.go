@@ -0,0 +1,103 @@
+package lsp
+
+import (
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/raiguard/luapls/util"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func (s *Server) textDocumentPrepareCallHierarchy(ctx *glsp.Context, params *protocol.CallHierarchyPrepareParams) ([]protocol.CallHierarchyItem, error) {
+	file := s.getFile(ctx, params.TextDocument.URI)
+	if file == nil || file.Block == nil {
+		return nil, nil
+	}
+	fn := s.environment.FunctionAt(file, file.LineBreaks.ToPos(params.Position))
+	if fn == nil {
+		return nil, nil
+	}
+	return []protocol.CallHierarchyItem{callHierarchyItem(fn)}, nil
+}
+
+func (s *Server) callHierarchyIncomingCalls(ctx *glsp.Context, params *protocol.CallHierarchyIncomingCallsParams) ([]protocol.CallHierarchyIncomingCall, error) {
+	fn := s.callHierarchyFunction(ctx, params.Item)
+	if fn == nil {
+		return nil, nil
+	}
+	// Calls can be made from any file, so every file has to be parsed
+	reqCtx := s.rpc.context(ctx)
+	select {
+	case <-s.indexed:
+	case <-reqCtx.Done():
+		return nil, nil
+	}
+	calls, err := s.environment.IncomingCalls(reqCtx, fn)
+	if err != nil {
+		return nil, nil
+	}
+	results := []protocol.CallHierarchyIncomingCall{}
+	indices := map[*types.Callable]int{}
+	for _, call := range calls {
+		rng := call.Caller.File.LineBreaks.ToProtocolRange(call.Range)
+		if i, ok := indices[call.Caller]; ok {
+			results[i].FromRanges = append(results[i].FromRanges, rng)
+			continue
+		}
+		indices[call.Caller] = len(results)
+		results = append(results, protocol.CallHierarchyIncomingCall{From: callHierarchyItem(call.Caller), FromRanges: []protocol.Range{rng}})
+	}
+	return results, nil
+}
+
+func (s *Server) callHierarchyOutgoingCalls(ctx *glsp.Context, params *protocol.CallHierarchyOutgoingCallsParams) ([]protocol.CallHierarchyOutgoingCall, error) {
+	fn := s.callHierarchyFunction(ctx, params.Item)
+	if fn == nil {
+		return nil, nil
+	}
+	results := []protocol.CallHierarchyOutgoingCall{}
+	indices := map[*types.Callable]int{}
+	for _, call := range s.environment.OutgoingCalls(fn) {
+		rng := fn.File.LineBreaks.ToProtocolRange(call.Range)
+		if i, ok := indices[call.Callee]; ok {
+			results[i].FromRanges = append(results[i].FromRanges, rng)
+			continue
+		}
+		indices[call.Callee] = len(results)
+		results = append(results, protocol.CallHierarchyOutgoingCall{To: callHierarchyItem(call.Callee), FromRanges: []protocol.Range{rng}})
+	}
+	return results, nil
+}
+
+// callHierarchyFunction returns the function of an item that was returned by an earlier request, or nil if it no
+// longer exists.
+func (s *Server) callHierarchyFunction(ctx *glsp.Context, item protocol.CallHierarchyItem) *types.Callable {
+	file := s.getFile(ctx, item.URI)
+	if file == nil || file.Block == nil {
+		return nil
+	}
+	if item.Kind == protocol.SymbolKindFile {
+		return types.MainChunk(file)
+	}
+	return s.environment.FunctionAt(file, file.LineBreaks.ToPos(item.SelectionRange.Start))
+}
+
+// callHierarchyItem describes the function, which is shown as a file if it is the main chunk.
+func callHierarchyItem(fn *types.Callable) protocol.CallHierarchyItem {
+	item := protocol.CallHierarchyItem{
+		Name:           fn.Name,
+		Kind:           protocol.SymbolKindFunction,
+		URI:            fn.File.URI,
+		Range:          fn.File.LineBreaks.ToProtocolRange(fn.Range()),
+		SelectionRange: fn.File.LineBreaks.ToProtocolRange(fn.NameRange()),
+	}
+	switch {
+	case fn.Node == nil:
+		item.Kind = protocol.SymbolKindFile
+	case fn.IsMethod():
+		item.Kind = protocol.SymbolKindMethod
+	}
+	if fn.Type != nil {
+		item.Detail = util.Ptr(fn.Type.String())
+	}
+	return item
+}
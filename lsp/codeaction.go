@@ -0,0 +1,294 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/raiguard/luapls/config"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/refactor"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+const commandAllowGlobal = "luapls.allowGlobal"
+
+// commands are the commands that the server can execute through workspace/executeCommand.
+var commands = []string{commandAllowGlobal}
+
+func (s *Server) textDocumentCodeAction(ctx *glsp.Context, params *protocol.CodeActionParams) (any, error) {
+	file := s.getFile(ctx, params.TextDocument.URI)
+	if file == nil || file.Block == nil {
+		return nil, nil
+	}
+	actions := []protocol.CodeAction{}
+	for _, diagnostic := range params.Context.Diagnostics {
+		data := decodeDiagnosticData(diagnostic)
+		for _, fix := range data.Fixes {
+			actions = append(actions, quickFix(fix.Title, diagnostic, file.URI, fix.Edits...))
+		}
+		code := data.Code
+		if code == "" {
+			code = diagnosticCode(diagnostic)
+		}
+		if code == "" {
+			continue
+		}
+		actions = append(actions, codeFixes(file, diagnostic, code)...)
+		actions = append(actions, suppressAction(file, diagnostic, code))
+	}
+	a := s.environment.Analysis(file)
+	rng := token.Range{Start: a.File.LineBreaks.ToPos(params.Range.Start), End: a.File.LineBreaks.ToPos(params.Range.End)}
+	refactors := []refactor.Action{}
+	if rng.Start < rng.End && wantsKind(params.Context.Only, protocol.CodeActionKindRefactorExtract) {
+		refactors = append(refactors, refactor.ExtractLocal(a.File, a, rng)...)
+		refactors = append(refactors, refactor.ExtractFunction(a.File, a, rng)...)
+	}
+	if wantsKind(params.Context.Only, protocol.CodeActionKindRefactorInline) {
+		refactors = append(refactors, refactor.InlineLocal(a.File, a, rng.Start)...)
+		refactors = append(refactors, refactor.InlineFunction(a.File, a, rng.Start)...)
+	}
+	if wantsKind(params.Context.Only, protocol.CodeActionKindRefactorRewrite) {
+		refactors = append(refactors, refactor.Rewrites(a.File, a, rng.Start)...)
+	}
+	for _, action := range refactors {
+		actions = append(actions, refactorAction(a.File, action))
+	}
+	return actions, nil
+}
+
+// wantsKind returns whether the client asked for code actions of the given kind. An empty list asks for every kind,
+// and a kind such as `refactor` includes its subkinds, such as `refactor.extract`.
+func wantsKind(only []protocol.CodeActionKind, kind protocol.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, wanted := range only {
+		if kind == wanted || strings.HasPrefix(string(kind), string(wanted)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// refactorAction converts a refactoring to a code action.
+func refactorAction(file *ast.File, action refactor.Action) protocol.CodeAction {
+	edits := make([]protocol.TextEdit, len(action.Edits))
+	for i, edit := range action.Edits {
+		edits[i] = protocol.TextEdit{Range: file.LineBreaks.ToProtocolRange(edit.Range), NewText: edit.NewText}
+	}
+	return protocol.CodeAction{
+		Title: action.Title,
+		Kind:  &action.Kind,
+		Edit: &protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentUri][]protocol.TextEdit{file.URI: edits},
+		},
+	}
+}
+
+// codeFixes returns the code actions that fix a diagnostic with the given code by changing the declaration it is
+// reported on.
+func codeFixes(file *ast.File, diagnostic protocol.Diagnostic, code string) []protocol.CodeAction {
+	nodePath := ast.GetSemanticNode(file.Block, file.LineBreaks.ToPos(diagnostic.Range.Start))
+	ident, ok := nodePath.Node.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	actions := []protocol.CodeAction{}
+	switch code {
+	case types.CodeUndefinedGlobal:
+		actions = append(actions, allowGlobalAction(diagnostic, ident))
+	case types.CodeNewGlobal:
+		actions = append(actions, allowGlobalAction(diagnostic, ident))
+		if pos, ok := localInsertPos(nodePath, ident); ok {
+			rng := file.LineBreaks.ToProtocolRange(token.Range{Start: pos, End: pos})
+			title := fmt.Sprintf("Convert '%s' to a local", ident.Token.Literal)
+			actions = append(actions, quickFix(title, diagnostic, file.URI, protocol.TextEdit{Range: rng, NewText: "local "}))
+		}
+	case types.CodeUnusedLocal, types.CodeUnusedFunction:
+		if stmt := removableDeclaration(nodePath, ident); stmt != nil {
+			rng := file.LineBreaks.ToProtocolRange(refactor.RemovalRange(file, ast.Range(stmt)))
+			title := fmt.Sprintf("Remove unused '%s'", ident.Token.Literal)
+			actions = append(actions, quickFix(title, diagnostic, file.URI, protocol.TextEdit{Range: rng, NewText: ""}))
+		}
+		fallthrough
+	case types.CodeUnusedParameter:
+		rng := file.LineBreaks.ToProtocolRange(ast.Range(ident))
+		title := fmt.Sprintf("Rename '%s' to '_'", ident.Token.Literal)
+		actions = append(actions, quickFix(title, diagnostic, file.URI, protocol.TextEdit{Range: rng, NewText: "_"}))
+	}
+	return actions
+}
+
+// suppressAction returns a code action that disables the diagnostic on its line with a
+// `---@diagnostic disable-next-line` comment above it.
+func suppressAction(file *ast.File, diagnostic protocol.Diagnostic, code string) protocol.CodeAction {
+	line := diagnostic.Range.Start.Line
+	start := protocol.Position{Line: line, Character: 0}
+	comment := fmt.Sprintf("%s---@diagnostic disable-next-line: %s\n", lineIndentation(file, line), code)
+	title := fmt.Sprintf("Disable '%s' on this line", code)
+	return quickFix(title, diagnostic, file.URI, protocol.TextEdit{Range: protocol.Range{Start: start, End: start}, NewText: comment})
+}
+
+// lineIndentation returns the whitespace before the first node on the given line.
+func lineIndentation(file *ast.File, line protocol.UInteger) string {
+	if int(line) >= len(file.LineBreaks) {
+		return ""
+	}
+	lineStart := file.LineBreaks.ToPos(protocol.Position{Line: line, Character: 0})
+	lineEnd := file.LineBreaks[line]
+	var first ast.Node
+	ast.WalkSemantic(file.Block, func(node ast.Node) bool {
+		if node.End() <= lineStart || node.Pos() >= lineEnd {
+			return false
+		}
+		if node.Pos() >= lineStart && (first == nil || node.Pos() < first.Pos()) {
+			first = node
+		}
+		return true
+	})
+	if first == nil {
+		return ""
+	}
+	trivia := first.GetLeadingTrivia()
+	if len(trivia) == 0 {
+		return ""
+	}
+	last := trivia[len(trivia)-1]
+	if last.Type != token.WHITESPACE || last.End() != first.Pos() || strings.ContainsAny(last.Literal, "\r\n") {
+		return ""
+	}
+	return last.Literal
+}
+
+// quickFix returns a code action that fixes the given diagnostic by editing a single file.
+func quickFix(title string, diagnostic protocol.Diagnostic, uri protocol.DocumentUri, edits ...protocol.TextEdit) protocol.CodeAction {
+	kind := protocol.CodeActionKindQuickFix
+	return protocol.CodeAction{
+		Title:       title,
+		Kind:        &kind,
+		Diagnostics: []protocol.Diagnostic{diagnostic},
+		Edit: &protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentUri][]protocol.TextEdit{uri: edits},
+		},
+	}
+}
+
+// allowGlobalAction returns a code action that adds the global to the allowed globals in the project configuration
+// file.
+func allowGlobalAction(diagnostic protocol.Diagnostic, ident *ast.Identifier) protocol.CodeAction {
+	kind := protocol.CodeActionKindQuickFix
+	title := fmt.Sprintf("Add '%s' to allowed globals", ident.Token.Literal)
+	return protocol.CodeAction{
+		Title:       title,
+		Kind:        &kind,
+		Diagnostics: []protocol.Diagnostic{diagnostic},
+		Command: &protocol.Command{
+			Title:     title,
+			Command:   commandAllowGlobal,
+			Arguments: []any{ident.Token.Literal},
+		},
+	}
+}
+
+// removableDeclaration returns the statement that declares ident if it can be removed without changing the behavior
+// of the program: it declares nothing else, and its values do not call any functions.
+func removableDeclaration(nodePath ast.NodePath, ident *ast.Identifier) ast.Statement {
+	switch stmt := enclosingStatement(nodePath).(type) {
+	case *ast.FunctionStatement:
+		if stmt.LocalTok != nil && stmt.Name == ident {
+			return stmt
+		}
+	case *ast.LocalStatement:
+		if len(stmt.Names.Pairs) != 1 || stmt.Names.Pairs[0].Node != ident {
+			return nil
+		}
+		if stmt.Exps != nil && hasCalls(stmt.Exps) {
+			return nil
+		}
+		return stmt
+	}
+	return nil
+}
+
+// hasCalls returns whether evaluating the node may call a function. The bodies of function expressions are not
+// evaluated, so they are not searched.
+func hasCalls(node ast.Node) bool {
+	found := false
+	ast.WalkSemantic(node, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.FunctionCall:
+			found = true
+		case *ast.FunctionExpression:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// localInsertPos returns where `local ` must be inserted to turn the assignment of ident into a local declaration.
+// Only statements that assign to nothing but ident can be converted.
+func localInsertPos(nodePath ast.NodePath, ident *ast.Identifier) (token.Pos, bool) {
+	switch stmt := enclosingStatement(nodePath).(type) {
+	case *ast.AssignmentStatement:
+		if len(stmt.Vars.Pairs) == 1 && stmt.Vars.Pairs[0].Node == ident {
+			return stmt.Pos(), true
+		}
+	case *ast.FunctionStatement:
+		if stmt.LocalTok == nil && stmt.Name == ident {
+			return stmt.FuncTok.Pos(), true
+		}
+	}
+	return 0, false
+}
+
+// enclosingStatement returns the innermost statement that contains the node, or nil if there is none.
+func enclosingStatement(nodePath ast.NodePath) ast.Statement {
+	for i := len(nodePath.Parents) - 1; i >= 0; i-- {
+		if stmt, ok := nodePath.Parents[i].(ast.Statement); ok {
+			return stmt
+		}
+	}
+	return nil
+}
+
+// diagnosticCode returns the code of the diagnostic, or an empty string if it does not have a string code.
+func diagnosticCode(diagnostic protocol.Diagnostic) string {
+	if diagnostic.Code == nil {
+		return ""
+	}
+	code, _ := diagnostic.Code.Value.(string)
+	return code
+}
+
+func (s *Server) workspaceExecuteCommand(ctx *glsp.Context, params *protocol.ExecuteCommandParams) (any, error) {
+	switch params.Command {
+	case commandAllowGlobal:
+		if len(params.Arguments) != 1 {
+			return nil, fmt.Errorf("%s expects one argument", commandAllowGlobal)
+		}
+		name, ok := params.Arguments[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s expects a global name", commandAllowGlobal)
+		}
+		if s.environment.RootPath == "" {
+			return nil, fmt.Errorf("There is no workspace folder to allow '%s' in", name)
+		}
+		// The global is saved in the project configuration file so that it is still allowed in later sessions
+		path, err := config.AddGlobal(s.environment.RootPath, name)
+		if err != nil {
+			return nil, err
+		}
+		s.log.Infof("Added '%s' to the allowed globals in %s", name, path)
+		s.loadProjectConfig()
+		s.configMu.Lock()
+		s.applyConfig()
+		s.configMu.Unlock()
+		s.recheck(ctx)
+		return nil, nil
+	}
+	return nil, fmt.Errorf("Unknown command '%s'", params.Command)
+}
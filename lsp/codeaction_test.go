@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/raiguard/luapls/lua/parser"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestRemoveUnusedFix(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string // The unused name is at the `|`
+		code     string
+		expected string
+	}{
+		{"local", "local |x = nil\nprint(1)\n", types.CodeUnusedLocal, "print(1)\n"},
+		{"indented", "do\n\tlocal |x = 1\n\tprint(1)\nend\n", types.CodeUnusedLocal, "do\n\tprint(1)\nend\n"},
+		{"last line", "print(1)\nlocal |x = 1\n", types.CodeUnusedLocal, "print(1)\n"},
+		{"shared line", "local |x = 1 print(1)\n", types.CodeUnusedLocal, " print(1)\n"},
+		{"documented function", "print(1)\n--- Does nothing\nlocal function |f() end\nprint(2)\n", types.CodeUnusedFunction, "print(1)\nprint(2)\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			pos := strings.Index(test.input, "|")
+			require.GreaterOrEqual(t, pos, 0)
+			source := test.input[:pos] + test.input[pos+1:]
+			file := parser.New(source).ParseFile()
+			diagnostic := protocol.Diagnostic{Range: file.LineBreaks.ToProtocolRange(token.Range{Start: pos, End: pos + 1})}
+			var edits []protocol.TextEdit
+			for _, action := range codeFixes(&file, diagnostic, test.code) {
+				if strings.HasPrefix(action.Title, "Remove unused") {
+					edits = action.Edit.Changes[file.URI]
+				}
+			}
+			require.Len(t, edits, 1)
+			start, end := offset(source, edits[0].Range.Start), offset(source, edits[0].Range.End)
+			assert.Equal(t, test.expected, source[:start]+edits[0].NewText+source[end:])
+		})
+	}
+}
+
+// offset returns the byte offset of a position in the source. Unlike LineBreaks.ToPos, it accepts the position after a
+// final line break, where edits that remove the last line end.
+func offset(source string, pos protocol.Position) int {
+	lines := strings.SplitAfter(source, "\n")
+	result := 0
+	for _, line := range lines[:pos.Line] {
+		result += len(line)
+	}
+	return result + int(pos.Character)
+}
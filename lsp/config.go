@@ -3,16 +3,28 @@ package lsp
 import (
 	"encoding/json"
 
+	"github.com/raiguard/luapls/config"
+	"github.com/raiguard/luapls/lua/types"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
-type Config struct {
-	Roots *[]string `json:"roots"`
+func (s *Server) didChangeConfiguration(ctx *glsp.Context, params *protocol.DidChangeConfigurationParams) error {
+	if err := s.updateConfig(params.Settings); err != nil {
+		return err
+	}
+	s.recheck(ctx)
+	return nil
 }
 
-func (s *Server) didChangeConfiguration(ctx *glsp.Context, params *protocol.DidChangeConfigurationParams) error {
-	return s.updateConfig(params.Settings)
+// recheck checks every file again and publishes the new diagnostics.
+func (s *Server) recheck(ctx *glsp.Context) {
+	if !s.isIndexed() {
+		return
+	}
+	for _, file := range s.environment.Recheck() {
+		s.publishDiagnostics(ctx, file)
+	}
 }
 
 func (s *Server) updateConfig(settings any) error {
@@ -22,12 +34,58 @@ func (s *Server) updateConfig(settings any) error {
 		return err
 	}
 
-	var config Config
-	err = json.Unmarshal(data, &config)
+	parsed, err := config.Parse(data)
 	if err != nil {
 		return err
 	}
 
-	s.config = config
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.settings = parsed
+	s.applyConfig()
 	return nil
 }
+
+// loadProjectConfig reads the project configuration file in the root directory, if there is one. It is applied by the
+// next call to applyConfig.
+func (s *Server) loadProjectConfig() {
+	project := s.readProjectConfig()
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.project = project
+}
+
+// readProjectConfig returns the project configuration, which is empty if there is no project configuration file.
+func (s *Server) readProjectConfig() config.Config {
+	if s.environment.RootPath == "" {
+		return config.Config{}
+	}
+	path := config.Find(s.environment.RootPath)
+	if path == "" {
+		return config.Config{}
+	}
+	project, err := config.Load(path)
+	if err != nil {
+		s.log.Errorf("Failed to read %s: %s", path, err)
+		return config.Config{}
+	}
+	s.log.Infof("Using configuration file %s", path)
+	return project
+}
+
+// applyConfig merges the settings of the editor with the project configuration, and passes the result on to the
+// environment. s.configMu must be held.
+func (s *Server) applyConfig() {
+	// The project configuration file takes precedence over the settings of the editor
+	s.config = s.settings.Merge(s.project)
+	for _, warning := range s.config.Apply(s.environment) {
+		s.log.Warning(warning)
+	}
+}
+
+// hintOptions returns which inlay hints are shown by the configuration that is in effect.
+func (s *Server) hintOptions() types.HintOptions {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	return s.config.HintOptions()
+}
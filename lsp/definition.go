@@ -8,7 +8,7 @@ import (
 )
 
 func (s *Server) textDocumentDefinition(ctx *glsp.Context, params *protocol.DefinitionParams) (any, error) {
-	file := s.getFile(params.TextDocument.URI)
+	file := s.getFile(ctx, params.TextDocument.URI)
 	if file == nil {
 		return nil, nil
 	}
@@ -1,23 +1,80 @@
 package lsp
 
 import (
+	"encoding/json"
+
 	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/util"
 
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
 func (s *Server) publishDiagnostics(ctx *glsp.Context, file *ast.File) {
+	if s.environment.IsLibrary(file.URI) {
+		return
+	}
+	s.publish(ctx, file.URI, file.LineBreaks, s.environment.Report(file))
+}
+
+// publish sends the diagnostics of the file with the given URI and line breaks to the client.
+func (s *Server) publish(ctx *glsp.Context, uri protocol.URI, lineBreaks token.LineBreaks, reported []ast.Diagnostic) {
 	diagnostics := []protocol.Diagnostic{}
-	for _, err := range file.Diagnostics {
-		diagnostics = append(diagnostics, protocol.Diagnostic{
-			Range:    file.LineBreaks.ToProtocolRange(err.Range),
-			Severity: &err.Severity,
+	for _, err := range reported {
+		severity := err.Severity
+		diagnostic := protocol.Diagnostic{
+			Range:    lineBreaks.ToProtocolRange(err.Range),
+			Severity: &severity,
+			Source:   util.Ptr(LS_NAME),
 			Message:  err.Message,
-		})
+			Tags:     err.Tags,
+		}
+		if err.Code != "" {
+			diagnostic.Code = &protocol.IntegerOrString{Value: err.Code}
+		}
+		if err.Code != "" || len(err.Fixes) > 0 {
+			data := diagnosticData{Code: err.Code}
+			for _, fix := range err.Fixes {
+				edits := make([]protocol.TextEdit, len(fix.Edits))
+				for i, edit := range fix.Edits {
+					edits[i] = protocol.TextEdit{Range: lineBreaks.ToProtocolRange(edit.Range), NewText: edit.NewText}
+				}
+				data.Fixes = append(data.Fixes, fixData{Title: fix.Title, Edits: edits})
+			}
+			diagnostic.Data = data
+		}
+		diagnostics = append(diagnostics, diagnostic)
 	}
 	ctx.Notify(protocol.ServerTextDocumentPublishDiagnostics, protocol.PublishDiagnosticsParams{
-		URI:         file.URI,
+		URI:         uri,
 		Diagnostics: diagnostics,
 	})
 }
+
+// diagnosticData is stored in the data field of published diagnostics, which clients send back unchanged when they
+// request code actions. The code is repeated here because GLSP cannot decode the code field of a diagnostic.
+type diagnosticData struct {
+	Code  string    `json:"code,omitempty"`
+	Fixes []fixData `json:"fixes,omitempty"`
+}
+
+// fixData is a fix of a diagnostic whose edits have been converted to protocol ranges.
+type fixData struct {
+	Title string              `json:"title"`
+	Edits []protocol.TextEdit `json:"edits"`
+}
+
+// decodeDiagnosticData returns the data of a diagnostic that was sent back by the client.
+func decodeDiagnosticData(diagnostic protocol.Diagnostic) diagnosticData {
+	data := diagnosticData{}
+	if diagnostic.Data == nil {
+		return data
+	}
+	// The data was decoded into generic maps, so it is encoded again to decode it into the actual type
+	raw, err := json.Marshal(diagnostic.Data)
+	if err == nil {
+		json.Unmarshal(raw, &data)
+	}
+	return data
+}
@@ -4,39 +4,42 @@ import (
 	"errors"
 	"time"
 
-	"github.com/raiguard/luapls/lua/parser"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
 // TODO: Incremental changes
 func (s *Server) textDocumentDidOpen(ctx *glsp.Context, params *protocol.DidOpenTextDocumentParams) error {
-	file := s.getFile(params.TextDocument.URI)
+	// Open files are added right away, even if the workspace is still being indexed
+	file := s.environment.File(params.TextDocument.URI)
 	if file == nil {
 		file = s.environment.AddTransientFile(params.TextDocument.URI, params.TextDocument.Text)
-	}
-	if file == nil {
-		return errors.New("Error creating file")
+		if file == nil {
+			return errors.New("Error creating file")
+		}
+		for _, affected := range s.environment.CheckFile(file) {
+			if affected != file {
+				s.publishDiagnostics(ctx, affected)
+			}
+		}
 	}
 	s.publishDiagnostics(ctx, file)
 	return nil
 }
 
 func (s *Server) textDocumentDidChange(ctx *glsp.Context, params *protocol.DidChangeTextDocumentParams) error {
-	file := s.getFile(params.TextDocument.URI)
-	if file == nil {
+	if s.environment.File(params.TextDocument.URI) == nil {
 		return nil
 	}
 	for _, change := range params.ContentChanges {
 		if change, ok := change.(protocol.TextDocumentContentChangeEventWhole); ok {
 			before := time.Now()
-			newFile := parser.New(change.Text).ParseFile()
-			s.log.Debugf("Reparse duration: %s", time.Since(before).String())
-			file.Block = newFile.Block
-			file.LineBreaks = newFile.LineBreaks
-			file.Diagnostics = newFile.Diagnostics
-			s.environment.CheckFilePhase1(file)
-			s.publishDiagnostics(ctx, file)
+			// The file is replaced rather than modified, so that requests that are still using it are not affected
+			affected := s.environment.UpdateFile(params.TextDocument.URI, change.Text)
+			s.log.Debugf("Update duration: %s", time.Since(before).String())
+			for _, affected := range affected {
+				s.publishDiagnostics(ctx, affected)
+			}
 		}
 	}
 	return nil
@@ -9,7 +9,7 @@ import (
 )
 
 func (s *Server) textDocumentHighlight(ctx *glsp.Context, params *protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
-	file := s.getFile(params.TextDocument.URI)
+	file := s.getFile(ctx, params.TextDocument.URI)
 	if file == nil {
 		return nil, errors.New("File not found")
 	}
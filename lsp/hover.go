@@ -3,48 +3,138 @@ package lsp
 import (
 	"errors"
 	"fmt"
+	"strings"
 
-	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/types"
 	"github.com/raiguard/luapls/util"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
+// maxSignatureWidth is the length of the parameter list of a function beyond which each parameter is shown on its
+// own line.
+const maxSignatureWidth = 60
+
 func (s *Server) textDocumentHover(ctx *glsp.Context, params *protocol.HoverParams) (*protocol.Hover, error) {
-	file := s.getFile(params.TextDocument.URI)
+	file := s.getFile(ctx, params.TextDocument.URI)
 	if file == nil {
 		return nil, nil
 	}
 	if file.Block == nil {
-		return nil, errors.New("Attempted to highlight file with no AST")
-	}
-	nodePath := ast.GetSemanticNode(file.Block, file.LineBreaks.ToPos(params.Position))
-	if nodePath.Node == nil {
-		return nil, nil
+		return nil, errors.New("Attempted to hover a file with no AST")
 	}
-	ident, ok := nodePath.Node.(*ast.Identifier)
-	if !ok {
+	symbol := s.environment.SymbolAt(file, file.LineBreaks.ToPos(params.Position))
+	if symbol == nil {
 		return nil, nil
 	}
-	// typ, ok := file.Env.Types[ident]
-	// if !ok {
-	// 	typ = &types.Unknown{}
-	// }
-	contents := fmt.Sprintf("```lua\n(variable) %s\n```", ident.Token.Literal)
-	// comments := ident.GetComments()
-	// i := len(nodePath.Parents) - 1
-	// for comments == "" && i >= 0 {
-	// 	comments = nodePath.Parents[i].GetComments()
-	// 	if _, ok := nodePath.Parents[i].(ast.Statement); ok {
-	// 		break
-	// 	}
-	// 	i--
-	// }
-	// if comments != "" {
-	// 	contents += "\n\n" + comments
-	// }
 	return &protocol.Hover{
-		Contents: contents,
-		Range:    util.Ptr(file.LineBreaks.ToProtocolRange(ast.Range(ident))),
+		Contents: protocol.MarkupContent{Kind: protocol.MarkupKindMarkdown, Value: hoverContents(symbol)},
+		Range:    util.Ptr(file.LineBreaks.ToProtocolRange(symbol.Range)),
 	}, nil
 }
+
+// hoverContents formats the declaration and documentation of the symbol as Markdown.
+func hoverContents(symbol *types.Symbol) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "```lua\n%s\n```", declaration(symbol))
+	if symbol.Kind == types.SymbolModule && symbol.Module != "" {
+		if path, err := util.URIToPath(symbol.Module); err == nil {
+			fmt.Fprintf(&sb, "\n\n[%s](%s)", path, symbol.Module)
+		}
+	}
+	if doc := symbol.Doc; doc != nil {
+		if doc.Deprecated != nil {
+			sb.WriteString("\n\n**Deprecated**")
+			if doc.Deprecated.Message != "" {
+				sb.WriteString(": " + doc.Deprecated.Message)
+			}
+		}
+		if doc.Description != "" {
+			sb.WriteString("\n\n" + doc.Description)
+		}
+	}
+	return sb.String()
+}
+
+// declaration formats the symbol as Lua-like code, such as `(local) x: number`.
+func declaration(symbol *types.Symbol) string {
+	kind := "(" + symbol.Kind.String() + ") "
+	switch symbol.Kind {
+	case types.SymbolClass:
+		return kind + classDeclaration(symbol.Type.(*types.Named))
+	case types.SymbolAlias:
+		return kind + symbol.Name + " = " + symbol.Type.(*types.Named).Alias.String()
+	case types.SymbolModule:
+		return fmt.Sprintf("%srequire(%q): %s", kind, symbol.Name, symbol.Type)
+	}
+	name := symbol.Name
+	isMethod := symbol.Kind == types.SymbolMethod
+	if symbol.Owner != "" {
+		separator := "."
+		if isMethod {
+			separator = ":"
+		}
+		name = symbol.Owner + separator + name
+	}
+	if fn, ok := types.First(symbol.Type).(*types.Function); ok {
+		return kind + signature(name, fn, isMethod)
+	}
+	return kind + name + ": " + symbol.Type.String()
+}
+
+// classDeclaration formats a class along with the classes it inherits from and its fields.
+func classDeclaration(class *types.Named) string {
+	var sb strings.Builder
+	sb.WriteString(class.Name)
+	for i, parent := range class.Parents {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(parent.Name)
+	}
+	if class.Fields == nil || len(class.Fields.Fields) == 0 {
+		return sb.String()
+	}
+	sb.WriteString(" {\n")
+	for _, field := range class.Fields.Fields {
+		sb.WriteString("    " + field.String() + ",\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// signature formats a function with the given name, putting its parameters on separate lines if they do not fit on
+// one and each return value on its own line. The `self` parameter of methods is left out.
+func signature(name string, fn *types.Function, isMethod bool) string {
+	params := []string{}
+	for i, param := range fn.Params {
+		if i == 0 && isMethod && param.Name == "self" {
+			continue
+		}
+		params = append(params, param.String())
+	}
+	if fn.Vararg {
+		params = append(params, "...")
+	}
+	var sb strings.Builder
+	sb.WriteString("function " + name + "(")
+	if joined := strings.Join(params, ", "); len(joined) <= maxSignatureWidth {
+		sb.WriteString(joined)
+	} else {
+		sb.WriteString("\n    " + strings.Join(params, ",\n    ") + "\n")
+	}
+	sb.WriteString(")")
+	if fn.Return == nil {
+		return sb.String()
+	}
+	returns := []types.Type{fn.Return}
+	if tuple, ok := fn.Return.(*types.Tuple); ok {
+		returns = tuple.Types
+	}
+	for _, ret := range returns {
+		sb.WriteString("\n  → " + ret.String())
+	}
+	return sb.String()
+}
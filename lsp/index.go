@@ -0,0 +1,133 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/util"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// indexProgressToken identifies the progress of indexing the workspace.
+const indexProgressToken = LS_NAME + "/index"
+
+// progressInterval is the least amount of time between two reports of the progress of indexing.
+const progressInterval = 100 * time.Millisecond
+
+// index parses and checks every file in the workspace, reporting its progress to the client if it supports it, then
+// publishes the diagnostics of every file. Requests can be handled in the meantime.
+func (s *Server) index(ctx *glsp.Context) {
+	indexCtx, cancel := context.WithCancel(context.Background())
+	s.cancelIndex = cancel
+	go func() {
+		defer cancel()
+		defer close(s.indexed)
+
+		progress := s.beginProgress(ctx)
+		err := s.environment.Index(indexCtx, progress)
+		s.endProgress(ctx, err)
+		if errors.Is(err, context.Canceled) {
+			s.log.Warning("Indexing was cancelled, only the files that were parsed so far will be checked")
+		}
+
+		if errs := s.environment.DiscoveryErrors; len(errs) > 0 {
+			ctx.Notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+				Type:    protocol.MessageTypeWarning,
+				Message: fmt.Sprintf("%d errors occurred while searching the workspace for Lua files, the first was: %s", len(errs), errs[0]),
+			})
+		}
+		// Files whose diagnostics are cached are not parsed, and the others are parsed by CachedDiagnostics if needed
+		for uri, summary := range s.environment.CachedDiagnostics() {
+			s.publish(ctx, uri, summary.LineBreaks, summary.Diagnostics)
+		}
+		for _, file := range s.environment.FileList() {
+			s.publishDiagnostics(ctx, file)
+		}
+		s.environment.SaveCache()
+	}()
+}
+
+// beginProgress asks the client to show the progress of indexing, and returns the function that reports it. It
+// returns nil if the client does not support progress reports.
+func (s *Server) beginProgress(ctx *glsp.Context) func(parsed, total int) {
+	if !s.workDoneProgress {
+		return nil
+	}
+	token := protocol.ProgressToken{Value: indexProgressToken}
+	ctx.Call(protocol.ServerWindowWorkDoneProgressCreate, protocol.WorkDoneProgressCreateParams{Token: token}, nil)
+	ctx.Notify(protocol.MethodProgress, protocol.ProgressParams{
+		Token: token,
+		Value: protocol.WorkDoneProgressBegin{
+			Kind:        "begin",
+			Title:       "Indexing workspace",
+			Cancellable: util.Ptr(true),
+			Percentage:  util.Ptr(protocol.UInteger(0)),
+		},
+	})
+	last := time.Now()
+	return func(parsed, total int) {
+		if parsed < total && time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+		ctx.Notify(protocol.MethodProgress, protocol.ProgressParams{
+			Token: token,
+			Value: protocol.WorkDoneProgressReport{
+				Kind:       "report",
+				Message:    util.Ptr(fmt.Sprintf("%d/%d files", parsed, total)),
+				Percentage: util.Ptr(protocol.UInteger(parsed * 100 / total)),
+			},
+		})
+	}
+}
+
+// endProgress tells the client that indexing is done.
+func (s *Server) endProgress(ctx *glsp.Context, err error) {
+	if !s.workDoneProgress {
+		return
+	}
+	message := "Done"
+	if err != nil {
+		message = "Cancelled"
+	}
+	ctx.Notify(protocol.MethodProgress, protocol.ProgressParams{
+		Token: protocol.ProgressToken{Value: indexProgressToken},
+		Value: protocol.WorkDoneProgressEnd{Kind: "end", Message: &message},
+	})
+}
+
+func (s *Server) workDoneProgressCancel(ctx *glsp.Context, params *protocol.WorkDoneProgressCancelParams) error {
+	// The token is not decoded by GLSP, but indexing is the only progress that is reported
+	if s.cancelIndex != nil {
+		s.cancelIndex()
+	}
+	return nil
+}
+
+// isIndexed returns whether indexing the workspace is done.
+func (s *Server) isIndexed() bool {
+	select {
+	case <-s.indexed:
+		return true
+	default:
+		return false
+	}
+}
+
+// getFile returns the file with the given URI. If it has not been parsed yet because the workspace is still being
+// indexed, it waits until indexing is done or the request is cancelled.
+func (s *Server) getFile(ctx *glsp.Context, uri protocol.URI) *ast.File {
+	if file := s.environment.File(uri); file != nil {
+		return file
+	}
+	select {
+	case <-s.indexed:
+	case <-s.rpc.context(ctx).Done():
+		return nil
+	}
+	return s.environment.File(uri)
+}
@@ -0,0 +1,35 @@
+package lsp
+
+import (
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/raiguard/luapls/util"
+	"github.com/tliron/glsp"
+)
+
+func (s *Server) textDocumentInlayHint(ctx *glsp.Context, params *InlayHintParams) (any, error) {
+	file := s.getFile(ctx, params.TextDocument.URI)
+	if file == nil || file.Block == nil {
+		return nil, nil
+	}
+	rng := token.Range{Start: file.LineBreaks.ToPos(params.Range.Start), End: file.LineBreaks.ToPos(params.Range.End)}
+	// Clients may ask for the hints of a range that extends past the end of the file
+	if rng.End == token.InvalidPos {
+		rng.End = len(file.Source)
+	}
+	hints := []InlayHint{}
+	for _, hint := range s.environment.InlayHints(file, rng, s.hintOptions()) {
+		result := InlayHint{Position: file.LineBreaks.ToProtocolPos(hint.Pos), Label: hint.Label}
+		switch hint.Kind {
+		case types.HintType:
+			result.Kind = util.Ptr(InlayHintKindType)
+		case types.HintParameter:
+			result.Kind = util.Ptr(InlayHintKindParameter)
+			result.PaddingRight = true
+		case types.HintSelf:
+			result.Kind = util.Ptr(InlayHintKindParameter)
+		}
+		hints = append(hints, result)
+	}
+	return hints, nil
+}
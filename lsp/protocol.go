@@ -0,0 +1,60 @@
+package lsp
+
+import (
+	"encoding/json"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// GLSP implements version 3.16 of the protocol. The methods and types of later versions that the server supports are
+// declared here, and their messages are handled before they are passed on to GLSP.
+
+const MethodTextDocumentInlayHint = "textDocument/inlayHint"
+
+type InlayHintParams struct {
+	protocol.WorkDoneProgressParams
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Range        protocol.Range                  `json:"range"`
+}
+
+type InlayHintKind = protocol.UInteger
+
+const (
+	InlayHintKindType      InlayHintKind = 1
+	InlayHintKindParameter InlayHintKind = 2
+)
+
+type InlayHint struct {
+	Position     protocol.Position `json:"position"`
+	Label        string            `json:"label"`
+	Kind         *InlayHintKind    `json:"kind,omitempty"`
+	PaddingLeft  bool              `json:"paddingLeft,omitempty"`
+	PaddingRight bool              `json:"paddingRight,omitempty"`
+}
+
+// serverCapabilities adds the capabilities of later versions of the protocol to those that GLSP knows about.
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+	InlayHintProvider bool `json:"inlayHintProvider,omitempty"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities                   `json:"capabilities"`
+	ServerInfo   *protocol.InitializeResultServerInfo `json:"serverInfo,omitempty"`
+}
+
+// extensionHandler handles a method that GLSP does not know about, and returns whether its parameters were valid.
+type extensionHandler func(ctx *glsp.Context) (result any, validParams bool, err error)
+
+// extension returns a handler that decodes the parameters of the message before passing them on.
+func extension[P any](handle func(ctx *glsp.Context, params *P) (any, error)) extensionHandler {
+	return func(ctx *glsp.Context) (any, bool, error) {
+		var params P
+		if err := json.Unmarshal(ctx.Params, &params); err != nil {
+			return nil, false, err
+		}
+		result, err := handle(ctx, &params)
+		return result, true, err
+	}
+}
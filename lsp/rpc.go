@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/commonlog"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// codeRequestCancelled is the error code of the response to a request that was cancelled by the client.
+const codeRequestCancelled = -32800
+
+// rpcHandler dispatches messages to the protocol handler. Unlike the server of GLSP, which handles every message in
+// the order it arrives, requests are handled concurrently so that slow requests do not hold up the others and can be
+// cancelled with `$/cancelRequest`. Notifications are still handled in order, so that changes to documents are
+// applied in the order they were made.
+type rpcHandler struct {
+	s *Server
+
+	mu       sync.Mutex
+	requests map[jsonrpc2.ID]context.CancelFunc
+	contexts map[*glsp.Context]context.Context
+}
+
+// serve handles messages from stdin and writes the responses to stdout until the connection is closed.
+func (h *rpcHandler) serve(debug bool) {
+	opts := []jsonrpc2.ConnOpt{}
+	if debug {
+		opts = append(opts, jsonrpc2.LogMessages(rpcLogger{commonlog.GetLogger(LS_NAME + ".rpc")}))
+	}
+	stream := jsonrpc2.NewBufferedStream(stdio{}, jsonrpc2.VSCodeObjectCodec{})
+	<-jsonrpc2.NewConn(context.Background(), stream, h, opts...).DisconnectNotify()
+}
+
+func (h *rpcHandler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	if req.Notif {
+		switch req.Method {
+		case protocol.MethodCancelRequest:
+			h.cancel(req)
+		case protocol.MethodExit:
+			h.dispatch(ctx, conn, req)
+			conn.Close()
+		default:
+			if _, err := h.dispatch(ctx, conn, req); err != nil {
+				h.s.log.Errorf("%s: %s", req.Method, err.Message)
+			}
+		}
+		return
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	h.mu.Lock()
+	h.requests[req.ID] = cancel
+	h.mu.Unlock()
+	go func() {
+		defer func() {
+			h.mu.Lock()
+			delete(h.requests, req.ID)
+			h.mu.Unlock()
+			cancel()
+		}()
+		result, err := h.dispatch(reqCtx, conn, req)
+		if reqCtx.Err() != nil {
+			err = &jsonrpc2.Error{Code: codeRequestCancelled, Message: "Request was cancelled"}
+		}
+		if err != nil {
+			conn.ReplyWithError(ctx, req.ID, err)
+		} else {
+			conn.Reply(ctx, req.ID, result)
+		}
+	}()
+}
+
+// dispatch passes the message on to its extension handler, or to the protocol handler of GLSP.
+func (h *rpcHandler) dispatch(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (any, *jsonrpc2.Error) {
+	glspContext := &glsp.Context{
+		Method: req.Method,
+		Notify: func(method string, params any) {
+			if err := conn.Notify(ctx, method, params); err != nil {
+				h.s.log.Errorf("%s", err)
+			}
+		},
+		Call: func(method string, params any, result any) {
+			if err := conn.Call(ctx, method, params, result); err != nil {
+				h.s.log.Errorf("%s", err)
+			}
+		},
+	}
+	if req.Params != nil {
+		glspContext.Params = *req.Params
+	}
+	h.mu.Lock()
+	h.contexts[glspContext] = ctx
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.contexts, glspContext)
+		h.mu.Unlock()
+	}()
+
+	var result any
+	var validMethod, validParams bool
+	var err error
+	if handle := h.s.extensions[req.Method]; handle != nil {
+		validMethod = true
+		result, validParams, err = handle(glspContext)
+	} else {
+		result, validMethod, validParams, err = h.s.handler.Handle(glspContext)
+	}
+	switch {
+	case !validMethod:
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", req.Method)}
+	case !validParams:
+		rpcErr := &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+		if err != nil {
+			rpcErr.Message = err.Error()
+		}
+		return nil, rpcErr
+	case err != nil:
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidRequest, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// cancel cancels the context of the request with the ID in the parameters, if it is still being handled.
+func (h *rpcHandler) cancel(req *jsonrpc2.Request) {
+	if req.Params == nil {
+		return
+	}
+	// The ID is decoded by jsonrpc2, because protocol.IntegerOrString can not be decoded
+	var params struct {
+		ID jsonrpc2.ID `json:"id"`
+	}
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		h.s.log.Errorf("%s: %s", req.Method, err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cancel := h.requests[params.ID]; cancel != nil {
+		cancel()
+	}
+}
+
+// context returns the context of the message that is being handled with the given GLSP context, which is cancelled
+// if the client cancels the request.
+func (h *rpcHandler) context(ctx *glsp.Context) context.Context {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if c := h.contexts[ctx]; c != nil {
+		return c
+	}
+	return context.Background()
+}
+
+type rpcLogger struct {
+	log commonlog.Logger
+}
+
+func (l rpcLogger) Printf(format string, v ...any) {
+	l.log.Debugf(strings.TrimSuffix(format, "\n"), v...)
+}
+
+// stdio is a connection over stdin and stdout.
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+func (stdio) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (stdio) Close() error {
+	if err := os.Stdin.Close(); err != nil {
+		return err
+	}
+	return os.Stdout.Close()
+}
@@ -0,0 +1,143 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// connect serves s over an in-memory connection and initializes it, without indexing the workspace. It returns the
+// connection of the client, and the pipe that it writes to.
+func connect(t *testing.T, s *Server) (*jsonrpc2.Conn, net.Conn) {
+	server, client := net.Pipe()
+	serverConn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewBufferedStream(server, jsonrpc2.VSCodeObjectCodec{}), s.rpc)
+	// Notifications from the server, such as diagnostics, are ignored
+	ignore := jsonrpc2.HandlerWithError(func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) { return nil, nil })
+	clientConn := jsonrpc2.NewConn(context.Background(), jsonrpc2.NewBufferedStream(client, jsonrpc2.VSCodeObjectCodec{}), ignore)
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+	var result any
+	require.NoError(t, clientConn.Call(context.Background(), protocol.MethodInitialize, protocol.InitializeParams{}, &result))
+	return clientConn, client
+}
+
+// highlight sends a request to highlight the node at the position, and returns the request so that it can be waited on.
+func highlight(t *testing.T, conn *jsonrpc2.Conn, id uint64, uri protocol.URI, pos protocol.Position) jsonrpc2.Waiter {
+	params := protocol.DocumentHighlightParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     pos,
+		},
+	}
+	waiter, err := conn.DispatchCall(context.Background(), protocol.MethodTextDocumentDocumentHighlight, params, jsonrpc2.PickID(jsonrpc2.ID{Num: id}))
+	require.NoError(t, err)
+	return waiter
+}
+
+// wait waits for the response to the request, failing the test if it takes too long.
+func wait(t *testing.T, waiter jsonrpc2.Waiter, result any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := waiter.Wait(ctx, result)
+	require.NotErrorIs(t, err, context.DeadlineExceeded)
+	return err
+}
+
+// pending asserts that the request has not been answered yet.
+func pending(t *testing.T, waiter jsonrpc2.Waiter) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, waiter.Wait(ctx, nil), context.DeadlineExceeded)
+}
+
+func TestCancelRequest(t *testing.T) {
+	s := newServer()
+	conn, _ := connect(t, s)
+
+	// The file is not open, so the request waits for the workspace to be indexed, which never happens
+	waiter := highlight(t, conn, 1, "file:///unopened.lua", protocol.Position{})
+	pending(t, waiter)
+	require.NoError(t, conn.Notify(context.Background(), protocol.MethodCancelRequest, protocol.CancelParams{ID: protocol.IntegerOrString{Value: 1}}))
+
+	err := wait(t, waiter, nil)
+	var rpcErr *jsonrpc2.Error
+	require.True(t, errors.As(err, &rpcErr), "expected a JSON-RPC error, got %v", err)
+	assert.Equal(t, int64(codeRequestCancelled), rpcErr.Code)
+	// The request is forgotten after its response is sent
+	assert.Eventually(t, func() bool {
+		s.rpc.mu.Lock()
+		defer s.rpc.mu.Unlock()
+		return len(s.rpc.requests) == 0 && len(s.rpc.contexts) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNotificationOrder(t *testing.T) {
+	s := newServer()
+	conn, pipe := connect(t, s)
+	uri := protocol.URI("file:///order.lua")
+
+	// The notifications are written at once, so that the server reads them as fast as it can, and the request is sent
+	// right after them without waiting. Applying them out of order would likely leave one of the other changes in place.
+	var buf bytes.Buffer
+	notify := func(method string, params any) {
+		req := &jsonrpc2.Request{Method: method, Notif: true}
+		require.NoError(t, req.SetParams(params))
+		require.NoError(t, jsonrpc2.VSCodeObjectCodec{}.WriteObject(&buf, req))
+	}
+	notify(protocol.MethodTextDocumentDidOpen, protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, LanguageID: "lua", Version: 1, Text: "x = 1\n"},
+	})
+	for i := 0; i <= 100; i++ {
+		text := fmt.Sprintf("x = %d\n", i)
+		if i == 100 {
+			text = "local abc = 1\n"
+		}
+		notify(protocol.MethodTextDocumentDidChange, protocol.DidChangeTextDocumentParams{
+			TextDocument:   protocol.VersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri}, Version: protocol.Integer(i + 2)},
+			ContentChanges: []any{protocol.TextDocumentContentChangeEventWhole{Text: text}},
+		})
+	}
+	_, err := pipe.Write(buf.Bytes())
+	require.NoError(t, err)
+	var highlights []protocol.DocumentHighlight
+	require.NoError(t, wait(t, highlight(t, conn, 1, uri, protocol.Position{Line: 0, Character: 7}), &highlights))
+
+	require.Len(t, highlights, 1)
+	expected := protocol.Range{Start: protocol.Position{Line: 0, Character: 6}, End: protocol.Position{Line: 0, Character: 9}}
+	assert.Equal(t, expected, highlights[0].Range)
+}
+
+func TestRequestWaitsForIndex(t *testing.T) {
+	s := newServer()
+	conn, _ := connect(t, s)
+	uri := protocol.URI("file:///open.lua")
+
+	require.NoError(t, conn.Notify(context.Background(), protocol.MethodTextDocumentDidOpen, protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, LanguageID: "lua", Version: 1, Text: "local abc = 1\n"},
+	}))
+
+	// Open files are answered while the workspace is being indexed
+	var highlights []protocol.DocumentHighlight
+	require.NoError(t, wait(t, highlight(t, conn, 1, uri, protocol.Position{Line: 0, Character: 7}), &highlights))
+	assert.Len(t, highlights, 1)
+
+	// Other files are answered once it has been indexed
+	waiter := highlight(t, conn, 2, "file:///unopened.lua", protocol.Position{})
+	pending(t, waiter)
+	close(s.indexed)
+	err := wait(t, waiter, nil)
+	var rpcErr *jsonrpc2.Error
+	require.True(t, errors.As(err, &rpcErr), "expected a JSON-RPC error, got %v", err)
+	assert.Equal(t, "File not found", rpcErr.Message)
+}
@@ -1,12 +1,17 @@
 package lsp
 
 import (
-	"github.com/raiguard/luapls/lua/ast"
+	"context"
+	"sync"
+
+	"github.com/raiguard/luapls/cache"
+	"github.com/raiguard/luapls/config"
 	"github.com/raiguard/luapls/lua/types"
+	"github.com/raiguard/luapls/util"
+	"github.com/sourcegraph/jsonrpc2"
 	"github.com/tliron/commonlog"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
-	glspserv "github.com/tliron/glsp/server"
 
 	// To enable logging
 	_ "github.com/tliron/commonlog/simple"
@@ -18,20 +23,44 @@ const LS_NAME = "luapls"
 type Server struct {
 	environment *types.Environment
 	handler     protocol.Handler
+	extensions  map[string]extensionHandler // Method -> its handler, for methods that GLSP does not know about
 	log         commonlog.Logger
 	rootPath    string
-	server      *glspserv.Server
+	rpc         *rpcHandler
 
-	config Config
+	configMu sync.Mutex    // Guards config, settings and project, which are used by concurrent requests
+	config   config.Config // The configuration that is in effect
+	settings config.Config // The configuration sent by the editor
+	project  config.Config // The configuration read from the project configuration file
 
-	isInitialized bool
+	indexed          chan struct{}      // Closed once the workspace has been indexed
+	cancelIndex      context.CancelFunc // Stops indexing the workspace
+	workDoneProgress bool               // Whether the client supports progress reports that the server starts
 }
 
 func Run(logLevel int) {
 	commonlog.Configure(logLevel, nil)
 
-	s := Server{
+	s := newServer()
+	if dir, err := cache.Dir(); err == nil {
+		s.environment.Cache = cache.Open(dir, cache.Version())
+	} else {
+		s.log.Warningf("Not caching files: %s", err)
+	}
+	s.rpc.serve(logLevel > 2)
+}
+
+// newServer creates a server with an empty environment and registers the handlers of every method it supports.
+func newServer() *Server {
+	s := &Server{
 		environment: types.NewEnvironment(),
+		indexed:     make(chan struct{}),
+		log:         commonlog.GetLogger(LS_NAME + ".server"),
+	}
+	s.rpc = &rpcHandler{
+		s:        s,
+		requests: map[jsonrpc2.ID]context.CancelFunc{},
+		contexts: map[*glsp.Context]context.Context{},
 	}
 
 	s.handler.Initialize = s.initialize
@@ -45,41 +74,74 @@ func Run(logLevel int) {
 	s.handler.TextDocumentDocumentHighlight = s.textDocumentHighlight
 	s.handler.TextDocumentHover = s.textDocumentHover
 	s.handler.TextDocumentDefinition = s.textDocumentDefinition
+	s.handler.TextDocumentCodeAction = s.textDocumentCodeAction
+	s.handler.TextDocumentPrepareCallHierarchy = s.textDocumentPrepareCallHierarchy
+	s.handler.CallHierarchyIncomingCalls = s.callHierarchyIncomingCalls
+	s.handler.CallHierarchyOutgoingCalls = s.callHierarchyOutgoingCalls
+	s.handler.WorkspaceExecuteCommand = s.workspaceExecuteCommand
+	s.handler.WindowWorkDoneProgressCancel = s.workDoneProgressCancel
+	s.extensions = map[string]extensionHandler{
+		MethodTextDocumentInlayHint: extension(s.textDocumentInlayHint),
+	}
 
-	s.server = glspserv.NewServer(&s.handler, LS_NAME, logLevel > 2)
-
-	s.log = s.server.Log
-
-	s.server.RunStdio()
+	return s
 }
 
 func (s *Server) initialize(ctx *glsp.Context, params *protocol.InitializeParams) (any, error) {
 	capabilities := s.handler.CreateServerCapabilities()
-	// TODO: RootURI / WorkspaceFolders fallbacks
-	s.environment.RootPath = *params.RootPath
+	capabilities.ExecuteCommandProvider = &protocol.ExecuteCommandOptions{Commands: commands}
+	s.setWorkspaceFolders(params)
+	if window := params.Capabilities.Window; window != nil && window.WorkDoneProgress != nil {
+		s.workDoneProgress = *window.WorkDoneProgress
+	}
 
+	s.loadProjectConfig()
 	s.updateConfig(params.InitializationOptions)
 
-	s.environment.Init()
-
-	return protocol.InitializeResult{
-		Capabilities: capabilities,
+	return initializeResult{
+		Capabilities: serverCapabilities{ServerCapabilities: capabilities, InlayHintProvider: true},
 		ServerInfo:   &protocol.InitializeResultServerInfo{Name: LS_NAME},
 	}, nil
 }
 
-func (s *Server) initialized(ctx *glsp.Context, params *protocol.InitializedParams) error {
-	s.isInitialized = true
-
-	for _, file := range s.environment.Files {
-		s.publishDiagnostics(ctx, file)
+// setWorkspaceFolders sets the root path of the environment from the URI of the first workspace folder, falling back
+// to the deprecated root URI and root path. The other workspace folders are searched for files as well.
+func (s *Server) setWorkspaceFolders(params *protocol.InitializeParams) {
+	folders := []string{}
+	for _, folder := range params.WorkspaceFolders {
+		if path, err := util.URIToPath(folder.URI); err == nil {
+			folders = append(folders, path)
+		} else {
+			s.log.Errorf("%s", err)
+		}
+	}
+	if len(folders) == 0 && params.RootURI != nil {
+		if path, err := util.URIToPath(*params.RootURI); err == nil {
+			folders = append(folders, path)
+		}
+	}
+	if len(folders) == 0 && params.RootPath != nil {
+		folders = append(folders, *params.RootPath)
 	}
+	if len(folders) == 0 {
+		s.log.Warning("No workspace folder was given, only open files will be checked")
+		return
+	}
+	s.environment.RootPath = folders[0]
+	s.environment.Folders = folders[1:]
+}
 
+func (s *Server) initialized(ctx *glsp.Context, params *protocol.InitializedParams) error {
+	// The workspace is indexed in the background so that the client is not blocked while it is
+	s.index(ctx)
 	return nil
 }
 
 func (s *Server) shutdown(ctx *glsp.Context) error {
 	protocol.SetTraceValue(protocol.TraceValueOff)
+	if s.isIndexed() {
+		s.environment.SaveCache()
+	}
 	return nil
 }
 
@@ -87,10 +149,3 @@ func (s *Server) setTrace(ctx *glsp.Context, params *protocol.SetTraceParams) er
 	protocol.SetTraceValue(params.Value)
 	return nil
 }
-
-func (s *Server) getFile(uri protocol.URI) *ast.File {
-	if !s.isInitialized {
-		return nil
-	}
-	return s.environment.Files[uri]
-}
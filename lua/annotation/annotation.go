@@ -1,12 +1,136 @@
 package annotation
 
+import (
+	"github.com/raiguard/luapls/lua/token"
+)
+
 type Annotation interface {
 	isAnnotation()
 }
 
-// TODO: Generics
-type Class struct {
-	Name string
+type (
+	// Alias gives a name to a type expression: `---@alias Name type`.
+	Alias struct {
+		Name      string
+		NameRange token.Range
+		Type      TypeExpr
+	}
+	// TODO: Generics
+	Class struct {
+		Name      string
+		NameRange token.Range
+		Parents   []string
+	}
+	// Deprecated marks the following definition as deprecated, with an optional message: `---@deprecated message`.
+	Deprecated struct {
+		Message string
+	}
+	// Diagnostic suppresses diagnostics: `---@diagnostic disable-next-line: code, code`. Without any codes, every
+	// diagnostic is suppressed.
+	Diagnostic struct {
+		Action string // One of DiagnosticActions
+		Codes  []string
+	}
+	// Field declares a field on the preceding class: `---@field name type`.
+	Field struct {
+		Name        string
+		NameRange   token.Range
+		Optional    bool
+		Type        TypeExpr
+		Description string
+	}
+	// Meta marks the file as a definition file, whose code is never executed.
+	Meta struct{}
+	// Param declares the type of a function parameter: `---@param name type`. The name of a vararg parameter
+	// is `...`.
+	Param struct {
+		Name        string
+		NameRange   token.Range
+		Optional    bool
+		Type        TypeExpr
+		Description string
+	}
+	// Return declares the types of one or more values returned by a function: `---@return type, type`.
+	Return struct {
+		Types       []TypeExpr
+		Vararg      bool // The last type is repeated for any number of values
+		Description string
+	}
+	// Type declares the types of the variables assigned by the following statement: `---@type type, type`.
+	Type struct {
+		Types []TypeExpr
+	}
+	// Version restricts the following definition to the given Lua versions: `---@version 5.1, >5.3, JIT`.
+	Version struct {
+		Versions []VersionConstraint
+	}
+)
+
+func (a *Alias) isAnnotation()      {}
+func (c *Class) isAnnotation()      {}
+func (d *Deprecated) isAnnotation() {}
+func (d *Diagnostic) isAnnotation() {}
+func (f *Field) isAnnotation()      {}
+func (m *Meta) isAnnotation()       {}
+func (p *Param) isAnnotation()      {}
+func (r *Return) isAnnotation()     {}
+func (t *Type) isAnnotation()       {}
+func (v *Version) isAnnotation()    {}
+
+// DiagnosticActions are the actions that a `---@diagnostic` annotation can perform.
+var DiagnosticActions = []string{"disable-next-line", "disable-line", "disable", "enable"}
+
+// VersionConstraint is a single Lua version, optionally preceded by `<` or `>` to match every earlier or later
+// version as well.
+type VersionConstraint struct {
+	Operator token.TokenType // token.INVALID for an exact match
+	Version  string
 }
 
-func (c *Class) isAnnotation() {}
+// TypeExpr is a type, as written in an annotation.
+type TypeExpr interface {
+	isTypeExpr()
+}
+
+type (
+	// ArrayType is a sequence of values: `T[]`.
+	ArrayType struct {
+		Elem TypeExpr
+	}
+	// FunctionType is a function signature: `fun(a: T, ...: U): R1, R2`.
+	FunctionType struct {
+		Params  []FunctionParam
+		Vararg  TypeExpr // nil if the function does not accept varargs
+		Returns []TypeExpr
+	}
+	// LiteralType is a single string literal value: `"name"`.
+	LiteralType struct {
+		Value string
+	}
+	// NamedType is a reference to a builtin type, class or alias, with optional arguments: `table<K, V>`.
+	NamedType struct {
+		Name string
+		Args []TypeExpr
+	}
+	// TableLiteralType is a table with a fixed set of fields: `{ name: T }`.
+	TableLiteralType struct {
+		Fields []FunctionParam
+	}
+	UnionType struct {
+		Types []TypeExpr
+	}
+)
+
+func (a *ArrayType) isTypeExpr()        {}
+func (f *FunctionType) isTypeExpr()     {}
+func (l *LiteralType) isTypeExpr()      {}
+func (n *NamedType) isTypeExpr()        {}
+func (t *TableLiteralType) isTypeExpr() {}
+func (u *UnionType) isTypeExpr()        {}
+
+// FunctionParam is a named member of a function or table literal type.
+type FunctionParam struct {
+	Name     string
+	Optional bool
+	Type     TypeExpr
+}
@@ -2,6 +2,8 @@ package annotation
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/raiguard/luapls/lua/ast"
 	"github.com/raiguard/luapls/lua/lexer"
@@ -10,12 +12,13 @@ import (
 )
 
 func Parse(src string) (Annotation, []ast.Diagnostic) {
-	tokens, _ := lexer.Run(src)
-	p := parser{tokens, -1, []ast.Diagnostic{}}
+	tokens, _ := lexer.RunAnnotation(src)
+	p := parser{src, tokens, -1, []ast.Diagnostic{}}
 	return p.parse()
 }
 
 type parser struct {
+	src         string
 	tokens      []token.Token
 	pos         int
 	diagnostics []ast.Diagnostic
@@ -24,9 +27,75 @@ type parser struct {
 func (p *parser) parse() (Annotation, []ast.Diagnostic) {
 	tok := p.next()
 	switch tok.Type {
+	case token.DOC_ALIAS:
+		name := p.expect(token.IDENT)
+		return &Alias{Name: name.Literal, NameRange: name.Range(), Type: p.parseType()}, p.diagnostics
 	case token.DOC_CLASS:
 		name := p.expect(token.IDENT)
-		return &Class{Name: name.Literal}, p.diagnostics
+		class := &Class{Name: name.Literal, NameRange: name.Range()}
+		if p.peek().Type == token.COLON {
+			p.next()
+			class.Parents = append(class.Parents, p.expect(token.IDENT).Literal)
+			for p.peek().Type == token.COMMA {
+				p.next()
+				class.Parents = append(class.Parents, p.expect(token.IDENT).Literal)
+			}
+		}
+		return class, p.diagnostics
+	case token.DOC_DEPRECATED:
+		return &Deprecated{Message: p.description()}, p.diagnostics
+	case token.DOC_DIAGNOSTIC:
+		return p.parseDiagnostic(tok), p.diagnostics
+	case token.DOC_FIELD:
+		name := p.expect(token.IDENT)
+		if isVisibility(name.Literal) && p.peek().Type == token.IDENT {
+			name = p.next()
+		}
+		field := &Field{Name: name.Literal, NameRange: name.Range(), Optional: p.optional()}
+		field.Type = p.parseType()
+		field.Description = p.description()
+		return field, p.diagnostics
+	case token.DOC_META:
+		return &Meta{}, p.diagnostics
+	case token.DOC_PARAM:
+		name := p.next()
+		if name.Type != token.IDENT && name.Type != token.VARARG {
+			p.error(name, "Expected parameter name")
+		}
+		param := &Param{Name: name.Literal, NameRange: name.Range(), Optional: p.optional()}
+		param.Type = p.parseType()
+		param.Description = p.description()
+		return param, p.diagnostics
+	case token.DOC_RETURN:
+		ret := &Return{Types: p.parseTypeList()}
+		if p.peek().Type == token.VARARG {
+			p.next()
+			ret.Vararg = true
+		}
+		ret.Description = p.description()
+		return ret, p.diagnostics
+	case token.DOC_TYPE:
+		return &Type{Types: p.parseTypeList()}, p.diagnostics
+	case token.DOC_VERSION:
+		version := &Version{}
+		for {
+			constraint := VersionConstraint{}
+			if typ := p.peek().Type; typ == token.GT || typ == token.LT {
+				constraint.Operator = p.next().Type
+			}
+			tok := p.next()
+			if tok.Type != token.NUMBER && tok.Type != token.IDENT {
+				p.error(tok, "Expected version")
+				break
+			}
+			constraint.Version = tok.Literal
+			version.Versions = append(version.Versions, constraint)
+			if p.peek().Type != token.COMMA {
+				break
+			}
+			p.next()
+		}
+		return version, p.diagnostics
 	case token.INVALID:
 		p.diagnostics = append(p.diagnostics, ast.Diagnostic{
 			Message:  "Unknown annotation",
@@ -39,6 +108,187 @@ func (p *parser) parse() (Annotation, []ast.Diagnostic) {
 	}
 }
 
+// parseDiagnostic parses the action and codes of a `---@diagnostic` annotation. Codes contain dashes, so they are
+// read from the source text rather than from tokens.
+func (p *parser) parseDiagnostic(tok *token.Token) *Diagnostic {
+	start := tok.End()
+	rest := p.src[start:]
+	actionText, codes, hasCodes := strings.Cut(rest, ":")
+	action := strings.TrimSpace(actionText)
+	d := &Diagnostic{Action: action}
+	if !slices.Contains(DiagnosticActions, action) {
+		offset := start + strings.Index(actionText, action)
+		p.diagnostics = append(p.diagnostics, ast.Diagnostic{
+			Message:  fmt.Sprintf("Unknown diagnostic action '%s'", action),
+			Range:    token.Range{Start: offset, End: offset + len(action)},
+			Severity: protocol.DiagnosticSeverityWarning,
+		})
+	}
+	if hasCodes {
+		for _, code := range strings.Split(codes, ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				d.Codes = append(d.Codes, code)
+			}
+		}
+	}
+	p.pos = len(p.tokens) - 1
+	return d
+}
+
+// parseTypeList parses one or more comma-separated types.
+func (p *parser) parseTypeList() []TypeExpr {
+	types := []TypeExpr{p.parseType()}
+	for p.peek().Type == token.COMMA {
+		p.next()
+		types = append(types, p.parseType())
+	}
+	return types
+}
+
+// parseType parses a type expression, which may be a union of several types.
+func (p *parser) parseType() TypeExpr {
+	types := []TypeExpr{p.parsePostfixType()}
+	for p.peek().Type == token.PIPE {
+		p.next()
+		types = append(types, p.parsePostfixType())
+	}
+	if len(types) == 1 {
+		return types[0]
+	}
+	return &UnionType{Types: types}
+}
+
+// parsePostfixType parses a type followed by any number of `[]` or `?` suffixes.
+func (p *parser) parsePostfixType() TypeExpr {
+	typ := p.parsePrimaryType()
+	for {
+		switch p.peek().Type {
+		case token.LBRACK:
+			p.next()
+			p.expect(token.RBRACK)
+			typ = &ArrayType{Elem: typ}
+		case token.QUESTION:
+			p.next()
+			typ = &UnionType{Types: []TypeExpr{typ, &NamedType{Name: "nil"}}}
+		default:
+			return typ
+		}
+	}
+}
+
+func (p *parser) parsePrimaryType() TypeExpr {
+	tok := p.next()
+	switch tok.Type {
+	case token.IDENT:
+		if tok.Literal == "fun" && p.peek().Type == token.LPAREN {
+			return p.parseFunctionType()
+		}
+		named := &NamedType{Name: tok.Literal}
+		if p.peek().Type == token.LT {
+			p.next()
+			named.Args = p.parseTypeList()
+			p.expect(token.GT)
+		}
+		return named
+	case token.FUNCTION:
+		return &NamedType{Name: "function"}
+	case token.NIL:
+		return &NamedType{Name: "nil"}
+	case token.TRUE, token.FALSE:
+		return &NamedType{Name: "boolean"}
+	case token.NUMBER:
+		return &NamedType{Name: "number"}
+	case token.STRING:
+		return &LiteralType{Value: tok.Literal[1 : len(tok.Literal)-1]}
+	case token.LPAREN:
+		typ := p.parseType()
+		p.expect(token.RPAREN)
+		return typ
+	case token.LBRACE:
+		table := &TableLiteralType{}
+		for p.peek().Type != token.RBRACE && p.peek().Type != token.EOF {
+			name := p.expect(token.IDENT)
+			field := FunctionParam{Name: name.Literal, Optional: p.optional()}
+			p.expect(token.COLON)
+			field.Type = p.parseType()
+			table.Fields = append(table.Fields, field)
+			if p.peek().Type != token.COMMA {
+				break
+			}
+			p.next()
+		}
+		p.expect(token.RBRACE)
+		return table
+	}
+	p.error(tok, "Expected type")
+	return &NamedType{Name: "unknown"}
+}
+
+// parseFunctionType parses the parameters and return types of a `fun(...)` type.
+func (p *parser) parseFunctionType() TypeExpr {
+	fn := &FunctionType{}
+	p.expect(token.LPAREN)
+	for p.peek().Type != token.RPAREN && p.peek().Type != token.EOF {
+		name := p.next()
+		if name.Type == token.VARARG {
+			fn.Vararg = p.paramType()
+		} else {
+			if name.Type != token.IDENT {
+				p.error(name, "Expected parameter name")
+			}
+			param := FunctionParam{Name: name.Literal, Optional: p.optional()}
+			param.Type = p.paramType()
+			fn.Params = append(fn.Params, param)
+		}
+		if p.peek().Type != token.COMMA {
+			break
+		}
+		p.next()
+	}
+	p.expect(token.RPAREN)
+	if p.peek().Type == token.COLON {
+		p.next()
+		fn.Returns = p.parseTypeList()
+	}
+	return fn
+}
+
+// paramType parses the optional type of a parameter in a function type, which defaults to `any`.
+func (p *parser) paramType() TypeExpr {
+	if p.peek().Type != token.COLON {
+		return &NamedType{Name: "any"}
+	}
+	p.next()
+	return p.parseType()
+}
+
+// optional consumes a `?` suffix on a parameter or field name.
+func (p *parser) optional() bool {
+	if p.peek().Type == token.QUESTION {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// description returns the rest of the annotation as free text.
+func (p *parser) description() string {
+	tok := p.next()
+	if tok.Type == token.EOF {
+		return ""
+	}
+	text := strings.TrimPrefix(strings.TrimSpace(p.src[tok.Pos:]), "#")
+	return strings.TrimSpace(text)
+}
+
+func isVisibility(word string) bool {
+	switch word {
+	case "public", "protected", "private", "package":
+		return true
+	}
+	return false
+}
+
 func (p *parser) read() *token.Token {
 	if p.pos < len(p.tokens)-1 {
 		p.pos++
@@ -54,14 +304,26 @@ func (p *parser) next() *token.Token {
 	return tok
 }
 
+// peek returns the next token without consuming it.
+func (p *parser) peek() *token.Token {
+	pos := p.pos
+	tok := p.next()
+	p.pos = pos
+	return tok
+}
+
 func (p *parser) expect(typ token.TokenType) *token.Token {
 	tok := p.next()
 	if tok.Type != typ {
-		p.diagnostics = append(p.diagnostics, ast.Diagnostic{
-			Message:  fmt.Sprintf("Expected %s", token.TokenStr[typ]),
-			Range:    tok.Range(),
-			Severity: protocol.DiagnosticSeverityWarning,
-		})
+		p.error(tok, fmt.Sprintf("Expected %s", token.TokenStr[typ]))
 	}
 	return tok
 }
+
+func (p *parser) error(tok *token.Token, message string) {
+	p.diagnostics = append(p.diagnostics, ast.Diagnostic{
+		Message:  message,
+		Range:    tok.Range(),
+		Severity: protocol.DiagnosticSeverityWarning,
+	})
+}
@@ -0,0 +1,82 @@
+package annotation
+
+import (
+	"testing"
+
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected Annotation
+	}{
+		{"class", "@class Foo", &Class{Name: "Foo"}},
+		{"class parents", "@class Foo : Bar, Baz", &Class{Name: "Foo", Parents: []string{"Bar", "Baz"}}},
+		{"alias", "@alias Mode 'r'|'w'", &Alias{Name: "Mode", Type: &UnionType{Types: []TypeExpr{&LiteralType{Value: "r"}, &LiteralType{Value: "w"}}}}},
+		{"meta", "@meta", &Meta{}},
+		{"deprecated", "@deprecated", &Deprecated{}},
+		{"deprecated message", "@deprecated Use bar instead", &Deprecated{Message: "Use bar instead"}},
+		{"param", "@param s string The string", &Param{Name: "s", Type: &NamedType{Name: "string"}, Description: "The string"}},
+		{"optional param", "@param n? integer", &Param{Name: "n", Optional: true, Type: &NamedType{Name: "integer"}}},
+		{"vararg param", "@param ... any", &Param{Name: "...", Type: &NamedType{Name: "any"}}},
+		{"field", "@field private x number # The x", &Field{Name: "x", Type: &NamedType{Name: "number"}, Description: "The x"}},
+		{"return list", "@return string, integer", &Return{Types: []TypeExpr{&NamedType{Name: "string"}, &NamedType{Name: "integer"}}}},
+		{"return vararg", "@return any ...", &Return{Types: []TypeExpr{&NamedType{Name: "any"}}, Vararg: true}},
+		{"nullable", "@type string?", &Type{Types: []TypeExpr{&UnionType{Types: []TypeExpr{&NamedType{Name: "string"}, &NamedType{Name: "nil"}}}}}},
+		{"array", "@type string[][]", &Type{Types: []TypeExpr{&ArrayType{Elem: &ArrayType{Elem: &NamedType{Name: "string"}}}}}},
+		{"generic table", "@type table<string, boolean>", &Type{Types: []TypeExpr{&NamedType{Name: "table", Args: []TypeExpr{&NamedType{Name: "string"}, &NamedType{Name: "boolean"}}}}}},
+		{"function", "@type fun(a: string, b?: number, ...: any): boolean, nil", &Type{Types: []TypeExpr{&FunctionType{
+			Params: []FunctionParam{
+				{Name: "a", Type: &NamedType{Name: "string"}},
+				{Name: "b", Optional: true, Type: &NamedType{Name: "number"}},
+			},
+			Vararg:  &NamedType{Name: "any"},
+			Returns: []TypeExpr{&NamedType{Name: "boolean"}, &NamedType{Name: "nil"}},
+		}}}},
+		{"table literal", "@type { x: number, y?: string }", &Type{Types: []TypeExpr{&TableLiteralType{Fields: []FunctionParam{
+			{Name: "x", Type: &NamedType{Name: "number"}},
+			{Name: "y", Optional: true, Type: &NamedType{Name: "string"}},
+		}}}}},
+		{"version", "@version >5.2, JIT", &Version{Versions: []VersionConstraint{{Operator: token.GT, Version: "5.2"}, {Version: "JIT"}}}},
+		{"diagnostic", "@diagnostic disable-next-line: undefined-global, unknown-field", &Diagnostic{Action: "disable-next-line", Codes: []string{"undefined-global", "unknown-field"}}},
+		{"diagnostic without codes", "@diagnostic disable", &Diagnostic{Action: "disable"}},
+		{"description", " Just a comment", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			a, diags := Parse(test.input)
+			require.Empty(t, diags)
+			clearRanges(a)
+			assert.Equal(t, test.expected, a)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, diags := Parse("@unknown")
+	assert.Len(t, diags, 1)
+	_, diags = Parse("@param")
+	assert.NotEmpty(t, diags)
+	_, diags = Parse("@type table<string")
+	assert.NotEmpty(t, diags)
+	_, diags = Parse("@diagnostic silence: undefined-global")
+	assert.Len(t, diags, 1)
+}
+
+// clearRanges zeroes the name ranges of the annotation so that it can be compared to a literal.
+func clearRanges(a Annotation) {
+	switch a := a.(type) {
+	case *Alias:
+		a.NameRange = token.Range{}
+	case *Class:
+		a.NameRange = token.Range{}
+	case *Field:
+		a.NameRange = token.Range{}
+	case *Param:
+		a.NameRange = token.Range{}
+	}
+}
@@ -11,6 +11,21 @@ type Diagnostic struct {
 	Message  string
 	Range    token.Range
 	Severity protocol.DiagnosticSeverity
+	Code     string // A stable identifier for the kind of diagnostic, such as `undefined-global`
+	Tags     []protocol.DiagnosticTag
+	Fixes    []Fix `json:",omitempty"` // Changes to the source that resolve the diagnostic
+}
+
+// Fix is a change to the source of a file that resolves a diagnostic.
+type Fix struct {
+	Title string
+	Edits []Edit
+}
+
+// Edit replaces the text in a range of the source with new text.
+type Edit struct {
+	Range   token.Range
+	NewText string
 }
 
 func (pe *Diagnostic) String() string {
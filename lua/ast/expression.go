@@ -1,6 +1,9 @@
 package ast
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/raiguard/luapls/lua/token"
 )
 
@@ -35,7 +38,7 @@ func (fc *FunctionCall) End() token.Pos {
 }
 
 type FunctionExpression struct {
-	FuncTok   Unit
+	FuncTok    Unit
 	LeftParen  Unit
 	Params     Punctuated[*Identifier]
 	Vararg     *Unit
@@ -154,6 +157,88 @@ func (sl *StringLiteral) End() token.Pos {
 }
 func (sl *StringLiteral) leaf() {}
 
+// Value returns the contents of the string with its delimiters removed and its escape sequences resolved.
+func (sl *StringLiteral) Value() string {
+	lit := sl.Token.Literal
+	if sl.Token.Type == token.RAWSTRING {
+		open := strings.Index(lit[1:], "[") + 2
+		if open < 2 || len(lit) < open*2 {
+			return ""
+		}
+		contents := lit[open : len(lit)-open]
+		// A newline immediately following the opening bracket is skipped
+		if strings.HasPrefix(contents, "\r\n") {
+			return contents[2:]
+		}
+		return strings.TrimPrefix(contents, "\n")
+	}
+	if len(lit) < 2 {
+		return ""
+	}
+	return unescape(lit[1 : len(lit)-1])
+}
+
+func unescape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n', '\n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'v':
+			sb.WriteByte('\v')
+		case 'x':
+			if i+2 < len(s) {
+				if n, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+					sb.WriteByte(byte(n))
+					i += 2
+				}
+			}
+		case 'z':
+			for i+1 < len(s) && strings.ContainsRune(" \t\r\n", rune(s[i+1])) {
+				i++
+			}
+		case 'u':
+			end := strings.IndexByte(s[i:], '}')
+			if i+1 < len(s) && s[i+1] == '{' && end > 0 {
+				if n, err := strconv.ParseUint(s[i+2:i+end], 16, 32); err == nil {
+					sb.WriteString(string(rune(n)))
+				}
+				i += end
+			}
+		default:
+			if c >= '0' && c <= '9' {
+				j := i
+				for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '9' {
+					j++
+				}
+				n, _ := strconv.Atoi(s[i:j])
+				if n < 256 {
+					sb.WriteByte(byte(n))
+				}
+				i = j - 1
+			} else {
+				sb.WriteByte(c)
+			}
+		}
+	}
+	return sb.String()
+}
+
 type TableLiteral struct {
 	LeftBrace  Unit
 	Fields     Punctuated[TableField]
@@ -7,7 +7,14 @@ import (
 
 type File struct {
 	Block       *Block
+	Comments    []token.Token // Every comment in the file, in order
 	Diagnostics []Diagnostic
 	LineBreaks  token.LineBreaks
+	Source      string `json:"-"` // The text that the file was parsed from
 	URI         protocol.URI
 }
+
+// Text returns the source text in the given range.
+func (f *File) Text(rng token.Range) string {
+	return f.Source[rng.Start:rng.End]
+}
@@ -102,7 +102,7 @@ func (fs *FunctionStatement) Pos() token.Pos {
 	if fs.LocalTok != nil {
 		return fs.LocalTok.Pos()
 	}
-	return fs.Name.Pos()
+	return fs.FuncTok.Pos()
 }
 func (fs *FunctionStatement) End() token.Pos {
 	return fs.EndTok.End()
@@ -132,7 +132,7 @@ func (is *IfStatement) Pos() token.Pos {
 	return is.IfTok.Pos()
 }
 func (is *IfStatement) End() token.Pos {
-	return is.EndTok.Pos()
+	return is.EndTok.End()
 }
 
 type IfClause struct {
@@ -0,0 +1,317 @@
+// Package cfg builds control-flow graphs over Lua function bodies.
+//
+// Each basic block contains the statements and conditions that always execute together, in order. Compound
+// statements are broken up: the condition of an `if`, `while` or `repeat` is placed in the block that evaluates it,
+// and the outgoing edges of that block are labelled with the outcome of the condition. Numeric and generic `for`
+// statements appear as a single node in the loop header. Nested function expressions are not descended into; every
+// function body has its own graph.
+package cfg
+
+import (
+	"github.com/raiguard/luapls/lua/ast"
+)
+
+type EdgeKind int
+
+const (
+	Normal      EdgeKind = iota
+	True                 // The condition of the edge evaluated to a truthy value
+	False                // The condition of the edge evaluated to a falsy value
+	Return               // A return statement
+	Abort                // A call that never returns, such as `error`
+	Fallthrough          // Execution reached the end of the body
+)
+
+// Graph is the control-flow graph of a single function body, or the main chunk of a file.
+type Graph struct {
+	Entry  *Block
+	Exit   *Block
+	Blocks []*Block
+
+	InvalidBreaks   []*ast.BreakStatement // Break statements that are not inside of a loop
+	UnresolvedGotos []*ast.GotoStatement  // Goto statements whose label is not visible
+
+	blockOf map[ast.Node]*Block
+}
+
+// Block is a sequence of nodes that always execute in order.
+type Block struct {
+	Index     int
+	Nodes     []ast.Node
+	Succs     []*Edge
+	Preds     []*Edge
+	Reachable bool
+}
+
+type Edge struct {
+	Kind EdgeKind
+	From *Block
+	To   *Block
+	Cond ast.Expression // Set for True and False edges
+}
+
+// New builds the control-flow graph of the given body.
+func New(body *ast.Block) *Graph {
+	g := &Graph{blockOf: map[ast.Node]*Block{}}
+	b := builder{g: g}
+	g.Entry = b.newBlock()
+	g.Exit = &Block{}
+	b.current = g.Entry
+	b.scopes = []*labelScope{{labels: map[string]*Block{}}}
+	b.statements(body)
+	b.jump(g.Exit, Fallthrough, nil)
+	b.closeScope()
+	g.Exit.Index = len(g.Blocks)
+	g.Blocks = append(g.Blocks, g.Exit)
+	g.markReachable()
+	return g
+}
+
+// BlockOf returns the block that contains the given statement or condition. Compound statements, such as `if`,
+// belong to the block that precedes them.
+func (g *Graph) BlockOf(node ast.Node) *Block {
+	return g.blockOf[node]
+}
+
+// IsReachable returns whether the given statement or condition can ever be executed.
+func (g *Graph) IsReachable(node ast.Node) bool {
+	block := g.blockOf[node]
+	return block != nil && block.Reachable
+}
+
+// FallsThrough returns whether execution can reach the end of the body without returning.
+func (g *Graph) FallsThrough() bool {
+	for _, edge := range g.Exit.Preds {
+		if edge.Kind == Fallthrough && edge.From.Reachable {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Graph) markReachable() {
+	stack := []*Block{g.Entry}
+	for len(stack) > 0 {
+		block := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if block.Reachable {
+			continue
+		}
+		block.Reachable = true
+		for _, edge := range block.Succs {
+			stack = append(stack, edge.To)
+		}
+	}
+}
+
+// IsNoReturn returns whether the given call never returns. Only calls to the global `error` function are
+// recognized.
+func IsNoReturn(call *ast.FunctionCall) bool {
+	ident, ok := call.Name.(*ast.Identifier)
+	return ok && ident.Token.Literal == "error"
+}
+
+type builder struct {
+	g       *Graph
+	current *Block
+	loops   []*Block // The blocks following each enclosing loop
+	scopes  []*labelScope
+}
+
+type labelScope struct {
+	labels map[string]*Block
+	gotos  []pendingGoto
+}
+
+type pendingGoto struct {
+	stmt *ast.GotoStatement
+	from *Block
+}
+
+func (b *builder) newBlock() *Block {
+	block := &Block{Index: len(b.g.Blocks)}
+	b.g.Blocks = append(b.g.Blocks, block)
+	return block
+}
+
+func (b *builder) add(node ast.Node) {
+	b.current.Nodes = append(b.current.Nodes, node)
+	b.g.blockOf[node] = b.current
+}
+
+func (b *builder) edge(from, to *Block, kind EdgeKind, cond ast.Expression) {
+	edge := &Edge{Kind: kind, From: from, To: to, Cond: cond}
+	from.Succs = append(from.Succs, edge)
+	to.Preds = append(to.Preds, edge)
+}
+
+func (b *builder) jump(to *Block, kind EdgeKind, cond ast.Expression) {
+	b.edge(b.current, to, kind, cond)
+}
+
+// startBlock begins a new block that is the successor of the current one.
+func (b *builder) startBlock() *Block {
+	block := b.newBlock()
+	b.jump(block, Normal, nil)
+	b.current = block
+	return block
+}
+
+// deadBlock begins a new block that has no predecessors. It is used for code following a jump.
+func (b *builder) deadBlock() {
+	b.current = b.newBlock()
+}
+
+func (b *builder) block(block *ast.Block) {
+	b.scopes = append(b.scopes, &labelScope{labels: map[string]*Block{}})
+	b.statements(block)
+	b.closeScope()
+}
+
+// closeScope resolves pending gotos against the labels in the innermost scope. Gotos that remain unresolved are
+// passed to the enclosing scope.
+func (b *builder) closeScope() {
+	scope := b.scopes[len(b.scopes)-1]
+	b.scopes = b.scopes[:len(b.scopes)-1]
+	for _, pending := range scope.gotos {
+		var label *Block
+		if pending.stmt.Name != nil {
+			label = scope.labels[pending.stmt.Name.Token.Literal]
+		}
+		if label != nil {
+			b.edge(pending.from, label, Normal, nil)
+		} else if len(b.scopes) > 0 {
+			parent := b.scopes[len(b.scopes)-1]
+			parent.gotos = append(parent.gotos, pending)
+		} else {
+			b.g.UnresolvedGotos = append(b.g.UnresolvedGotos, pending.stmt)
+		}
+	}
+}
+
+func (b *builder) statements(block *ast.Block) {
+	for _, pair := range block.Pairs {
+		b.statement(pair.Node)
+	}
+}
+
+func (b *builder) statement(stmt ast.Statement) {
+	switch stmt.(type) {
+	case *ast.DoStatement, *ast.IfStatement, *ast.RepeatStatement, *ast.WhileStatement:
+		// Compound statements are not nodes of the graph, so they belong to the block that is current when they begin
+		b.g.blockOf[stmt] = b.current
+	}
+	switch stmt := stmt.(type) {
+	case *ast.BreakStatement:
+		b.add(stmt)
+		if len(b.loops) == 0 {
+			b.g.InvalidBreaks = append(b.g.InvalidBreaks, stmt)
+			b.jump(b.g.Exit, Abort, nil)
+		} else {
+			b.jump(b.loops[len(b.loops)-1], Normal, nil)
+		}
+		b.deadBlock()
+	case *ast.DoStatement:
+		b.block(&stmt.Body)
+	case *ast.ForInStatement:
+		b.loop(stmt, &stmt.Body)
+	case *ast.ForStatement:
+		b.loop(stmt, &stmt.Body)
+	case *ast.FunctionCall:
+		b.add(stmt)
+		if IsNoReturn(stmt) {
+			b.jump(b.g.Exit, Abort, nil)
+			b.deadBlock()
+		}
+	case *ast.GotoStatement:
+		b.add(stmt)
+		scope := b.scopes[len(b.scopes)-1]
+		scope.gotos = append(scope.gotos, pendingGoto{stmt, b.current})
+		b.deadBlock()
+	case *ast.IfStatement:
+		ends := []*Block{}
+		hasElse := false
+		for _, clause := range stmt.Clauses {
+			if clause.Condition == nil {
+				hasElse = true
+				b.block(&clause.Body)
+				ends = append(ends, b.current)
+				break
+			}
+			b.add(clause.Condition)
+			test := b.current
+			b.current = b.newBlock()
+			b.edge(test, b.current, True, clause.Condition)
+			b.block(&clause.Body)
+			ends = append(ends, b.current)
+			b.current = b.newBlock()
+			b.edge(test, b.current, False, clause.Condition)
+		}
+		if !hasElse {
+			ends = append(ends, b.current)
+		}
+		after := b.newBlock()
+		for _, end := range ends {
+			b.edge(end, after, Normal, nil)
+		}
+		b.current = after
+	case *ast.LabelStatement:
+		b.startBlock()
+		b.add(stmt)
+		if stmt.Name != nil {
+			scope := b.scopes[len(b.scopes)-1]
+			if scope.labels[stmt.Name.Token.Literal] == nil {
+				scope.labels[stmt.Name.Token.Literal] = b.current
+			}
+		}
+	case *ast.RepeatStatement:
+		body := b.startBlock()
+		after := b.newBlock()
+		b.loops = append(b.loops, after)
+		// The condition is part of the body's scope
+		b.scopes = append(b.scopes, &labelScope{labels: map[string]*Block{}})
+		b.statements(&stmt.Body)
+		b.add(stmt.Condition)
+		b.closeScope()
+		b.loops = b.loops[:len(b.loops)-1]
+		b.jump(after, True, stmt.Condition)
+		b.jump(body, False, stmt.Condition)
+		b.current = after
+	case *ast.ReturnStatement:
+		b.add(stmt)
+		b.jump(b.g.Exit, Return, nil)
+		b.deadBlock()
+	case *ast.WhileStatement:
+		header := b.startBlock()
+		b.add(stmt.Condition)
+		body := b.newBlock()
+		after := b.newBlock()
+		b.edge(header, body, True, stmt.Condition)
+		b.edge(header, after, False, stmt.Condition)
+		b.current = body
+		b.loops = append(b.loops, after)
+		b.block(&stmt.Body)
+		b.loops = b.loops[:len(b.loops)-1]
+		b.jump(header, Normal, nil)
+		b.current = after
+	default:
+		b.add(stmt)
+	}
+}
+
+// loop adds a numeric or generic for loop. The statement itself is placed in the loop header, which either enters
+// the body or exits the loop.
+func (b *builder) loop(stmt ast.Statement, body *ast.Block) {
+	header := b.startBlock()
+	b.add(stmt)
+	bodyBlock := b.newBlock()
+	after := b.newBlock()
+	b.edge(header, bodyBlock, Normal, nil)
+	b.edge(header, after, Normal, nil)
+	b.current = bodyBlock
+	b.loops = append(b.loops, after)
+	b.block(body)
+	b.loops = b.loops[:len(b.loops)-1]
+	b.jump(header, Normal, nil)
+	b.current = after
+}
@@ -0,0 +1,69 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func build(input string) (*Graph, *ast.Block) {
+	file := parser.New(input).ParseFile()
+	return New(file.Block), file.Block
+}
+
+func TestReachability(t *testing.T) {
+	tests := []struct {
+		label     string
+		input     string
+		reachable []bool // For each top-level statement
+	}{
+		{"straight", "local a = 1 local b = 2", []bool{true, true}},
+		{"return", "local a = 1 return a", []bool{true, true}},
+		{"error", "error('foo') local a = 1", []bool{true, false}},
+		{"if both return", "if a then return 1 else return 2 end local b", []bool{true, false}},
+		{"if one returns", "if a then return 1 end local b", []bool{true, true}},
+		{"infinite loop", "while true do end local b", []bool{true, true}},
+		{"goto", "goto skip local a = 1 ::skip:: local b = 2", []bool{true, false, true, true}},
+		{"compound", "if a then end error('foo') while a do end do end", []bool{true, true, false, false}},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			g, block := build(test.input)
+			for i, pair := range block.Pairs {
+				assert.Equal(t, test.reachable[i], g.IsReachable(pair.Node), "statement %d", i)
+			}
+		})
+	}
+}
+
+func TestFallsThrough(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"local a = 1", true},
+		{"return 1", false},
+		{"if a then return 1 end", true},
+		{"if a then return 1 else return 2 end", false},
+		{"if a then return 1 elseif b then return 2 end", true},
+		{"if a then return 1 else error('b') end", false},
+		{"while a do return 1 end", true},
+		{"repeat return 1 until a", false},
+		{"do return end", false},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			g, _ := build(test.input)
+			assert.Equal(t, test.expected, g.FallsThrough())
+		})
+	}
+}
+
+func TestInvalidJumps(t *testing.T) {
+	g, _ := build("break for i = 1, 2 do break end goto missing do goto outer end ::outer::")
+	assert.Len(t, g.InvalidBreaks, 1)
+	assert.Len(t, g.UnresolvedGotos, 1)
+	assert.Equal(t, "missing", g.UnresolvedGotos[0].Name.Token.Literal)
+}
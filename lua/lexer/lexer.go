@@ -18,6 +18,8 @@ type Lexer struct {
 	pos   int    // current position in the input.
 	width int    // width of last rune read.
 
+	annotation bool // whether to read the tokens that only appear in annotations, such as `|` and `?`.
+
 	lineBreaks []int
 }
 
@@ -25,6 +27,13 @@ func New(input string) *Lexer {
 	return &Lexer{input: input, pos: 0, lineBreaks: []int{}}
 }
 
+// NewAnnotation returns a lexer for the text of an annotation, which has tokens that are invalid in Lua code.
+func NewAnnotation(input string) *Lexer {
+	l := New(input)
+	l.annotation = true
+	return l
+}
+
 func (l *Lexer) Next() token.Token {
 	l.ignore()
 
@@ -121,6 +130,14 @@ func (l *Lexer) Next() token.Token {
 		}
 	case ';':
 		tok = token.SEMICOLON
+	case '|':
+		if l.annotation {
+			tok = token.PIPE
+		}
+	case '?':
+		if l.annotation {
+			tok = token.QUESTION
+		}
 	case '\'', '"':
 		if l.readString(r) {
 			tok = token.STRING
@@ -159,7 +176,15 @@ func (l *Lexer) GetLineBreaks() []int {
 }
 
 func Run(input string) ([]token.Token, []int) {
-	l := New(input)
+	return New(input).run()
+}
+
+// RunAnnotation reads every token of the text of an annotation.
+func RunAnnotation(input string) ([]token.Token, []int) {
+	return NewAnnotation(input).run()
+}
+
+func (l *Lexer) run() ([]token.Token, []int) {
 	tokens := []token.Token{}
 	for {
 		tok := l.Next()
@@ -117,6 +117,23 @@ func TestLabel(t *testing.T) {
 	testLexer(t, input, tokens)
 }
 
+func TestAnnotationTokens(t *testing.T) {
+	input := "a|b?"
+	testLexer(t, input, []token.Token{
+		{Type: token.IDENT, Literal: "a", Pos: 0},
+		{Type: token.INVALID, Literal: "|", Pos: 1},
+		{Type: token.IDENT, Literal: "b", Pos: 2},
+		{Type: token.INVALID, Literal: "?", Pos: 3},
+		{Type: token.EOF, Literal: "", Pos: 4},
+	})
+	tokens, _ := RunAnnotation(input)
+	types := []string{}
+	for _, tok := range tokens {
+		types = append(types, tok.Type.String())
+	}
+	assert.Equal(t, []string{"identifier", "pipe", "identifier", "question", "eof"}, types)
+}
+
 func testLexer(t *testing.T, input string, tokens []token.Token) {
 	l := New(input)
 	for _, expected := range tokens {
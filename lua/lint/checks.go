@@ -0,0 +1,76 @@
+package lint
+
+import (
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/types"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// checks are the checks of the types package, by name. Each reports diagnostics with several codes.
+var checks = map[string]func(env *types.View, a *types.Analysis) []ast.Diagnostic{
+	"requires":  (*types.View).CheckRequires,
+	"globals":   (*types.View).CheckGlobals,
+	"unused":    (*types.View).CheckUnused,
+	"flow":      (*types.View).CheckFlow,
+	"shadowing": (*types.View).CheckShadowing,
+	"types":     (*types.View).CheckTypes,
+}
+
+// checkRule reports the diagnostics of a check that have its code. A check is run at most once for each file, and
+// its diagnostics are shared by the rules of its other codes. The checks cover the whole file at once, so there is
+// nothing to visit.
+type checkRule struct {
+	code     string
+	severity protocol.DiagnosticSeverity
+	check    string
+}
+
+func init() {
+	for _, rule := range []checkRule{
+		{types.CodeModuleNotFound, protocol.DiagnosticSeverityWarning, "requires"},
+		{types.CodeCircularRequire, protocol.DiagnosticSeverityWarning, "requires"},
+		{types.CodeUndefinedGlobal, protocol.DiagnosticSeverityWarning, "globals"},
+		{types.CodeNewGlobal, protocol.DiagnosticSeverityWarning, "globals"},
+		{types.CodeUnusedAssignment, protocol.DiagnosticSeverityHint, "unused"},
+		{types.CodeUnusedFunction, protocol.DiagnosticSeverityHint, "unused"},
+		{types.CodeUnusedLabel, protocol.DiagnosticSeverityHint, "unused"},
+		{types.CodeUnusedLocal, protocol.DiagnosticSeverityHint, "unused"},
+		{types.CodeUnusedParameter, protocol.DiagnosticSeverityHint, "unused"},
+		{types.CodeDuplicateLabel, protocol.DiagnosticSeverityError, "flow"},
+		{types.CodeGotoIntoScope, protocol.DiagnosticSeverityError, "flow"},
+		{types.CodeInvalidBreak, protocol.DiagnosticSeverityError, "flow"},
+		{types.CodeMissingReturn, protocol.DiagnosticSeverityWarning, "flow"},
+		{types.CodeUndefinedLabel, protocol.DiagnosticSeverityError, "flow"},
+		{types.CodeUnreachable, protocol.DiagnosticSeverityHint, "flow"},
+		{types.CodeRedefinedLocalFunction, protocol.DiagnosticSeverityWarning, "shadowing"},
+		{types.CodeShadowedLocal, protocol.DiagnosticSeverityWarning, "shadowing"},
+		{types.CodeArgumentCount, protocol.DiagnosticSeverityWarning, "types"},
+		{types.CodeArgumentType, protocol.DiagnosticSeverityWarning, "types"},
+		{types.CodeAssignmentType, protocol.DiagnosticSeverityWarning, "types"},
+		{types.CodeReturnCount, protocol.DiagnosticSeverityWarning, "types"},
+		{types.CodeReturnType, protocol.DiagnosticSeverityWarning, "types"},
+		{types.CodeUnknownField, protocol.DiagnosticSeverityWarning, "types"},
+	} {
+		Register(rule)
+	}
+}
+
+func (r checkRule) Name() string { return r.code }
+
+func (r checkRule) Severity() protocol.DiagnosticSeverity { return r.severity }
+
+func (checkRule) Visit(ctx *Context, node ast.Node) {}
+
+func (r checkRule) CheckFile(ctx *Context) {
+	diagnostics, ok := ctx.checks[r.check]
+	if !ok {
+		diagnostics = checks[r.check](ctx.Env, ctx.Analysis)
+		ctx.checks[r.check] = diagnostics
+	}
+	for _, diag := range diagnostics {
+		if diag.Code == r.code {
+			diag.Severity = ctx.severity
+			ctx.diagnostics = append(ctx.diagnostics, diag)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/stretchr/testify/assert"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestCheckRules(t *testing.T) {
+	expectRule(t, "undefined-global", `
+print(undefined) -- want: Undefined global 'undefined'
+local x = 1 -- the unused local is reported by another rule
+`)
+	expectRule(t, "unused-local", `
+local x = 1 -- want: Unused local 'x'
+local function f() end -- reported as an unused function instead
+`)
+	expectRule(t, "invalid-break", `
+break -- want: 'break' outside of a loop
+while c do break end
+`)
+	expectRule(t, "argument-count", `
+---@param a number
+local function f(a) return a end
+f(1, 2) -- want: Expected 1 argument but got 2
+`)
+}
+
+func TestCheckRuleSeverities(t *testing.T) {
+	env, a := analyze(t, "local x = 1\nbreak")
+	env.Severities = map[string]protocol.DiagnosticSeverity{"unused-local": protocol.DiagnosticSeverityWarning}
+	linter := Linter{Only: []string{"unused-local", "invalid-break"}}
+	diagnostics := linter.LintAll(env, []*types.Analysis{a})[0]
+	if assert.Len(t, diagnostics, 2) {
+		assert.Equal(t, protocol.DiagnosticSeverityWarning, diagnostics[0].Severity)
+		assert.Equal(t, protocol.DiagnosticSeverityError, diagnostics[1].Severity)
+		assert.NotEmpty(t, diagnostics[0].Tags)
+	}
+}
@@ -0,0 +1,51 @@
+package lint
+
+import (
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/types"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// duplicateKey reports keys that are assigned more than once in a table literal, including constant keys that
+// collide with the array part of the table.
+type duplicateKey struct{}
+
+func init() {
+	Register(duplicateKey{})
+}
+
+func (duplicateKey) Name() string { return "duplicate-key" }
+
+func (duplicateKey) Severity() protocol.DiagnosticSeverity { return protocol.DiagnosticSeverityWarning }
+
+func (duplicateKey) Visit(ctx *Context, node ast.Node) {
+	table, ok := node.(*ast.TableLiteral)
+	if !ok {
+		return
+	}
+	seen := map[string]bool{}
+	index := 1
+	for _, pair := range table.Fields.Pairs {
+		var name string
+		var key ast.Node
+		switch field := pair.Node.(type) {
+		case *ast.TableArrayField:
+			name, key = types.ArrayFieldName(index), field
+			index++
+		case *ast.TableExpressionKeyField:
+			constant, ok := types.ConstantKey(field.Name)
+			if !ok {
+				continue
+			}
+			name, key = constant, field.Name
+		case *ast.TableSimpleKeyField:
+			name, key = field.Name.Token.Literal, &field.Name
+		default:
+			continue
+		}
+		if seen[name] {
+			ctx.Report(ast.Range(key), "Duplicate key '%s' in table", name)
+		}
+		seen[name] = true
+	}
+}
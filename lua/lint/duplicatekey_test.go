@@ -0,0 +1,20 @@
+package lint
+
+import "testing"
+
+func TestDuplicateKey(t *testing.T) {
+	expectRule(t, "duplicate-key", `
+return {
+	a = 1,
+	b = 2,
+	a = 3, -- want: Duplicate key 'a' in table
+	["a"] = 4, -- want: Duplicate key 'a' in table
+	5,
+	[1] = 6, -- want: Duplicate key '[1]' in table
+	[2] = 7,
+	[k] = 8,
+	[k] = 9,
+	nested = { a = 1, b = 2 },
+}
+`)
+}
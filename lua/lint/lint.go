@@ -0,0 +1,188 @@
+// Package lint runs configurable rules over analyzed Lua files.
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Rule is a single check. Its name is used as the code of the diagnostics it reports, and as its key in the
+// configuration. Rules must not modify the environment or keep state between files, so that LintAll can visit the
+// nodes of several files in parallel.
+type Rule interface {
+	Name() string
+	Severity() protocol.DiagnosticSeverity // The severity used when the configuration does not override it
+	Visit(ctx *Context, node ast.Node)     // Called for every semantic node of the file, in order
+}
+
+// FileRule is a rule that also checks the whole file at once, before its nodes are visited. CheckFile may use the
+// checks of types.View, which resolve types and parse files on demand, so it is never called for two files at once.
+// The built-in checks of the types package are file rules: they infer the types of other files and resolve modules
+// as they go, which modifies the environment, so only the rules that merely visit nodes are run in parallel.
+type FileRule interface {
+	Rule
+	CheckFile(ctx *Context)
+}
+
+// Context is the file that a rule is checking, along with its type and scope information.
+type Context struct {
+	Env      *types.View
+	Analysis *types.Analysis
+	File     *ast.File
+
+	rule        Rule
+	severity    protocol.DiagnosticSeverity
+	diagnostics []ast.Diagnostic
+	checks      map[string][]ast.Diagnostic // Check -> the diagnostics it reported, shared by the rules of the file
+}
+
+// Report adds a diagnostic for the rule that is being run.
+func (c *Context) Report(rng token.Range, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, ast.Diagnostic{
+		Message:  fmt.Sprintf(format, args...),
+		Range:    rng,
+		Severity: c.severity,
+		Code:     c.rule.Name(),
+	})
+}
+
+var (
+	registry   = map[string]Rule{}
+	registryMu sync.Mutex
+)
+
+// Register adds the rule to the registry. It panics if a rule with the same name is already registered.
+func Register(rule Rule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[rule.Name()]; exists {
+		panic(fmt.Sprintf("lint rule '%s' is already registered", rule.Name()))
+	}
+	registry[rule.Name()] = rule
+}
+
+// Rules returns every registered rule, sorted by name.
+func Rules() []Rule {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	rules := make([]Rule, 0, len(registry))
+	for _, rule := range registry {
+		rules = append(rules, rule)
+	}
+	slices.SortFunc(rules, func(a, b Rule) int { return strings.Compare(a.Name(), b.Name()) })
+	return rules
+}
+
+// Lookup returns the registered rule with the given name, or nil if there is none.
+func Lookup(name string) Rule {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return registry[name]
+}
+
+// Linter runs the registered rules with the severities configured in the environment. It implements types.Linter.
+type Linter struct {
+	Only []string // If not empty, the names of the only rules to run
+}
+
+// enabled returns the rules that should be run, along with the severity of each.
+func (l *Linter) enabled(env *types.View) ([]Rule, []protocol.DiagnosticSeverity) {
+	rules := []Rule{}
+	severities := []protocol.DiagnosticSeverity{}
+	for _, rule := range Rules() {
+		if len(l.Only) > 0 && !slices.Contains(l.Only, rule.Name()) {
+			continue
+		}
+		severity := rule.Severity()
+		if override, ok := env.Severity(rule.Name()); ok {
+			severity = override
+		}
+		if severity == types.SeverityOff {
+			continue
+		}
+		rules = append(rules, rule)
+		severities = append(severities, severity)
+	}
+	return rules, severities
+}
+
+// contexts returns a context for every enabled rule, or nil if the file should not be linted.
+func (l *Linter) contexts(env *types.View, a *types.Analysis) []*Context {
+	if env.IsLibrary(a.File.URI) || a.File.Block == nil {
+		return nil
+	}
+	rules, severities := l.enabled(env)
+	checks := map[string][]ast.Diagnostic{}
+	contexts := make([]*Context, len(rules))
+	for i, rule := range rules {
+		contexts[i] = &Context{Env: env, Analysis: a, File: a.File, rule: rule, severity: severities[i], checks: checks}
+	}
+	return contexts
+}
+
+// checkFile runs the file rules of the contexts.
+func checkFile(contexts []*Context) {
+	for _, ctx := range contexts {
+		if rule, ok := ctx.rule.(FileRule); ok {
+			rule.CheckFile(ctx)
+		}
+	}
+}
+
+// visit walks the syntax tree of the file once, passing every node to the rules of the contexts, and returns the
+// diagnostics of every rule.
+func visit(a *types.Analysis, contexts []*Context) []ast.Diagnostic {
+	if contexts == nil {
+		return nil
+	}
+	ast.WalkSemantic(a.File.Block, func(node ast.Node) bool {
+		for _, ctx := range contexts {
+			ctx.rule.Visit(ctx, node)
+		}
+		return true
+	})
+	diagnostics := []ast.Diagnostic{}
+	for _, ctx := range contexts {
+		diagnostics = append(diagnostics, ctx.diagnostics...)
+	}
+	slices.SortStableFunc(diagnostics, func(a, b ast.Diagnostic) int { return int(a.Range.Start - b.Range.Start) })
+	return diagnostics
+}
+
+// Lint runs every enabled rule over the file, walking its syntax tree once.
+func (l *Linter) Lint(env *types.View, a *types.Analysis) []ast.Diagnostic {
+	contexts := l.contexts(env, a)
+	checkFile(contexts)
+	return visit(a, contexts)
+}
+
+// LintAll lints the files and returns the diagnostics of each, in the same order. The files must already be
+// analyzed. File rules, which include every built-in check, are run one file at a time, and then the nodes of the
+// files are visited in parallel. The environment is locked until every file has been linted.
+func (l *Linter) LintAll(env *types.Environment, analyses []*types.Analysis) [][]ast.Diagnostic {
+	results := make([][]ast.Diagnostic, len(analyses))
+	env.View(func(v *types.View) {
+		contexts := make([][]*Context, len(analyses))
+		for i, a := range analyses {
+			contexts[i] = l.contexts(v, a)
+			checkFile(contexts[i])
+		}
+		var wg sync.WaitGroup
+		for i, a := range analyses {
+			wg.Add(1)
+			go func(i int, a *types.Analysis) {
+				defer wg.Done()
+				results[i] = visit(a, contexts[i])
+			}(i, a)
+		}
+		wg.Wait()
+	})
+	return results
+}
@@ -0,0 +1,115 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// analyze adds the source to a new environment as a single file and analyzes it.
+func analyze(t *testing.T, src string) (*types.Environment, *types.Analysis) {
+	env := types.NewEnvironment()
+	file := env.AddTransientFile("file:///test.lua", src)
+	require.NotNil(t, file)
+	env.CheckPhase1()
+	env.CheckPhase2()
+	return env, env.Analysis(file)
+}
+
+// expectations returns the diagnostics that the file expects through `-- want: <message>` comments, formatted as
+// `<line>: <message>`. Each comment expects a diagnostic that starts on its own line.
+func expectations(file *ast.File) []string {
+	expected := []string{}
+	for _, comment := range file.Comments {
+		message, ok := strings.CutPrefix(comment.Literal, "-- want: ")
+		if !ok {
+			continue
+		}
+		line := file.LineBreaks.ToProtocolPos(comment.Pos).Line + 1
+		expected = append(expected, fmt.Sprintf("%d: %s", line, strings.TrimSpace(message)))
+	}
+	sort.Strings(expected)
+	return expected
+}
+
+// expectRule runs a single rule over the source and compares its diagnostics to the expectation comments.
+func expectRule(t *testing.T, rule string, src string) {
+	env, a := analyze(t, src)
+	linter := Linter{Only: []string{rule}}
+	actual := []string{}
+	for _, diag := range linter.LintAll(env, []*types.Analysis{a})[0] {
+		assert.Equal(t, rule, diag.Code)
+		line := a.File.LineBreaks.ToProtocolPos(diag.Range.Start).Line + 1
+		actual = append(actual, fmt.Sprintf("%d: %s", line, diag.Message))
+	}
+	sort.Strings(actual)
+	assert.Equal(t, expectations(a.File), actual)
+}
+
+func TestRegistry(t *testing.T) {
+	names := []string{}
+	for _, rule := range Rules() {
+		names = append(names, rule.Name())
+		assert.Equal(t, rule, Lookup(rule.Name()))
+	}
+	assert.Contains(t, names, "duplicate-key")
+	assert.True(t, sort.StringsAreSorted(names))
+	assert.Nil(t, Lookup("no-such-rule"))
+	assert.Panics(t, func() { Register(duplicateKey{}) })
+}
+
+func TestSeverities(t *testing.T) {
+	src := "local table = {}\nreturn { a = 1, a = 2, table }"
+	env, a := analyze(t, src)
+	env.Severities = map[string]protocol.DiagnosticSeverity{
+		"duplicate-key":   protocol.DiagnosticSeverityError,
+		"shadowed-global": types.SeverityOff,
+	}
+
+	linter := Linter{Only: []string{"duplicate-key", "shadowed-global"}}
+	diagnostics := linter.LintAll(env, []*types.Analysis{a})[0]
+	if assert.Len(t, diagnostics, 1) {
+		assert.Equal(t, "duplicate-key", diagnostics[0].Code)
+		assert.Equal(t, protocol.DiagnosticSeverityError, diagnostics[0].Severity)
+	}
+}
+
+func TestLintAll(t *testing.T) {
+	env := types.NewEnvironment()
+	sources := []string{"return { a = 1, a = 2 }", "return { 1, [1] = 2 }", "return {}"}
+	files := []*ast.File{}
+	for i, src := range sources {
+		files = append(files, env.AddTransientFile(protocol.URI(fmt.Sprintf("file:///%d.lua", i)), src))
+	}
+	env.CheckPhase1()
+	env.CheckPhase2()
+	analyses := []*types.Analysis{}
+	for _, file := range files {
+		analyses = append(analyses, env.Analysis(file))
+	}
+	linter := Linter{Only: []string{"duplicate-key"}}
+	results := linter.LintAll(env, analyses)
+	assert.Len(t, results[0], 1)
+	assert.Len(t, results[1], 1)
+	assert.Len(t, results[2], 0)
+}
+
+func TestEnvironmentLinter(t *testing.T) {
+	env := types.NewEnvironment()
+	env.Linter = &Linter{}
+	file := env.AddTransientFile("file:///test.lua", "return { a = 1, a = 2 }")
+	env.CheckPhase1()
+	env.CheckPhase2()
+	codes := []string{}
+	for _, diag := range env.Diagnostics(file) {
+		codes = append(codes, diag.Code)
+	}
+	assert.Contains(t, codes, "duplicate-key")
+}
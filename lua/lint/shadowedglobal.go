@@ -0,0 +1,38 @@
+package lint
+
+import (
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/types"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// shadowedGlobal reports locals that hide a global from the standard library, such as `table` or `string`.
+type shadowedGlobal struct{}
+
+func init() {
+	Register(shadowedGlobal{})
+}
+
+func (shadowedGlobal) Name() string { return "shadowed-global" }
+
+func (shadowedGlobal) Severity() protocol.DiagnosticSeverity {
+	return protocol.DiagnosticSeverityWarning
+}
+
+func (shadowedGlobal) Visit(ctx *Context, node ast.Node) {
+	var ident *ast.Identifier
+	switch node := node.(type) {
+	case *ast.ForStatement:
+		// The name of a numeric for loop is not one of its semantic children
+		ident = node.Name
+	case *ast.Identifier:
+		ident = node
+	default:
+		return
+	}
+	v := ctx.Analysis.Bindings[ident]
+	if v == nil || v.Def != ident || v.Kind != types.KindLocal || !ctx.Env.IsStdlibGlobal(v.Name) {
+		return
+	}
+	ctx.Report(ast.Range(ident), "Local '%s' shadows a global from the standard library", v.Name)
+}
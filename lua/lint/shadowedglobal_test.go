@@ -0,0 +1,16 @@
+package lint
+
+import "testing"
+
+func TestShadowedGlobal(t *testing.T) {
+	expectRule(t, "shadowed-global", `
+local table = {} -- want: Local 'table' shadows a global from the standard library
+local print = print -- want: Local 'print' shadows a global from the standard library
+local function string() end -- want: Local 'string' shadows a global from the standard library
+for next = 1, 2 do end -- want: Local 'next' shadows a global from the standard library
+local function f(type) return type end
+local custom = 1
+table.x, custom = 1, 2
+return f, string
+`)
+}
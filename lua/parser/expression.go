@@ -52,7 +52,7 @@ func (p *Parser) parseExpression(precedence operatorPrecedence, allowCall bool)
 
 	for isInfixOperator(p.unit().Type()) {
 		tokPrecedence := p.tokPrecedence()
-		if isRightAssociative(p.unit().Type()) {
+		if IsRightAssociative(p.unit().Type()) {
 			tokPrecedence++
 		}
 		if precedence >= tokPrecedence {
@@ -291,7 +291,17 @@ func isInfixOperator(tok token.TokenType) bool {
 	return infixOperators[tok]
 }
 
-func isRightAssociative(tok token.TokenType) bool {
+// Precedence returns how tightly an operator binds its operands, where higher values bind more tightly. Unary
+// operators, including unary minus, have the same precedence.
+func Precedence(op token.TokenType, unary bool) int {
+	if unary {
+		return int(PREFIX)
+	}
+	return int(precedences[op])
+}
+
+// IsRightAssociative returns whether a chain of the binary operator groups from the right, as in `a ^ (b ^ c)`.
+func IsRightAssociative(tok token.TokenType) bool {
 	return tok == token.POW || tok == token.CONCAT
 }
 
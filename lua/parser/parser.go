@@ -19,6 +19,7 @@ import (
 
 type Parser struct {
 	errors     []ast.Diagnostic
+	input      string
 	lineBreaks []int
 	units      []ast.Unit
 	pos        int
@@ -28,6 +29,7 @@ func New(input string) *Parser {
 	units, lineBreaks := Run(input)
 	p := &Parser{
 		errors:     []ast.Diagnostic{},
+		input:      input,
 		lineBreaks: lineBreaks,
 		units:      units,
 	}
@@ -84,13 +86,36 @@ func (p *Parser) Errors() []ast.Diagnostic {
 }
 
 func (p *Parser) ParseFile() ast.File {
+	block := p.parseBlock()
 	return ast.File{
-		Block:       util.Ptr(p.parseBlock()),
+		Block:       &block,
+		Comments:    p.comments(),
 		Diagnostics: p.errors,
 		LineBreaks:  p.lineBreaks,
+		Source:      p.input,
 	}
 }
 
+// comments returns every comment in the source. Error recovery may move trivia to a later unit, so comments that
+// have already been seen are skipped.
+func (p *Parser) comments() []token.Token {
+	comments := []token.Token{}
+	last := token.InvalidPos
+	add := func(trivia []token.Token) {
+		for _, tok := range trivia {
+			if tok.Type == token.COMMENT && tok.Pos > last {
+				comments = append(comments, tok)
+				last = tok.Pos
+			}
+		}
+	}
+	for _, unit := range p.units {
+		add(unit.LeadingTrivia)
+		add(unit.TrailingTrivia)
+	}
+	return comments
+}
+
 func (p *Parser) unit() *ast.Unit {
 	return &p.units[p.pos]
 }
@@ -154,7 +179,9 @@ func (p *Parser) accept(tokenType token.TokenType) *ast.Unit {
 	if !p.tokIs(tokenType) {
 		return nil
 	}
-	return util.Ptr(p.next())
+	unit := *p.unit()
+	p.next()
+	return &unit
 }
 
 func (p *Parser) expect(tokenType token.TokenType) ast.Unit {
@@ -191,12 +218,17 @@ func (p *Parser) expect(tokenType token.TokenType) ast.Unit {
 				unit.LeadingTrivia = append(unit.LeadingTrivia, tok)
 			}
 		} else {
+			// The missing token belongs directly after the previous token
+			pos := p.unit().Pos()
+			if initialPos > 0 {
+				pos = p.units[initialPos-1].End()
+			}
 			fakeTok := ast.Unit{
 				LeadingTrivia: []token.Token{},
 				Token: token.Token{
 					Type:    tokenType,
 					Literal: "",
-					Pos:     initialPos,
+					Pos:     pos,
 				},
 				TrailingTrivia: []token.Token{},
 			}
@@ -204,6 +236,7 @@ func (p *Parser) expect(tokenType token.TokenType) ast.Unit {
 				Message:  fmt.Sprintf("Missing %s", token.TokenStr[tokenType]),
 				Range:    fakeTok.Range(),
 				Severity: protocol.DiagnosticSeverityError,
+				Fixes:    insertTokenFix(tokenType, pos),
 			})
 			p.next()
 			return fakeTok
@@ -213,6 +246,23 @@ func (p *Parser) expect(tokenType token.TokenType) ast.Unit {
 	return p.units[p.pos-1]
 }
 
+// insertTokenFix returns the fix that inserts the given token at the given position, if the token is always written
+// the same way.
+func insertTokenFix(tokenType token.TokenType, pos token.Pos) []ast.Fix {
+	text := tokenType.Text()
+	if text == "" {
+		return nil
+	}
+	newText := text
+	if _, ok := token.Reserved[text]; ok {
+		newText = " " + text
+	}
+	return []ast.Fix{{
+		Title: fmt.Sprintf("Insert '%s'", text),
+		Edits: []ast.Edit{{Range: token.Range{Start: pos, End: pos}, NewText: newText}},
+	}}
+}
+
 func (p *Parser) expectedTokenError(expected token.TokenType) {
 	p.addError(
 		fmt.Sprintf("Expected %s, got %s",
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/raiguard/luapls/lua/ast"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -55,3 +56,79 @@ func testSpec(t *testing.T, spec *TestSpec) {
 	}
 	assert.JSONEq(t, string(spec.Errors), string(errors))
 }
+
+func TestMissingTokenFix(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected string
+	}{
+		{"then", "if true print(1) end", "if true then print(1) end"},
+		{"end", "local function f() return 1", "local function f() return 1 end"},
+		{"paren", "print(1", "print(1)"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file := New(test.input).ParseFile()
+			require.NotEmpty(t, file.Diagnostics)
+			require.Len(t, file.Diagnostics[0].Fixes, 1)
+			output := test.input
+			edits := file.Diagnostics[0].Fixes[0].Edits
+			for i := len(edits) - 1; i >= 0; i-- {
+				output = output[:edits[i].Range.Start] + edits[i].NewText + output[edits[i].Range.End:]
+			}
+			assert.Equal(t, test.expected, output)
+		})
+	}
+}
+
+func TestStatementRange(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected string // The text of the range of the first statement
+	}{
+		{"function", "function f() end", "function f() end"},
+		{"local function", "local function f(a) return a end", "local function f(a) return a end"},
+		{"method", "function a.b:c() end", "function a.b:c() end"},
+		{"indented method", "\t  function a.b:c() end -- comment", "function a.b:c() end"},
+		{"documented function", "---@return number\nfunction a.b() return 1 end", "function a.b() return 1 end"},
+		{"if", "if a then x() end", "if a then x() end"},
+		{"if else", "if a then x() else y() end", "if a then x() else y() end"},
+		{"if elseif else", "if a then x() elseif b then y() elseif c then else z() end\nw()", "if a then x() elseif b then y() elseif c then else z() end"},
+		{"nested if", "if a then if b then end end", "if a then if b then end end"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file := New(test.input).ParseFile()
+			require.Empty(t, file.Diagnostics)
+			require.NotEmpty(t, file.Block.Pairs)
+			assert.Equal(t, test.expected, file.Text(ast.Range(file.Block.Pairs[0].Node)))
+		})
+	}
+}
+
+func TestSemanticNode(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string // The cursor is at the `|`
+		expected string // The text of the innermost node at the cursor
+	}{
+		{"elseif condition", "if a then elseif |b then else end", "b"},
+		{"else body", "if a then elseif b then else |z() end", "z"},
+		{"end of if", "if a then else z() |end", "if a then else z() end"},
+		{"end of nested if", "if a then if b then |end end", "if b then end"},
+		{"after if", "if a then end |x()", "x"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			pos := strings.Index(test.input, "|")
+			require.GreaterOrEqual(t, pos, 0)
+			file := New(test.input[:pos] + test.input[pos+1:]).ParseFile()
+			require.Empty(t, file.Diagnostics)
+			path := ast.GetSemanticNode(file.Block, pos)
+			require.NotNil(t, path.Node)
+			assert.Equal(t, test.expected, file.Text(ast.Range(path.Node)))
+		})
+	}
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"github.com/raiguard/luapls/lua/ast"
 	"github.com/raiguard/luapls/lua/token"
+	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
 func (p *Parser) parseTableFieldList() ast.Punctuated[ast.TableField] {
@@ -53,7 +54,7 @@ func (p *Parser) parseTableField() ast.TableField {
 
 	name, ok := expr.(*ast.Identifier)
 	if !ok {
-		panic("TODO: Table key was not an identifier!")
+		return p.parseUnwrappedKeyField(expr, assignTok)
 	}
 
 	expr = p.parseExpression(LOWEST, true)
@@ -64,3 +65,35 @@ func (p *Parser) parseTableField() ast.TableField {
 		Expr:      expr,
 	}
 }
+
+// parseUnwrappedKeyField recovers from a key that is an expression other than a name, but is not wrapped in brackets.
+// The field is parsed as if the brackets were there.
+func (p *Parser) parseUnwrappedKeyField(key ast.Expression, assignTok ast.Unit) *ast.TableExpressionKeyField {
+	bracket := func(tokenType token.TokenType, pos token.Pos) ast.Unit {
+		return ast.Unit{
+			LeadingTrivia:  []token.Token{},
+			Token:          token.Token{Type: tokenType, Pos: pos},
+			TrailingTrivia: []token.Token{},
+		}
+	}
+	rng := ast.Range(key)
+	p.errors = append(p.errors, ast.Diagnostic{
+		Message:  "Missing brackets around expression key",
+		Range:    rng,
+		Severity: protocol.DiagnosticSeverityError,
+		Fixes: []ast.Fix{{
+			Title: "Wrap the key in brackets",
+			Edits: []ast.Edit{
+				{Range: token.Range{Start: rng.Start, End: rng.Start}, NewText: "["},
+				{Range: token.Range{Start: rng.End, End: rng.End}, NewText: "]"},
+			},
+		}},
+	})
+	return &ast.TableExpressionKeyField{
+		LeftBracket:  bracket(token.LBRACK, rng.Start),
+		Name:         key,
+		RightBracket: bracket(token.RBRACK, rng.End),
+		AssignTok:    assignTok,
+		Expr:         p.parseExpression(LOWEST, true),
+	}
+}
@@ -0,0 +1,478 @@
+package refactor
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/parser"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// ExtractLocal returns actions that move the selected expression into a new local variable declared before the
+// statement that contains it. If the expression is pure and occurs more than once in the rest of the block, an
+// additional action replaces every occurrence that has the same value.
+func ExtractLocal(file *ast.File, a *types.Analysis, rng token.Range) []Action {
+	if file.Block == nil {
+		return nil
+	}
+	rng = trimRange(file, rng)
+	// Parentheses are not part of the range of the expression they surround
+	target, parents := selectedExpression(file, a, rng)
+	for inner := rng; target == nil && inner.End-inner.Start > 2 && file.Source[inner.Start] == '(' && file.Source[inner.End-1] == ')'; {
+		inner = trimRange(file, token.Range{Start: inner.Start + 1, End: inner.End - 1})
+		target, parents = selectedExpression(file, a, inner)
+	}
+	if target == nil {
+		return nil
+	}
+	text := file.Text(rng)
+	if !parses("return " + text) {
+		return nil
+	}
+	stmt, i, block := blockStatement(parents)
+	name := uniqueName(a, "extracted")
+	declaration := ast.Edit{
+		Range:   token.Range{Start: stmt.Pos(), End: stmt.Pos()},
+		NewText: "local " + name + " = " + text + "\n" + indentation(file, stmt.Pos()),
+	}
+	actions := []Action{{
+		Title: "Extract to local variable",
+		Kind:  protocol.CodeActionKindRefactorExtract,
+		Edits: []ast.Edit{declaration, {Range: rng, NewText: name}},
+	}}
+
+	// Replacing impure expressions would change how many times they are evaluated
+	if !isPure(target) {
+		return actions
+	}
+	occurrences := occurrencesOf(file, a, target, parents[:i-1], block, parents[i-1])
+	if len(occurrences) < 2 || !slices.Contains(occurrences, target) {
+		return actions
+	}
+	edits := []ast.Edit{declaration}
+	for _, occurrence := range occurrences {
+		occurrenceRange := ast.Range(occurrence)
+		if occurrence == target {
+			occurrenceRange = rng
+		}
+		edits = append(edits, ast.Edit{Range: occurrenceRange, NewText: name})
+	}
+	return append(actions, Action{
+		Title: fmt.Sprintf("Extract all %d occurrences to local variable", len(occurrences)),
+		Kind:  protocol.CodeActionKindRefactorExtract,
+		Edits: edits,
+	})
+}
+
+// selectedExpression returns the outermost expression that spans exactly the given range and can be replaced by a
+// variable, along with the nodes that contain it.
+func selectedExpression(file *ast.File, a *types.Analysis, rng token.Range) (ast.Expression, []ast.Node) {
+	var target ast.Expression
+	var targetParents []ast.Node
+	walkPaths(file.Block, nil, func(node ast.Node, parents []ast.Node) bool {
+		if target != nil || node.Pos() > rng.Start || node.End() < rng.End {
+			return false
+		}
+		expr, ok := node.(ast.Expression)
+		if ok && node.Pos() == rng.Start && node.End() == rng.End && extractable(a, expr, parents) {
+			target, targetParents = expr, parents
+			return false
+		}
+		return true
+	})
+	return target, targetParents
+}
+
+// extractable returns whether the expression at the end of the path is a value that can be computed before the
+// statement that contains it. This excludes names that are declared or assigned to, field names, method calls
+// without their arguments, calls that are statements of their own, loop conditions, which are evaluated more than
+// once, and expressions that are not always evaluated, such as later `elseif` conditions and the right operands of
+// `and` and `or`.
+func extractable(a *types.Analysis, expr ast.Expression, parents []ast.Node) bool {
+	switch parent := parents[len(parents)-1].(type) {
+	case *ast.Pair[ast.Statement], *ast.Pair[*ast.Identifier], *ast.GotoStatement:
+		return false
+	case *ast.IndexExpression:
+		if parent.Inner == expr && parent.LeftIndexer.Type() != token.LBRACK {
+			return false
+		}
+	case *ast.TableSimpleKeyField:
+		if &parent.Name == expr {
+			return false
+		}
+	case *ast.FunctionStatement:
+		if parent.Name == expr {
+			return false
+		}
+	}
+	switch expr := expr.(type) {
+	case *ast.IndexExpression:
+		if expr.LeftIndexer.Type() == token.COLON {
+			return false
+		}
+	case *ast.Identifier:
+		if v := a.Bindings[expr]; v != nil && v.Def == expr {
+			return false
+		}
+	}
+	stmt, i, _ := blockStatement(parents)
+	if shortCircuited(expr, parents[i+1:]) {
+		return false
+	}
+	switch stmt := stmt.(type) {
+	case nil, *ast.WhileStatement, *ast.RepeatStatement:
+		return false
+	case *ast.AssignmentStatement:
+		for _, pair := range stmt.Vars.Pairs {
+			if pair.Node == expr {
+				return false
+			}
+		}
+	case *ast.IfStatement:
+		if parents[i+1] != stmt.Clauses[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// shortCircuited returns whether the expression is within the right operand of an `and` or `or` expression in the
+// path, which is only evaluated depending on the left operand.
+func shortCircuited(expr ast.Expression, parents []ast.Node) bool {
+	var child ast.Node = expr
+	for i := len(parents) - 1; i >= 0; i-- {
+		if ie, ok := parents[i].(*ast.InfixExpression); ok && ie.Right == child {
+			switch ie.Operator.Type() {
+			case token.AND, token.OR:
+				return true
+			}
+		}
+		child = parents[i]
+	}
+	return false
+}
+
+// occurrencesOf returns the expressions that are identical to the target in the statements of the block from the one
+// that contains the target onwards. Since they are all replaced by a value that is computed before that statement, it
+// stops at the first statement that assigns to a variable that the target refers to, or to one of its fields, and
+// leaves out the occurrences that are evaluated after a call, which may change them as well. Occurrences in nested
+// functions are left out, as they are evaluated whenever the function is called.
+func occurrencesOf(file *ast.File, a *types.Analysis, target ast.Expression, blockParents []ast.Node, block *ast.Block, stmtPair ast.Node) []ast.Expression {
+	text := file.Text(ast.Range(target))
+	targetIdents := identifiers(target)
+	occurrences := []ast.Expression{}
+	// Evaluation is assumed to go from left to right, so the first call to return is the one that ends first
+	firstCall := token.InvalidPos
+	started := false
+	for i := range block.Pairs {
+		pair := &block.Pairs[i]
+		if pair == stmtPair {
+			started = true
+		} else if !started {
+			continue
+		} else if firstCall != token.InvalidPos || assignsTo(a, pair, targetIdents) {
+			break
+		}
+		candidates := []ast.Expression{}
+		walkPaths(pair, blockParents, func(node ast.Node, parents []ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.FunctionExpression, *ast.FunctionStatement:
+				return false
+			case *ast.FunctionCall:
+				if firstCall == token.InvalidPos || node.End() < firstCall {
+					firstCall = node.End()
+				}
+			}
+			expr, ok := node.(ast.Expression)
+			if !ok || file.Text(ast.Range(node)) != text {
+				return true
+			}
+			if !extractable(a, expr, parents) || !sameBindings(a, targetIdents, identifiers(expr)) {
+				return true
+			}
+			candidates = append(candidates, expr)
+			return false
+		})
+		for _, expr := range candidates {
+			if firstCall == token.InvalidPos || expr.Pos() < firstCall {
+				occurrences = append(occurrences, expr)
+			}
+		}
+	}
+	return occurrences
+}
+
+// sameBindings returns whether the identifiers of two expressions with the same text refer to the same variables.
+func sameBindings(a *types.Analysis, idents, others []*ast.Identifier) bool {
+	if len(idents) != len(others) {
+		return false
+	}
+	for i := range idents {
+		if a.Bindings[idents[i]] != a.Bindings[others[i]] {
+			return false
+		}
+	}
+	return true
+}
+
+// assignsTo returns whether the node assigns to a variable that one of the identifiers refers to, or to a field of
+// one.
+func assignsTo(a *types.Analysis, node ast.Node, idents []*ast.Identifier) bool {
+	found := false
+	walkPaths(node, nil, func(node ast.Node, parents []ast.Node) bool {
+		ident, ok := node.(*ast.Identifier)
+		if found || !ok || !isWrite(ident, parents) && !isFieldWrite(ident, parents) {
+			return !found
+		}
+		for _, other := range idents {
+			if a.Bindings[ident] == a.Bindings[other] && ident.Token.Literal == other.Token.Literal {
+				found = true
+			}
+		}
+		return false
+	})
+	return found
+}
+
+// isWrite returns whether the expression at the end of the path is assigned to.
+func isWrite(expr ast.Expression, parents []ast.Node) bool {
+	switch parent := parents[len(parents)-1].(type) {
+	case *ast.FunctionStatement:
+		return parent.Name == expr && parent.LocalTok == nil
+	case *ast.Pair[ast.Expression]:
+		if len(parents) < 3 {
+			return false
+		}
+		if stmt, ok := parents[len(parents)-3].(*ast.AssignmentStatement); ok {
+			for _, pair := range stmt.Vars.Pairs {
+				if pair.Node == expr {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isFieldWrite returns whether the identifier at the end of the path is the prefix of a field that is assigned to,
+// such as `t` in `t.x.y = 1` or `function t.f() end`.
+func isFieldWrite(ident *ast.Identifier, parents []ast.Node) bool {
+	var expr ast.Expression = ident
+	i := len(parents) - 1
+	for ; i > 0; i-- {
+		ie, ok := parents[i].(*ast.IndexExpression)
+		if !ok || ie.Prefix != expr {
+			break
+		}
+		expr = ie
+	}
+	return expr != ast.Expression(ident) && isWrite(expr, parents[:i+1])
+}
+
+// ExtractFunction returns an action that moves the selected statements into a new local function declared in their
+// place and calls it. Variables of the enclosing scopes that the statements use become parameters. The function
+// returns the locals that the statements declare and that are used afterwards, followed by the variables of the
+// enclosing scopes that the statements assign to.
+func ExtractFunction(file *ast.File, a *types.Analysis, rng token.Range) []Action {
+	if file.Block == nil {
+		return nil
+	}
+	rng = trimRange(file, rng)
+	block, first, last := selectedStatements(file, rng)
+	if block == nil {
+		return nil
+	}
+	stmts := block.Pairs[first : last+1]
+	for i := range stmts {
+		if escapes(&stmts[i], false) {
+			return nil
+		}
+	}
+	selection := token.Range{Start: stmts[0].Pos(), End: stmts[len(stmts)-1].End()}
+
+	params := []string{}
+	written := []string{}
+	seen := map[*types.Variable]bool{}
+	seenWritten := map[*types.Variable]bool{}
+	for i := range stmts {
+		walkPaths(&stmts[i], nil, func(node ast.Node, parents []ast.Node) bool {
+			ident, ok := node.(*ast.Identifier)
+			if !ok {
+				return true
+			}
+			v := a.Bindings[ident]
+			if v == nil || v.Def != nil && contains(selection, v.Def) {
+				return true
+			}
+			if !seen[v] {
+				seen[v] = true
+				params = append(params, v.Name)
+			}
+			if isWrite(ident, parents) && !seenWritten[v] {
+				seenWritten[v] = true
+				written = append(written, v.Name)
+			}
+			return true
+		})
+	}
+	declared := []string{}
+	for _, v := range a.Variables {
+		if v.Def == nil || !contains(selection, v.Def) || !isDeclaredBy(v, stmts) {
+			continue
+		}
+		for _, ref := range v.Refs {
+			if ref.Pos() >= selection.End {
+				declared = append(declared, v.Name)
+				break
+			}
+		}
+	}
+
+	name := uniqueName(a, "extracted_function")
+	indent := indentation(file, rng.Start)
+	innerIndent := indent + indentUnit(file)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "local function %s(%s)\n", name, strings.Join(params, ", "))
+	sb.WriteString(reindent(file, block, rng, indent, innerIndent))
+	returns := append(declared[:len(declared):len(declared)], written...)
+	if len(returns) > 0 {
+		sb.WriteString("\n" + innerIndent + "return " + strings.Join(returns, ", "))
+	}
+	sb.WriteString("\n" + indent + "end\n" + indent)
+	call := fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+	switch {
+	case len(declared) > 0 && len(written) == 0:
+		sb.WriteString("local " + strings.Join(declared, ", ") + " = " + call)
+	case len(declared) > 0:
+		sb.WriteString("local " + strings.Join(declared, ", ") + "\n" + indent + strings.Join(returns, ", ") + " = " + call)
+	case len(written) > 0:
+		sb.WriteString(strings.Join(written, ", ") + " = " + call)
+	default:
+		sb.WriteString(call)
+	}
+	if !parses(sb.String()) {
+		return nil
+	}
+	return []Action{{
+		Title: "Extract to function",
+		Kind:  protocol.CodeActionKindRefactorExtract,
+		Edits: []ast.Edit{{Range: rng, NewText: sb.String()}},
+	}}
+}
+
+// selectedStatements returns the outermost block that has statements which are entirely selected, along with the
+// indices of the first and last of them. Besides those statements, the range may only contain trivia.
+func selectedStatements(file *ast.File, rng token.Range) (block *ast.Block, first int, last int) {
+	walkPaths(file.Block, nil, func(node ast.Node, parents []ast.Node) bool {
+		if block != nil || node.End() <= rng.Start || node.Pos() >= rng.End {
+			return false
+		}
+		b, ok := node.(*ast.Block)
+		if !ok {
+			return true
+		}
+		first, last = -1, -1
+		for i := range b.Pairs {
+			pair := &b.Pairs[i]
+			switch {
+			case pair.End() <= rng.Start || pair.Pos() >= rng.End:
+			case contains(rng, pair):
+				if first < 0 {
+					first = i
+				}
+				last = i
+			default:
+				// Partially selected, so the selection may be within one of its blocks
+				return true
+			}
+		}
+		if first < 0 {
+			return false
+		}
+		before := token.Range{Start: rng.Start, End: b.Pairs[first].Pos()}
+		after := token.Range{Start: b.Pairs[last].End(), End: rng.End}
+		if onlyTrivia(file, before) && onlyTrivia(file, after) {
+			block = b
+		}
+		return false
+	})
+	return block, first, last
+}
+
+// escapes returns whether the node would behave differently inside of a function of its own, which is the case for
+// `return`, `goto`, labels, varargs, and `break` outside of a loop. Nested functions are not searched.
+func escapes(node ast.Node, inLoop bool) bool {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return false
+	}
+	switch node.(type) {
+	case *ast.FunctionExpression, *ast.FunctionStatement:
+		return false
+	case *ast.ReturnStatement, *ast.GotoStatement, *ast.LabelStatement, *ast.Vararg:
+		return true
+	case *ast.BreakStatement:
+		return !inLoop
+	case *ast.WhileStatement, *ast.RepeatStatement, *ast.ForStatement, *ast.ForInStatement:
+		inLoop = true
+	}
+	for _, child := range node.GetSemanticChildren() {
+		if escapes(child, inLoop) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeclaredBy returns whether the variable is declared by one of the statements, rather than within them.
+func isDeclaredBy(v *types.Variable, stmts []ast.Pair[ast.Statement]) bool {
+	for _, pair := range stmts {
+		if v.Decl == pair.Node {
+			return true
+		}
+	}
+	return false
+}
+
+// reindent returns the text of the range with the indentation of each line changed from one prefix to another. The
+// first line is assumed to start at its indentation. Lines inside of multi-line strings and comments are kept as
+// they are.
+func reindent(file *ast.File, block *ast.Block, rng token.Range, from, to string) string {
+	lines := strings.Split(file.Text(rng), "\n")
+	lineStart := rng.Start
+	for i, line := range lines {
+		pos := lineStart
+		lineStart += len(line) + 1
+		switch {
+		case i == 0:
+			lines[i] = to + line
+		case inToken(file, block, pos-1):
+		case strings.TrimSpace(line) == "":
+			lines[i] = ""
+		case strings.HasPrefix(line, from):
+			lines[i] = to + line[len(from):]
+		default:
+			lines[i] = to + strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inToken returns whether the position is inside of a comment or string literal.
+func inToken(file *ast.File, block *ast.Block, pos token.Pos) bool {
+	if commentAt(file, pos) != nil {
+		return true
+	}
+	_, ok := ast.GetSemanticNode(block, pos).Node.(*ast.StringLiteral)
+	return ok
+}
+
+// parses returns whether the code is free of syntax errors.
+func parses(code string) bool {
+	file := parser.New(code).ParseFile()
+	return len(file.Diagnostics) == 0
+}
@@ -0,0 +1,218 @@
+package refactor
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractLocal(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string // The selection is between `«` and `»`
+		expected []string
+	}{
+		{"infix", "local x = «a * b» + 2", []string{"local extracted = a * b\nlocal x = extracted + 2"}},
+		{"indented", "if c then\n\tprint(«f(1)»)\nend", []string{"if c then\n\tlocal extracted = f(1)\n\tprint(extracted)\nend"}},
+		{"parenthesized", "local x = «(a + b)» * 2", []string{"local extracted = (a + b)\nlocal x = extracted * 2"}},
+		{"trimmed", "local x = « 42 »", []string{"local extracted = 42\nlocal x =  extracted "}},
+		{"unique name", "local extracted = 1\nprint(«2»)", []string{"local extracted = 1\nlocal extracted2 = 2\nprint(extracted2)"}},
+		{"first if condition", "if «a.b» then end", []string{"local extracted = a.b\nif extracted then end"}},
+		{"comment kept", "-- Prints it\nprint(«a.b»)", []string{"-- Prints it\nlocal extracted = a.b\nprint(extracted)"}},
+		{
+			"all occurrences",
+			"local y = «t.x» + 1\nprint(t.x)",
+			[]string{
+				"local extracted = t.x\nlocal y = extracted + 1\nprint(t.x)",
+				"local extracted = t.x\nlocal y = extracted + 1\nprint(extracted)",
+			},
+		},
+		{
+			"occurrences before assignment",
+			"local t = {}\nlocal a = «t.x»\nlocal b = t.x\nt = {}\nlocal c = t.x",
+			[]string{
+				"local t = {}\nlocal extracted = t.x\nlocal a = extracted\nlocal b = t.x\nt = {}\nlocal c = t.x",
+				"local t = {}\nlocal extracted = t.x\nlocal a = extracted\nlocal b = extracted\nt = {}\nlocal c = t.x",
+			},
+		},
+		{
+			"occurrences before field assignment",
+			"local y = «t.x» + 1\nlocal z = t.x\nt.x = 5\nlocal w = t.x",
+			[]string{
+				"local extracted = t.x\nlocal y = extracted + 1\nlocal z = t.x\nt.x = 5\nlocal w = t.x",
+				"local extracted = t.x\nlocal y = extracted + 1\nlocal z = extracted\nt.x = 5\nlocal w = t.x",
+			},
+		},
+		{
+			"occurrences before call",
+			"print(«t.x», t.x, g(), t.x)\nprint(t.x)",
+			[]string{
+				"local extracted = t.x\nprint(extracted, t.x, g(), t.x)\nprint(t.x)",
+				"local extracted = t.x\nprint(extracted, extracted, g(), t.x)\nprint(t.x)",
+			},
+		},
+		{"call occurrences", "local a = «f()»\nlocal b = f()", []string{"local extracted = f()\nlocal a = extracted\nlocal b = f()"}},
+		{"field assigned", "local y = «t.x» + 1\nt.x = 5\nprint(t.x)", []string{"local extracted = t.x\nlocal y = extracted + 1\nt.x = 5\nprint(t.x)"}},
+		{"method defined", "local y = «t.f»\nfunction t.f() end\nprint(t.f)", []string{"local extracted = t.f\nlocal y = extracted\nfunction t.f() end\nprint(t.f)"}},
+		{"after call", "local y = «t.x» + 1\ng()\nprint(t.x)", []string{"local extracted = t.x\nlocal y = extracted + 1\ng()\nprint(t.x)"}},
+		{"in function", "local y = «t.x»\nlocal f = function() return t.x end", []string{"local extracted = t.x\nlocal y = extracted\nlocal f = function() return t.x end"}},
+		{
+			"different variables",
+			"local x = 1\nprint(«x + 1»)\ndo local x = 2 print(x + 1) end",
+			[]string{"local x = 1\nlocal extracted = x + 1\nprint(extracted)\ndo local x = 2 print(x + 1) end"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, rng := selection(t, test.input)
+			actions := ExtractLocal(file, a, rng)
+			require.Len(t, actions, len(test.expected))
+			for i, action := range actions {
+				assert.Equal(t, test.expected[i], apply(file.Source, action.Edits))
+			}
+		})
+	}
+}
+
+func TestExtractLocalNothing(t *testing.T) {
+	tests := []struct {
+		label string
+		input string
+	}{
+		{"call statement", "«f()»"},
+		{"declaration", "local «x» = 1"},
+		{"assignment target", "«t.x» = 1"},
+		{"field name", "print(t.«x»)"},
+		{"method", "«t:m»()"},
+		{"table key", "print({«x» = 1})"},
+		{"while condition", "while «a.b» do end"},
+		{"repeat condition", "repeat until «a.b»"},
+		{"elseif condition", "if a then elseif «a.b» then end"},
+		{"and operand", "local v = ok and «compute()»"},
+		{"or operand", "local v = ok or «t.x.y»"},
+		{"within or operand", "local v = ok or f(«g()»)"},
+		{"partial", "local x = «a +» b"},
+		{"not an expression", "local x = «1, 2»"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, rng := selection(t, test.input)
+			assert.Empty(t, ExtractLocal(file, a, rng))
+		})
+	}
+}
+
+func TestExtractFunction(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected string
+	}{
+		{
+			"parameters",
+			"local a = 1\n«print(a)\nprint(a + b)»\n",
+			"local a = 1\nlocal function extracted_function(a)\n\tprint(a)\n\tprint(a + b)\nend\nextracted_function(a)\n",
+		},
+		{
+			"declared locals",
+			"«local x = 1\nlocal y = 2»\nprint(x)",
+			"local function extracted_function()\n\tlocal x = 1\n\tlocal y = 2\n\treturn x\nend\nlocal x = extracted_function()\nprint(x)",
+		},
+		{
+			"assigned locals",
+			"local n = 0\n«n = n + 1»\nprint(n)",
+			"local n = 0\nlocal function extracted_function(n)\n\tn = n + 1\n\treturn n\nend\nn = extracted_function(n)\nprint(n)",
+		},
+		{
+			"declared and assigned locals",
+			"local n = 0\n«n = 1\nlocal m = 2»\nprint(n, m)",
+			"local n = 0\nlocal function extracted_function(n)\n\tn = 1\n\tlocal m = 2\n\treturn m, n\nend\nlocal m\nm, n = extracted_function(n)\nprint(n, m)",
+		},
+		{
+			"nested",
+			"function f()\n\t«-- Greet\n\tprint([[a\nb]])\n\tif x then\n\t\tprint(1)\n\tend»\nend",
+			"function f()\n\tlocal function extracted_function()\n\t\t-- Greet\n\t\tprint([[a\nb]])\n\t\tif x then\n\t\t\tprint(1)\n\t\tend\n\tend\n\textracted_function()\nend",
+		},
+		{
+			"spaces",
+			"do\n  «print(1)»\nend",
+			"do\n  local function extracted_function()\n    print(1)\n  end\n  extracted_function()\nend",
+		},
+		{
+			"loop",
+			"«for i = 1, 10 do\n\tif i > 5 then break end\nend»",
+			"local function extracted_function()\n\tfor i = 1, 10 do\n\t\tif i > 5 then break end\n\tend\nend\nextracted_function()",
+		},
+		{
+			"nested function",
+			"«local f = function(...) return ... end»",
+			"local function extracted_function()\n\tlocal f = function(...) return ... end\nend\nextracted_function()",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, rng := selection(t, test.input)
+			actions := ExtractFunction(file, a, rng)
+			require.Len(t, actions, 1)
+			assert.Equal(t, test.expected, apply(file.Source, actions[0].Edits))
+		})
+	}
+}
+
+func TestExtractFunctionNothing(t *testing.T) {
+	tests := []struct {
+		label string
+		input string
+	}{
+		{"return", "local function f()\n\t«print(1)\n\treturn 1»\nend"},
+		{"break", "while true do\n\t«break»\nend"},
+		{"goto", "«goto done»\n::done::"},
+		{"vararg", "«print(...)»"},
+		{"partial", "«local x» = 1"},
+		{"keyword", "«do\n\tprint(1)»\nend"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, rng := selection(t, test.input)
+			assert.Empty(t, ExtractFunction(file, a, rng))
+		})
+	}
+}
+
+// selection analyzes the input with the `«` and `»` that mark the selection removed, and returns the selected range.
+func selection(t *testing.T, input string) (*ast.File, *types.Analysis, token.Range) {
+	start := strings.Index(input, "«")
+	require.GreaterOrEqual(t, start, 0)
+	input = strings.Replace(input, "«", "", 1)
+	end := strings.Index(input, "»")
+	require.GreaterOrEqual(t, end, 0)
+	input = strings.Replace(input, "»", "", 1)
+	file, a := analyze(t, input)
+	return file, a, token.Range{Start: start, End: end}
+}
+
+func analyze(t *testing.T, input string) (*ast.File, *types.Analysis) {
+	env := types.NewEnvironment()
+	file := env.AddTransientFile("file:///test.lua", input)
+	require.NotNil(t, file)
+	env.CheckPhase1()
+	env.CheckPhase2()
+	return file, env.Analysis(file)
+}
+
+// apply returns the source with the edits applied. Edits that start at the same position are applied in order.
+func apply(source string, edits []ast.Edit) string {
+	edits = append([]ast.Edit{}, edits...)
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Range.Start < edits[j].Range.Start })
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		source = source[:edit.Range.Start] + edit.NewText + source[edit.Range.End:]
+	}
+	return source
+}
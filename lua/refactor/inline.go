@@ -0,0 +1,582 @@
+package refactor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/parser"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// atomPrecedence is the precedence of expressions that are not operators, which never need parentheses as operands.
+const atomPrecedence = 100
+
+// InlineLocal returns an action that replaces every use of the local variable at the given position with its value
+// and removes its declaration. It is only offered when the variable is never reassigned and evaluating the value at
+// each use gives the same result as evaluating it once at the declaration, with the same effects in the same order.
+func InlineLocal(file *ast.File, a *types.Analysis, pos token.Pos) []Action {
+	v := variableAt(file, a, pos)
+	if v == nil || v.Kind != types.KindLocal || len(v.Refs) == 0 {
+		return nil
+	}
+	stmt, ok := v.Decl.(*ast.LocalStatement)
+	if !ok || len(stmt.Names.Pairs) != 1 || stmt.Exps == nil || len(stmt.Exps.Pairs) != 1 {
+		return nil
+	}
+	value := stmt.Exps.Pairs[0].Node
+	valueRange, ok := expressionRange(file, value)
+	if !ok || hasVararg(value) {
+		return nil
+	}
+	refPaths := make([][]ast.Node, len(v.Refs))
+	for i, ref := range v.Refs {
+		refPaths[i] = ast.GetSemanticNode(file.Block, ref.Pos()).Parents
+		if isWrite(ref, refPaths[i]) {
+			return nil
+		}
+	}
+	switch {
+	case !isPure(value):
+		if !evaluatedOnceAfter(file, stmt, v.Refs, refPaths) {
+			return nil
+		}
+	case readsState(value):
+		for i, ref := range v.Refs {
+			if changedBefore(file, stmt.End(), ref, refPaths[i]) {
+				return nil
+			}
+		}
+	}
+	free := freeIdentifiers(a, value)
+	if reassignedAfter(file, a, free, stmt.End()) {
+		return nil
+	}
+	edits := []ast.Edit{}
+	for i, ref := range v.Refs {
+		if shadowed(a, free, stmt.End(), ref.Pos()) {
+			return nil
+		}
+		text := file.Text(valueRange)
+		edits = append(edits, ast.Edit{Range: ast.Range(ref), NewText: parenthesize(file, text, value, ref, refPaths[i])})
+	}
+	declaration := token.Range{Start: stmt.Pos(), End: valueRange.End}
+	for text := file.Text(declaration); strings.Count(text, "(") > strings.Count(text, ")"); text = file.Text(declaration) {
+		// The value is parenthesized
+		end := trimRange(file, token.Range{Start: declaration.End, End: len(file.Source)}).Start
+		if end == len(file.Source) || file.Source[end] != ')' {
+			return nil
+		}
+		declaration.End = end + 1
+	}
+	edits = append(edits, ast.Edit{Range: RemovalRange(file, declaration), NewText: ""})
+	return []Action{{
+		Title: fmt.Sprintf("Inline local '%s'", v.Name),
+		Kind:  protocol.CodeActionKindRefactorInline,
+		Edits: sortedEdits(edits),
+	}}
+}
+
+// InlineFunction returns an action that replaces every call of the local function at the given position with the
+// expression that it returns, and removes the function. It is only offered for functions whose body is a single
+// `return` of one value, and when substituting the arguments for the parameters evaluates each argument with side
+// effects exactly once, before anything that it could affect.
+func InlineFunction(file *ast.File, a *types.Analysis, pos token.Pos) []Action {
+	v := variableAt(file, a, pos)
+	if v == nil || len(v.Refs) == 0 {
+		return nil
+	}
+	fs, ok := v.Decl.(*ast.FunctionStatement)
+	if !ok || fs.LocalTok == nil || fs.Vararg != nil || len(fs.Body.Pairs) != 1 {
+		return nil
+	}
+	ret, ok := fs.Body.Pairs[0].Node.(*ast.ReturnStatement)
+	if !ok || ret.Exps == nil || len(ret.Exps.Pairs) != 1 {
+		return nil
+	}
+	result := ret.Exps.Pairs[0].Node
+	if _, ok := expressionRange(file, result); !ok || hasFunction(result) {
+		return nil
+	}
+	params := make([]*types.Variable, len(fs.Params.Pairs))
+	for i, pair := range fs.Params.Pairs {
+		params[i] = a.Bindings[pair.Node]
+	}
+	free := []*ast.Identifier{}
+	for _, ident := range freeIdentifiers(a, result) {
+		if a.Bindings[ident] == v {
+			// Recursive
+			return nil
+		}
+		if indexOf(params, a.Bindings[ident]) < 0 {
+			free = append(free, ident)
+		}
+	}
+
+	edits := []ast.Edit{}
+	for _, ref := range v.Refs {
+		path := ast.GetSemanticNode(file.Block, ref.Pos()).Parents
+		if len(path) < 2 {
+			return nil
+		}
+		call, ok := path[len(path)-1].(*ast.FunctionCall)
+		if !ok || call.Name != ref || shadowed(a, free, fs.End(), ref.Pos()) {
+			return nil
+		}
+		text, top, ok := inlineCall(file, a, call, result, params)
+		if !ok {
+			return nil
+		}
+		callPath := path[:len(path)-1]
+		if _, ok := callPath[len(callPath)-1].(*ast.Pair[ast.Statement]); ok {
+			// Only a call can replace a call statement
+			if _, ok := top.(*ast.FunctionCall); !ok {
+				return nil
+			}
+		}
+		callRange, ok := expressionRange(file, call)
+		if !ok {
+			return nil
+		}
+		edits = append(edits, ast.Edit{Range: callRange, NewText: parenthesize(file, text, top, call, callPath)})
+	}
+	edits = append(edits, ast.Edit{Range: RemovalRange(file, ast.Range(fs)), NewText: ""})
+	return []Action{{
+		Title: fmt.Sprintf("Inline function '%s'", v.Name),
+		Kind:  protocol.CodeActionKindRefactorInline,
+		Edits: sortedEdits(edits),
+	}}
+}
+
+// inlineCall returns the text of the result expression with the arguments of the call substituted for the
+// parameters, along with the expression that is at the top of the substituted text.
+func inlineCall(file *ast.File, a *types.Analysis, call *ast.FunctionCall, result ast.Expression, params []*types.Variable) (string, ast.Expression, bool) {
+	args := call.Args.Pairs
+	if len(args) > len(params) {
+		for _, arg := range args[len(params):] {
+			if !isPure(arg.Node) {
+				return "", nil, false
+			}
+		}
+	}
+	if len(args) > 0 && len(args) < len(params) && isMultiValue(args[len(args)-1].Node) {
+		// The last argument may provide the values of the remaining parameters
+		return "", nil, false
+	}
+	uses := make([]int, len(params))
+	type substitution struct {
+		ident   *ast.Identifier
+		parents []ast.Node
+		param   int
+	}
+	substitutions := []substitution{}
+	walkPaths(result, nil, func(node ast.Node, parents []ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			if i := indexOf(params, a.Bindings[ident]); i >= 0 {
+				uses[i]++
+				substitutions = append(substitutions, substitution{ident, parents, i})
+			}
+		}
+		return true
+	})
+	impure := -1
+	for i := range params {
+		if i < len(args) && !isPure(args[i].Node) {
+			if impure >= 0 || uses[i] != 1 {
+				// The arguments could be evaluated in a different order, or a different number of times
+				return "", nil, false
+			}
+			impure = i
+		}
+	}
+	if impure >= 0 {
+		// The impure argument is evaluated where its parameter is used instead of before the call, so it has to be
+		// evaluated unconditionally, and the values that are evaluated before it must not be affected by it
+		for i, arg := range args {
+			if i != impure && readsState(arg.Node) {
+				return "", nil, false
+			}
+		}
+		for _, sub := range substitutions {
+			if sub.param == impure && (shortCircuited(sub.ident, sub.parents) || evaluatedBefore(result, sub.ident)) {
+				return "", nil, false
+			}
+		}
+	}
+
+	resultRange, _ := expressionRange(file, result)
+	text := file.Text(resultRange)
+	top := result
+	for i := len(substitutions) - 1; i >= 0; i-- {
+		sub := substitutions[i]
+		argText, arg := "nil", ast.Expression(&ast.NilLiteral{})
+		if sub.param < len(args) {
+			arg = args[sub.param].Node
+			argRange, ok := expressionRange(file, arg)
+			if !ok {
+				return "", nil, false
+			}
+			argText = file.Text(argRange)
+		}
+		if sub.ident == result {
+			top = arg
+		}
+		replacement := parenthesize(file, argText, arg, sub.ident, sub.parents)
+		start, end := sub.ident.Pos()-resultRange.Start, sub.ident.End()-resultRange.Start
+		text = text[:start] + replacement + text[end:]
+	}
+	return text, top, true
+}
+
+// variableAt returns the local variable that the identifier at the given position refers to or declares.
+func variableAt(file *ast.File, a *types.Analysis, pos token.Pos) *types.Variable {
+	if file.Block == nil {
+		return nil
+	}
+	ident, ok := ast.GetSemanticNode(file.Block, pos).Node.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	return a.Bindings[ident]
+}
+
+// expressionRange returns the range of the source that contains the expression. This differs from the range of the
+// expression when it starts or ends with a parenthesized expression, since the parentheses are not part of the
+// range of the expression they surround.
+func expressionRange(file *ast.File, expr ast.Expression) (token.Range, bool) {
+	rng := ast.Range(expr)
+	for !parses("return " + file.Text(rng)) {
+		text := file.Text(rng)
+		opening, closing := strings.Count(text, "("), strings.Count(text, ")")
+		switch {
+		case closing > opening:
+			start := trimRange(file, token.Range{Start: 0, End: rng.Start}).End
+			if start == 0 || file.Source[start-1] != '(' {
+				return rng, false
+			}
+			rng.Start = start - 1
+		case opening > closing:
+			end := trimRange(file, token.Range{Start: rng.End, End: len(file.Source)}).Start
+			if end == len(file.Source) || file.Source[end] != ')' {
+				return rng, false
+			}
+			rng.End = end + 1
+		default:
+			return rng, false
+		}
+	}
+	return rng, true
+}
+
+// parenthesize returns the text of an expression that replaces the node at the end of the path, surrounded by
+// parentheses if they are needed to keep its meaning.
+func parenthesize(file *ast.File, text string, expr ast.Expression, target ast.Node, parents []ast.Node) string {
+	if needsParentheses(expr, target, parents) || strings.HasPrefix(text, "-") && strings.HasSuffix(file.Source[:target.Pos()], "-") {
+		return "(" + text + ")"
+	}
+	return text
+}
+
+// needsParentheses returns whether the expression must be parenthesized to replace the node at the end of the path.
+// This is the case when the expression binds less tightly than the operator it becomes an operand of, when it
+// becomes the prefix of a call or an index but is not a variable or a call, and when it may produce several values
+// at the end of a list that would otherwise receive only one.
+func needsParentheses(expr ast.Expression, target ast.Node, parents []ast.Node) bool {
+	if len(parents) == 0 {
+		return false
+	}
+	switch parent := parents[len(parents)-1].(type) {
+	case *ast.InfixExpression:
+		op := parent.Operator.Type()
+		prec, exprPrec := parser.Precedence(op, false), precedence(expr)
+		if exprPrec != prec {
+			return exprPrec < prec
+		}
+		return (parent.Right == target) != parser.IsRightAssociative(op)
+	case *ast.PrefixExpression:
+		_, isPrefix := expr.(*ast.PrefixExpression)
+		return isPrefix || precedence(expr) < parser.Precedence(parent.Operator.Type(), true)
+	case *ast.IndexExpression:
+		return parent.Prefix == target && !isPrefixExpression(expr)
+	case *ast.FunctionCall:
+		return parent.Name == target && !isPrefixExpression(expr)
+	case *ast.Pair[ast.Expression]:
+		if len(parents) >= 2 && isMultiValue(expr) {
+			list, ok := parents[len(parents)-2].(*ast.Punctuated[ast.Expression])
+			return ok && parent == &list.Pairs[len(list.Pairs)-1]
+		}
+	case *ast.TableArrayField:
+		if len(parents) >= 3 && isMultiValue(expr) {
+			fields, ok := parents[len(parents)-3].(*ast.Punctuated[ast.TableField])
+			return ok && parents[len(parents)-2] == &fields.Pairs[len(fields.Pairs)-1]
+		}
+	}
+	return false
+}
+
+// precedence returns how tightly the top of the expression binds its operands.
+func precedence(expr ast.Expression) int {
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(expr.Operator.Type(), false)
+	case *ast.PrefixExpression:
+		return parser.Precedence(expr.Operator.Type(), true)
+	}
+	return atomPrecedence
+}
+
+// isPrefixExpression returns whether the expression can be called or indexed without parentheses.
+func isPrefixExpression(expr ast.Expression) bool {
+	switch expr.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.FunctionCall:
+		return true
+	}
+	return false
+}
+
+// isMultiValue returns whether the expression may produce more than one value.
+func isMultiValue(expr ast.Expression) bool {
+	switch expr.(type) {
+	case *ast.FunctionCall, *ast.Vararg:
+		return true
+	}
+	return false
+}
+
+// isPure returns whether evaluating the expression more than once, or later than where it is written, gives the same
+// result, as long as the variables it reads are not reassigned. Calls may have side effects, and table constructors
+// and functions create a new value each time they are evaluated.
+func isPure(expr ast.Expression) bool {
+	pure := true
+	ast.WalkSemantic(expr, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.FunctionCall, *ast.TableLiteral, *ast.FunctionExpression, *ast.Vararg:
+			pure = false
+		}
+		return pure
+	})
+	return pure
+}
+
+// readsState returns whether evaluating the expression may give a different result after a call or an assignment to
+// a field, or may call a function itself. This is the case for index expressions and operators other than `and`,
+// `or` and `not`, which may read a field or call a metamethod.
+func readsState(expr ast.Expression) bool {
+	found := false
+	ast.WalkSemantic(expr, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.IndexExpression:
+			found = true
+		case *ast.InfixExpression:
+			found = node.Operator.Type() != token.AND && node.Operator.Type() != token.OR
+		case *ast.PrefixExpression:
+			found = node.Operator.Type() != token.NOT
+		case *ast.FunctionExpression:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// evaluatedBefore returns whether an expression within the root that is evaluated before the target is impure or
+// reads state, so that evaluating the target later than it would change the result of either. Evaluation is assumed
+// to go from left to right.
+func evaluatedBefore(root ast.Node, target ast.Node) bool {
+	found := false
+	ast.WalkSemantic(root, func(node ast.Node) bool {
+		switch {
+		case found || node.Pos() >= target.End():
+			return false
+		case node.End() <= target.Pos():
+			expr, ok := node.(ast.Expression)
+			if !ok {
+				return true
+			}
+			found = !isPure(expr) || readsState(expr)
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// changedBefore returns whether a call or an assignment to a field may happen after the given position and before
+// the reference at the end of the path is evaluated. A reference in a loop may be evaluated again after anything
+// else in the loop, and a reference in a function may be evaluated at any time.
+func changedBefore(file *ast.File, from token.Pos, ref *ast.Identifier, refPath []ast.Node) bool {
+	to := ref.Pos()
+	for _, node := range refPath {
+		if node.Pos() <= from {
+			continue
+		}
+		switch node.(type) {
+		case *ast.FunctionExpression, *ast.FunctionStatement:
+			return true
+		case *ast.WhileStatement, *ast.RepeatStatement, *ast.ForStatement, *ast.ForInStatement:
+			to = max(to, node.End())
+		}
+	}
+	changed := false
+	walkPaths(file.Block, nil, func(node ast.Node, parents []ast.Node) bool {
+		if changed || node.End() <= from || node.Pos() >= to {
+			return false
+		}
+		if node.Pos() < from || node.End() > to {
+			// Only part of it is evaluated in between
+			return true
+		}
+		switch node := node.(type) {
+		case *ast.FunctionCall:
+			changed = true
+		case *ast.IndexExpression:
+			changed = isWrite(node, parents)
+		case *ast.FunctionStatement:
+			_, changed = node.Name.(*ast.IndexExpression)
+			return false
+		case *ast.FunctionExpression:
+			return false
+		}
+		return !changed
+	})
+	return changed
+}
+
+func hasVararg(expr ast.Expression) bool {
+	found := false
+	ast.WalkSemantic(expr, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.Vararg:
+			found = true
+		case *ast.FunctionExpression:
+			// A function has its own varargs
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+func hasFunction(expr ast.Expression) bool {
+	found := false
+	ast.WalkSemantic(expr, func(node ast.Node) bool {
+		_, found = node.(*ast.FunctionExpression)
+		return !found
+	})
+	return found
+}
+
+// evaluatedOnceAfter returns whether there is a single reference, which is in the statement that follows the
+// declaration and is always evaluated exactly once by that statement, after nothing that is impure or reads state,
+// so that evaluating a value there instead of at the declaration has the same effects.
+func evaluatedOnceAfter(file *ast.File, decl ast.Statement, refs []*ast.Identifier, refPaths [][]ast.Node) bool {
+	if len(refs) != 1 {
+		return false
+	}
+	declPath := ast.GetSemanticNode(file.Block, decl.Pos())
+	_, i, block := blockStatement(append(declPath.Parents, declPath.Node))
+	if block == nil {
+		return false
+	}
+	next := len(block.Pairs)
+	for j := range block.Pairs {
+		if &block.Pairs[j] == declPath.Parents[i-1] {
+			next = j + 1
+		}
+	}
+	if next >= len(block.Pairs) {
+		return false
+	}
+	path := refPaths[0]
+	j := slices.Index(path, ast.Node(&block.Pairs[next]))
+	if j < 0 {
+		return false
+	}
+	for k, node := range path[j+1:] {
+		switch node := node.(type) {
+		case *ast.FunctionExpression, *ast.FunctionStatement, *ast.WhileStatement, *ast.RepeatStatement, *ast.ForStatement, *ast.ForInStatement, *ast.Block:
+			return false
+		case *ast.IfStatement:
+			if k+j+2 < len(path) && path[k+j+2] != node.Clauses[0] {
+				return false
+			}
+		}
+	}
+	return !shortCircuited(refs[0], path[j+1:]) && !evaluatedBefore(&block.Pairs[next], refs[0])
+}
+
+func indexOf(vars []*types.Variable, v *types.Variable) int {
+	for i := range vars {
+		if v != nil && vars[i] == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// freeIdentifiers returns the identifiers in the expression that refer to variables declared outside of it,
+// including globals.
+func freeIdentifiers(a *types.Analysis, expr ast.Expression) []*ast.Identifier {
+	rng := ast.Range(expr)
+	globals := map[*ast.Identifier]bool{}
+	for _, ident := range a.Globals {
+		globals[ident] = true
+	}
+	free := []*ast.Identifier{}
+	for _, ident := range identifiers(expr) {
+		v := a.Bindings[ident]
+		if v == nil && globals[ident] || v != nil && (v.Def == nil || !contains(rng, v.Def)) {
+			free = append(free, ident)
+		}
+	}
+	return free
+}
+
+// reassignedAfter returns whether a variable that one of the identifiers refers to is assigned to after the given
+// position.
+func reassignedAfter(file *ast.File, a *types.Analysis, idents []*ast.Identifier, pos token.Pos) bool {
+	for _, ident := range idents {
+		refs := a.Globals
+		if v := a.Bindings[ident]; v != nil {
+			refs = v.Refs
+		}
+		for _, ref := range refs {
+			if ref.Pos() < pos || ref.Token.Literal != ident.Token.Literal {
+				continue
+			}
+			if isWrite(ref, ast.GetSemanticNode(file.Block, ref.Pos()).Parents) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shadowed returns whether one of the identifiers would refer to a different variable at the given position,
+// because a variable of the same name is declared between the two positions.
+func shadowed(a *types.Analysis, idents []*ast.Identifier, from, to token.Pos) bool {
+	for _, ident := range idents {
+		for _, v := range a.Variables {
+			if v.Name != ident.Token.Literal || v == a.Bindings[ident] || v.Def == nil {
+				continue
+			}
+			if v.Def.Pos() < from || v.Def.Pos() >= to {
+				continue
+			}
+			if scope := v.Scope.Node; scope == nil || scope.Pos() <= to && to < scope.End() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortedEdits sorts the edits by their position.
+func sortedEdits(edits []ast.Edit) []ast.Edit {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Range.Start < edits[j].Range.Start })
+	return edits
+}
@@ -0,0 +1,143 @@
+package refactor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineLocal(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string // The cursor is at the `|`
+		expected string
+	}{
+		{"lower precedence", "local |x = a + b\nprint(x * 2)", "print((a + b) * 2)"},
+		{"higher precedence", "local x = a * b\nprint(|x + 1, x)", "print(a * b + 1, a * b)"},
+		{"right operand", "local |x = a - b\nprint(c - x)", "print(c - (a - b))"},
+		{"left operand", "local |x = a - b\nprint(x - c)", "print(a - b - c)"},
+		{"right associative", "local |x = a .. b\nprint(x .. c, c .. x)", "print((a .. b) .. c, c .. a .. b)"},
+		{"prefix", "local |x = -a\nprint(b -x, x ^ 2)", "print(b -(-a), (-a) ^ 2)"},
+		{"method prefix", "local |s = 'foo'\nprint(s:upper())", "print(('foo'):upper())"},
+		{"parenthesized", "local |x = (a + b)\nprint(x)", "print(a + b)"},
+		{"call", "local |x = f()\nprint(x, 1)", "print(f(), 1)"},
+		{"call at the end of a list", "local |x = f()\nprint(x)", "print((f()))"},
+		{"pure value used twice", "local |x = a.b\nlocal y = x\nlocal z = x", "local y = a.b\nlocal z = a.b"},
+		{"field read by a call", "local |x = t.y\nprint(x)", "print(t.y)"},
+		{"constant after a call", "local |x = a\nprint(1)\nprint(x)", "print(1)\nprint(a)"},
+		{"call in the first condition", "local |x = f()\nif x then end", "if f() then end"},
+		{"documented", "--- The value\nlocal |x = 1\nprint(x)", "print(1)"},
+		{"indented", "do\n\tlocal |x = 1\n\tprint(x)\nend", "do\n\tprint(1)\nend"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, pos := cursor(t, test.input)
+			actions := InlineLocal(file, a, pos)
+			require.Len(t, actions, 1)
+			assert.Equal(t, test.expected, apply(file.Source, actions[0].Edits))
+		})
+	}
+}
+
+func TestInlineLocalNothing(t *testing.T) {
+	tests := []struct {
+		label string
+		input string
+	}{
+		{"reassigned", "local |x = 1\nx = 2\nprint(x)"},
+		{"call used twice", "local |x = f()\nprint(x, x)"},
+		{"call used later", "local |x = f()\ng()\nprint(x)"},
+		{"call used in a loop", "local |x = f()\nfor i = 1, 2 do print(x) end"},
+		{"call used in a function", "local |x = f()\nlocal g = function() return x end"},
+		{"call evaluated after another", "local |x = f()\nprint(g(), x)"},
+		{"call evaluated after a field", "local |x = f()\nprint(t.y, x)"},
+		{"call short-circuited", "local |x = f()\nprint(c and x)"},
+		{"call in a branch", "local |x = f()\nif c then print(x) end"},
+		{"field assigned", "local |v = t.x\nt.x = 2\nprint(v)"},
+		{"field assigned by a function", "local |v = t.f\nfunction t.f() end\nprint(v)"},
+		{"field used after a call", "local |v = t.x\ng()\nprint(v)"},
+		{"field used after a call in the same statement", "local |v = t.x\nprint(g(), v)"},
+		{"field used in a loop", "local |v = t.x\nfor i = 1, 2 do print(v) t.x = i end"},
+		{"field used in a function", "local |v = t.x\nlocal g = function() return v end"},
+		{"operator used after a call", "local |v = a + b\ng()\nprint(v)"},
+		{"table used twice", "local |t = {}\nprint(t, t)"},
+		{"input reassigned", "local a = 1\nlocal |x = a\na = 2\nprint(x)"},
+		{"global input reassigned", "local |x = a\na = 2\nprint(x)"},
+		{"shadowed", "local |x = a\ndo local a = 2 print(x) end"},
+		{"unused", "local |x = 1"},
+		{"parameter", "local function f(|a) return a end"},
+		{"several names", "local |x, y = 1, 2\nprint(x)"},
+		{"vararg", "local |x = ...\nprint(x)"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, pos := cursor(t, test.input)
+			assert.Empty(t, InlineLocal(file, a, pos))
+		})
+	}
+}
+
+func TestInlineFunction(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected string
+	}{
+		{"operator", "local function |add(a, b) return a + b end\nprint(add(1, 2) * 3)", "print((1 + 2) * 3)"},
+		{"argument precedence", "local function double(n) return n * 2 end\nprint(|double(x + 1))", "print((x + 1) * 2)"},
+		{"missing argument", "local function |f(a, b) return b end\nprint(f(1))", "print(nil)"},
+		{"field", "local function |get(t) return t.value end\nlocal v = get(obj)", "local v = obj.value"},
+		{"call statement", "local function |log(m) return print(m) end\nlog('hi')", "print('hi')"},
+		{"call used once", "local function |inc(n) return n + 1 end\nprint(inc(f()))", "print(f() + 1)"},
+		{"several calls", "local function |sq(n) return n * n end\nprint(sq(2), sq(a.b))", "print(2 * 2, a.b * a.b)"},
+		{"documented", "---@param n number\nlocal function |neg(n) return -n end\nprint(neg(1))", "print(-1)"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, pos := cursor(t, test.input)
+			actions := InlineFunction(file, a, pos)
+			require.Len(t, actions, 1)
+			assert.Equal(t, test.expected, apply(file.Source, actions[0].Edits))
+		})
+	}
+}
+
+func TestInlineFunctionNothing(t *testing.T) {
+	tests := []struct {
+		label string
+		input string
+	}{
+		{"several statements", "local function |f() print(1) return 1 end\nf()"},
+		{"call used twice", "local function |sq(n) return n * n end\nprint(sq(f()))"},
+		{"calls reordered", "local function |sub(a, b) return b - a end\nprint(sub(f(), g()))"},
+		{"call short-circuited", "local function |h(c, x) return c and x end\nprint(h(ok, g()))"},
+		{"call after a field", "local function |f(t, x) return t.y + x end\nprint(f(t, g()))"},
+		{"field argument reordered", "local function |sub(a, b) return b - a end\nprint(sub(t.y, g()))"},
+		{"used as a value", "local function |f(x) return x end\nlocal g = f"},
+		{"recursive", "local function |f(n) return f(n) end\nf(1)"},
+		{"expression statement", "local function |f(x) return x + 1 end\nf(1)"},
+		{"multiple values", "local function |f(a, b) return a + b end\nprint(f(g()))"},
+		{"shadowed", "local function |f() return a end\ndo local a = 1 print(f()) end"},
+		{"vararg", "local function |f(...) return ... end\nprint(f(1))"},
+		{"global", "function |f() return 1 end\nprint(f())"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, pos := cursor(t, test.input)
+			assert.Empty(t, InlineFunction(file, a, pos))
+		})
+	}
+}
+
+// cursor analyzes the input with the `|` that marks the cursor removed, and returns the position of the cursor.
+func cursor(t *testing.T, input string) (*ast.File, *types.Analysis, token.Pos) {
+	pos := strings.Index(input, "|")
+	require.GreaterOrEqual(t, pos, 0)
+	file, a := analyze(t, input[:pos]+input[pos+1:])
+	return file, a, pos
+}
@@ -0,0 +1,176 @@
+// Package refactor computes changes that restructure Lua code without changing its behavior.
+package refactor
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Action is a refactoring that can be applied to a file. The edits must not overlap, and edits that start at the same
+// position are applied in order.
+type Action struct {
+	Title string
+	Kind  protocol.CodeActionKind
+	Edits []ast.Edit
+}
+
+// walkPaths calls the visitor for the node and each of its semantic descendants, in order, along with the nodes that
+// contain them. If the visitor returns false, the children of the node are not visited.
+func walkPaths(node ast.Node, parents []ast.Node, visitor func(node ast.Node, parents []ast.Node) bool) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+	if !visitor(node, parents) {
+		return
+	}
+	parents = append(parents[:len(parents):len(parents)], node)
+	for _, child := range node.GetSemanticChildren() {
+		walkPaths(child, parents, visitor)
+	}
+}
+
+// blockStatement returns the innermost statement of the path that is a direct member of a block, along with its
+// index in the path and the block. The index is -1 if there is no such statement.
+func blockStatement(parents []ast.Node) (ast.Statement, int, *ast.Block) {
+	for i := len(parents) - 1; i >= 2; i-- {
+		stmt, ok := parents[i].(ast.Statement)
+		if !ok {
+			continue
+		}
+		if _, ok := parents[i-1].(*ast.Pair[ast.Statement]); !ok {
+			continue
+		}
+		if block, ok := parents[i-2].(*ast.Block); ok {
+			return stmt, i, block
+		}
+	}
+	return nil, -1, nil
+}
+
+// trimRange shrinks the range so that it does not start or end with whitespace.
+func trimRange(file *ast.File, rng token.Range) token.Range {
+	for rng.Start < rng.End && isSpace(file.Source[rng.Start]) {
+		rng.Start++
+	}
+	for rng.End > rng.Start && isSpace(file.Source[rng.End-1]) {
+		rng.End--
+	}
+	return rng
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// onlyTrivia returns whether the range only contains whitespace and comments. Parentheses are allowed as well, since
+// they are not part of the range of the expression they surround.
+func onlyTrivia(file *ast.File, rng token.Range) bool {
+	for pos := rng.Start; pos < rng.End; pos++ {
+		if c := file.Source[pos]; isSpace(c) || c == '(' || c == ')' {
+			continue
+		}
+		comment := commentAt(file, pos)
+		if comment == nil {
+			return false
+		}
+		pos = comment.End() - 1
+	}
+	return true
+}
+
+// commentAt returns the comment that contains the given position, or nil if there is none.
+func commentAt(file *ast.File, pos token.Pos) *token.Token {
+	for i := range file.Comments {
+		if file.Comments[i].Pos <= pos && pos < file.Comments[i].End() {
+			return &file.Comments[i]
+		}
+	}
+	return nil
+}
+
+// indentation returns the whitespace at the start of the line that contains the given position.
+func indentation(file *ast.File, pos token.Pos) string {
+	lineStart := strings.LastIndexByte(file.Source[:pos], '\n') + 1
+	line := file.Source[lineStart:pos]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// indentUnit returns the whitespace that the file indents each level with, defaulting to a tab.
+func indentUnit(file *ast.File) string {
+	for _, line := range strings.Split(file.Source, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			return "\t"
+		}
+		if trimmed := strings.TrimLeft(line, " "); trimmed != line && trimmed != "" {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "\t"
+}
+
+// uniqueName returns the given name, followed by a number if needed to make it different from the name of every
+// variable in the file.
+func uniqueName(a *types.Analysis, base string) string {
+	used := map[string]bool{}
+	for _, v := range a.Variables {
+		used[v.Name] = true
+	}
+	for _, ident := range a.Globals {
+		used[ident.Token.Literal] = true
+	}
+	name := base
+	for i := 2; used[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	return name
+}
+
+// identifiers returns every identifier in the node, in order.
+func identifiers(node ast.Node) []*ast.Identifier {
+	idents := []*ast.Identifier{}
+	ast.WalkSemantic(node, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			idents = append(idents, ident)
+		}
+		return true
+	})
+	return idents
+}
+
+// contains returns whether the range contains the node.
+func contains(rng token.Range, node ast.Node) bool {
+	return rng.Start <= node.Pos() && node.End() <= rng.End
+}
+
+// RemovalRange returns the range to delete to remove the code in the given range. If nothing else is on its lines,
+// the lines are removed along with the documentation comments directly above them.
+func RemovalRange(file *ast.File, rng token.Range) token.Range {
+	lineStart := strings.LastIndexByte(file.Source[:rng.Start], '\n') + 1
+	if strings.TrimSpace(file.Source[lineStart:rng.Start]) != "" {
+		return rng
+	}
+	rest := file.Source[rng.End:]
+	lineEnd := strings.IndexByte(rest, '\n')
+	if lineEnd < 0 {
+		lineEnd = len(rest)
+	} else {
+		lineEnd++
+	}
+	if strings.TrimSpace(rest[:lineEnd]) != "" {
+		return rng
+	}
+	for lineStart > 0 {
+		previous := strings.LastIndexByte(file.Source[:lineStart-1], '\n') + 1
+		if !strings.HasPrefix(strings.TrimSpace(file.Source[previous:lineStart]), "---") {
+			break
+		}
+		lineStart = previous
+	}
+	return token.Range{Start: lineStart, End: rng.End + lineEnd}
+}
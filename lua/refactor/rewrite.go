@@ -0,0 +1,273 @@
+package refactor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/types"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Rewrites returns actions that change the syntax of the code at the given position without changing its behavior,
+// such as the quotes of a string or how a function is declared.
+func Rewrites(file *ast.File, a *types.Analysis, pos token.Pos) []Action {
+	if file.Block == nil {
+		return nil
+	}
+	path := ast.GetSemanticNode(file.Block, pos)
+	if path.Node == nil {
+		return nil
+	}
+	nodes := append(path.Parents, path.Node)
+	actions := []Action{}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		switch node := nodes[i].(type) {
+		case *ast.StringLiteral:
+			actions = append(actions, quoteRewrites(node)...)
+		case *ast.IndexExpression:
+			if pos >= node.LeftIndexer.Pos() && !inFunctionName(nodes[:i], node) {
+				actions = append(actions, indexRewrites(node)...)
+			}
+		case *ast.FunctionCall:
+			if pos >= node.Name.End() {
+				actions = append(actions, callRewrites(file, node)...)
+			}
+		case *ast.FunctionStatement:
+			if pos < node.RightParen.End() {
+				actions = append(actions, functionRewrites(a, node)...)
+				actions = append(actions, methodRewrites(node)...)
+			}
+		case *ast.LocalStatement:
+			actions = append(actions, localFunctionRewrites(a, node, pos)...)
+		}
+	}
+	return actions
+}
+
+// quoteRewrites returns actions that write the string with each kind of delimiter that it does not already use.
+func quoteRewrites(sl *ast.StringLiteral) []Action {
+	value := sl.Value()
+	current := sl.Token.Literal[0]
+	actions := []Action{}
+	for _, quote := range []byte{'\'', '"'} {
+		if current != quote {
+			title := "Convert to single-quoted string"
+			if quote == '"' {
+				title = "Convert to double-quoted string"
+			}
+			actions = append(actions, rewrite(title, ast.Range(sl), quoteString(value, quote)))
+		}
+	}
+	if current != '[' && !strings.Contains(value, "\r") && utf8.ValidString(value) {
+		actions = append(actions, rewrite("Convert to long string", ast.Range(sl), longString(value)))
+	}
+	return actions
+}
+
+// quoteString returns a string literal with the given value and quote, escaping the characters that cannot be
+// written as they are.
+func quoteString(value string, quote byte) string {
+	var sb strings.Builder
+	sb.WriteByte(quote)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case quote, '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if c < ' ' || c == 0x7f {
+				// Pad to three digits so that a following digit is not part of the escape
+				fmt.Fprintf(&sb, `\%03d`, c)
+			} else {
+				sb.WriteByte(c)
+			}
+		}
+	}
+	sb.WriteByte(quote)
+	return sb.String()
+}
+
+// longString returns a long bracket string literal with the given value, using the lowest level whose closing
+// bracket does not appear in the value.
+func longString(value string) string {
+	level := ""
+	for strings.Contains(value+"]", "]"+level+"]") {
+		level += "="
+	}
+	// A newline immediately following the opening bracket is skipped
+	if strings.HasPrefix(value, "\n") {
+		value = "\n" + value
+	}
+	return "[" + level + "[" + value + "]" + level + "]"
+}
+
+// indexRewrites returns an action that converts between `a.b` and `a["b"]`.
+func indexRewrites(ie *ast.IndexExpression) []Action {
+	rng := token.Range{Start: ie.LeftIndexer.Pos(), End: ie.End()}
+	switch ie.LeftIndexer.Type() {
+	case token.DOT:
+		if name, ok := ie.Inner.(*ast.Identifier); ok {
+			return []Action{rewrite("Convert to bracket index", rng, "["+quoteString(name.Token.Literal, '"')+"]")}
+		}
+	case token.LBRACK:
+		if sl, ok := ie.Inner.(*ast.StringLiteral); ok && isName(sl.Value()) && ie.RightIndexer != nil {
+			return []Action{rewrite("Convert to field access", rng, "."+sl.Value())}
+		}
+	}
+	return nil
+}
+
+// isName returns whether the string can be written as an identifier.
+func isName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	if _, ok := token.Reserved[s]; ok {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// callRewrites returns an action that adds parentheses around the single string or table argument of a call, or
+// one that removes them.
+func callRewrites(file *ast.File, call *ast.FunctionCall) []Action {
+	if len(call.Args.Pairs) != 1 {
+		return nil
+	}
+	arg := call.Args.Pairs[0].Node
+	argText := file.Text(ast.Range(arg))
+	if call.LeftParen == nil {
+		rng := token.Range{Start: call.Name.End(), End: call.End()}
+		return []Action{rewrite("Add parentheses to call", rng, "("+argText+")")}
+	}
+	switch arg.(type) {
+	case *ast.StringLiteral, *ast.TableLiteral:
+	default:
+		return nil
+	}
+	if call.RightParen == nil || !onlyTrivia(file, token.Range{Start: call.LeftParen.End(), End: arg.Pos()}) {
+		return nil
+	}
+	if strings.TrimSpace(file.Text(token.Range{Start: arg.End(), End: call.RightParen.Pos()})) != "" {
+		return nil
+	}
+	rng := token.Range{Start: call.LeftParen.Pos(), End: call.RightParen.End()}
+	return []Action{rewrite("Remove parentheses from call", rng, " "+argText)}
+}
+
+// functionRewrites returns an action that converts `local function f()` to `local f = function()`. Functions that
+// call themselves are left alone, since the function would no longer be able to see its own name.
+func functionRewrites(a *types.Analysis, fs *ast.FunctionStatement) []Action {
+	name, ok := fs.Name.(*ast.Identifier)
+	if !ok || fs.LocalTok == nil {
+		return nil
+	}
+	if v := a.Bindings[name]; v == nil || hasRefWithin(v.Refs, ast.Range(fs)) {
+		return nil
+	}
+	rng := token.Range{Start: fs.FuncTok.Pos(), End: name.End()}
+	return []Action{rewrite("Convert to local variable with function value", rng, name.Token.Literal+" = function")}
+}
+
+// localFunctionRewrites returns an action that converts `local f = function()` to `local function f()`, unless the
+// function refers to something else named `f`, which would then refer to the function itself.
+func localFunctionRewrites(a *types.Analysis, ls *ast.LocalStatement, pos token.Pos) []Action {
+	if len(ls.Names.Pairs) != 1 || ls.Exps == nil || len(ls.Exps.Pairs) != 1 {
+		return nil
+	}
+	fe, ok := ls.Exps.Pairs[0].Node.(*ast.FunctionExpression)
+	if !ok || pos >= fe.RightParen.End() {
+		return nil
+	}
+	name := ls.Names.Pairs[0].Node
+	for _, ident := range freeIdentifiers(a, fe) {
+		if ident.Token.Literal == name.Token.Literal {
+			return nil
+		}
+	}
+	rng := token.Range{Start: name.Pos(), End: fe.FuncTok.End()}
+	return []Action{rewrite("Convert to local function", rng, "function "+name.Token.Literal)}
+}
+
+// methodRewrites returns an action that converts between `function T.m(self)` and `function T:m()`.
+func methodRewrites(fs *ast.FunctionStatement) []Action {
+	ie, ok := fs.Name.(*ast.IndexExpression)
+	if !ok {
+		return nil
+	}
+	indexer := ie.LeftIndexer.Range()
+	params := fs.Params.Pairs
+	switch ie.LeftIndexer.Type() {
+	case token.COLON:
+		insert := token.Range{Start: fs.LeftParen.End(), End: fs.LeftParen.End()}
+		self := "self"
+		if len(params) > 0 || fs.Vararg != nil {
+			self = "self, "
+		}
+		return []Action{{
+			Title: "Convert to function with explicit self",
+			Kind:  protocol.CodeActionKindRefactorRewrite,
+			Edits: []ast.Edit{{Range: indexer, NewText: "."}, {Range: insert, NewText: self}},
+		}}
+	case token.DOT:
+		if len(params) == 0 || params[0].Node.Token.Literal != "self" {
+			return nil
+		}
+		remove := token.Range{Start: params[0].Pos(), End: params[0].End()}
+		switch {
+		case len(params) > 1:
+			remove.End = params[1].Pos()
+		case fs.Vararg != nil:
+			remove.End = fs.Vararg.Pos()
+		}
+		return []Action{{
+			Title: "Convert to method",
+			Kind:  protocol.CodeActionKindRefactorRewrite,
+			Edits: []ast.Edit{{Range: indexer, NewText: ":"}, {Range: remove, NewText: ""}},
+		}}
+	}
+	return nil
+}
+
+// inFunctionName returns whether the node is part of the name of a function statement among its parents, which can
+// only be a chain of fields.
+func inFunctionName(parents []ast.Node, node ast.Node) bool {
+	for _, parent := range parents {
+		if fs, ok := parent.(*ast.FunctionStatement); ok && contains(ast.Range(fs.Name), node) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRefWithin(refs []*ast.Identifier, rng token.Range) bool {
+	for _, ref := range refs {
+		if contains(rng, ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// rewrite returns an action that replaces the text of a single range.
+func rewrite(title string, rng token.Range, text string) Action {
+	return Action{
+		Title: title,
+		Kind:  protocol.CodeActionKindRefactorRewrite,
+		Edits: []ast.Edit{{Range: rng, NewText: text}},
+	}
+}
@@ -0,0 +1,79 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrites(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string // The cursor is at the `|`
+		title    string
+		expected string
+	}{
+		{"to function value", "local function |f(a) return a end", "Convert to local variable with function value", "local f = function(a) return a end"},
+		{"to local function", "local |f = function(a) return a end", "Convert to local function", "local function f(a) return a end"},
+		{"to method", "local T = {}\nfunction T.|m(self, a) end", "Convert to method", "local T = {}\nfunction T:m(a) end"},
+		{"to method with only self", "local T = {}\nfunction T.m(|self) end", "Convert to method", "local T = {}\nfunction T:m() end"},
+		{"to method with vararg", "local T = {}\nfunction T.m(|self, ...) end", "Convert to method", "local T = {}\nfunction T:m(...) end"},
+		{"to explicit self", "local T = {}\nfunction T:|m(a) end", "Convert to function with explicit self", "local T = {}\nfunction T.m(self, a) end"},
+		{"to explicit self without parameters", "local T = {}\nfunction |T:m() end", "Convert to function with explicit self", "local T = {}\nfunction T.m(self) end"},
+		{"to double quotes", `print(|'say "hi"\n')`, "Convert to double-quoted string", `print("say \"hi\"\n")`},
+		{"to single quotes", `print(|"it's")`, "Convert to single-quoted string", `print('it\'s')`},
+		{"control characters", `print(|"a\0001")`, "Convert to single-quoted string", `print('a\0001')`},
+		{"to long string", `print(|"a\nb")`, "Convert to long string", "print([[a\nb]])"},
+		{"to long string with brackets", `print(|"t[i]]")`, "Convert to long string", "print([=[t[i]]]=])"},
+		{"to long string with leading newline", `print(|"\nx")`, "Convert to long string", "print([[\n\nx]])"},
+		{"from long string", "print(|[[a\\b\n]])", "Convert to double-quoted string", `print("a\\b\n")`},
+		{"to bracket index", "print(t.|x)", "Convert to bracket index", `print(t["x"])`},
+		{"to field access", `print(t|["x"])`, "Convert to field access", "print(t.x)"},
+		{"add parentheses to string call", `require |"foo"`, "Add parentheses to call", `require("foo")`},
+		{"add parentheses to table call", "f|{1, 2}", "Add parentheses to call", "f({1, 2})"},
+		{"remove parentheses from string call", `require(|"foo")`, "Remove parentheses from call", `require "foo"`},
+		{"remove parentheses from table call", "f(|{1, 2})", "Remove parentheses from call", "f {1, 2}"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, pos := cursor(t, test.input)
+			var found *Action
+			actions := Rewrites(file, a, pos)
+			for i := range actions {
+				if actions[i].Title == test.title {
+					found = &actions[i]
+				}
+			}
+			require.NotNil(t, found, "no action titled %q", test.title)
+			assert.Equal(t, test.expected, apply(file.Source, found.Edits))
+		})
+	}
+}
+
+func TestRewritesNothing(t *testing.T) {
+	tests := []struct {
+		label string
+		input string
+		title string
+	}{
+		{"recursive function", "local function |f(n) return f(n - 1) end", "Convert to local variable with function value"},
+		{"captured name", "local |f = function() return f() end", "Convert to local function"},
+		{"method without self", "local T = {}\nfunction T.|m(a) end", "Convert to method"},
+		{"keyword key", `print(t|["end"])`, "Convert to field access"},
+		{"invalid name key", `print(t|["a b"])`, "Convert to field access"},
+		{"function name", "local T = {}\nfunction T.|m() end", "Convert to bracket index"},
+		{"several arguments", `f(|"a", "b")`, "Remove parentheses from call"},
+		{"other argument", "f(|1)", "Remove parentheses from call"},
+		{"same quotes", `print(|"a")`, "Convert to double-quoted string"},
+		{"carriage return", `print(|"a\rb")`, "Convert to long string"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			file, a, pos := cursor(t, test.input)
+			for _, action := range Rewrites(file, a, pos) {
+				assert.NotEqual(t, test.title, action.Title)
+			}
+		})
+	}
+}
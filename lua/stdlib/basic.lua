@@ -0,0 +1,225 @@
+---@meta
+
+---@class userdata
+
+---@class lightuserdata : userdata
+
+---@class thread
+
+---A global variable that holds the global environment.
+---@type table
+_G = {}
+
+---The command-line arguments passed to a standalone script.
+---@type string[]
+arg = {}
+
+---The environment of the current chunk.
+---@version >5.2
+---@type table
+_ENV = {}
+
+---A global variable that holds a string containing the running Lua version.
+---@type string
+_VERSION = ""
+
+---Raises an error if the value of its argument is false; otherwise, returns all its arguments.
+---@param v any
+---@param message? any
+---@return any ...
+function assert(v, message, ...) end
+
+---Performs a garbage-collection cycle, or controls the garbage collector, depending on the option.
+---@param opt? string
+---@param arg? integer
+---@return any
+function collectgarbage(opt, arg) end
+
+---Opens the named file and executes its content as a Lua chunk.
+---@param filename? string
+---@return any ...
+function dofile(filename) end
+
+---Raises an error with the given message. This function never returns.
+---@param message any
+---@param level? integer
+function error(message, level) end
+
+---Returns the current environment in use by the function.
+---@version 5.1, JIT
+---@param f? integer|function
+---@return table
+function getfenv(f) end
+
+---Returns the metatable of the given object, or nil if it does not have one.
+---@param object any
+---@return table|nil
+function getmetatable(object) end
+
+---Returns an iterator function, the table and 0, which iterate over the array part of the table.
+---@param t table
+---@return fun(t: table, i: integer): integer, any
+---@return table
+---@return integer
+function ipairs(t) end
+
+---Loads a chunk from the given string or function.
+---@version 5.1, JIT
+---@param func fun(): string|nil
+---@param chunkname? string
+---@return function|nil
+---@return string|nil error_message
+function load(func, chunkname) end
+
+---Loads a chunk from the given string or function.
+---@version >5.2
+---@param chunk string|fun(): string|nil
+---@param chunkname? string
+---@param mode? string
+---@param env? table
+---@return function|nil
+---@return string|nil error_message
+function load(chunk, chunkname, mode, env) end
+
+---Loads a chunk from the given file without running it.
+---@param filename? string
+---@param mode? string
+---@param env? table
+---@return function|nil
+---@return string|nil error_message
+function loadfile(filename, mode, env) end
+
+---Loads a chunk from the given string without running it.
+---@version 5.1, JIT
+---@param text string
+---@param chunkname? string
+---@return function|nil
+---@return string|nil error_message
+function loadstring(text, chunkname) end
+
+---Creates a module.
+---@version 5.1, JIT
+---@param name string
+function module(name, ...) end
+
+---Returns the next key of the table and its value.
+---@param t table
+---@param index? any
+---@return any
+---@return any
+function next(t, index) end
+
+---Returns the next function, the table and nil, which iterate over every key of the table.
+---@param t table
+---@return fun(t: table, k: any): any, any
+---@return table
+---@return nil
+function pairs(t) end
+
+---Calls the function in protected mode. Returns whether the call succeeded, followed by its results or the error.
+---@param f function
+---@param ... any
+---@return boolean success
+---@return any ...
+function pcall(f, ...) end
+
+---Converts every argument to a string and prints them to `stdout`.
+---@param ... any
+function print(...) end
+
+---Checks whether the two values are equal, without invoking the `__eq` metamethod.
+---@param v1 any
+---@param v2 any
+---@return boolean
+function rawequal(v1, v2) end
+
+---Gets the value of `table[index]`, without invoking the `__index` metamethod.
+---@param table table
+---@param index any
+---@return any
+function rawget(table, index) end
+
+---Returns the length of the table or string, without invoking the `__len` metamethod.
+---@version >5.2
+---@param v table|string
+---@return integer
+function rawlen(v) end
+
+---Sets the value of `table[index]`, without invoking the `__newindex` metamethod.
+---@param table table
+---@param index any
+---@param value any
+---@return table
+function rawset(table, index, value) end
+
+---Loads the given module.
+---@param modname string
+---@return any
+function require(modname) end
+
+---If index is a number, returns all arguments after argument number index. Otherwise, index must be the string
+---`"#"`, and it returns the total number of extra arguments.
+---@param index integer|"#"
+---@param ... any
+---@return any ...
+function select(index, ...) end
+
+---Sets the environment to be used by the given function.
+---@version 5.1, JIT
+---@param f integer|function
+---@param table table
+---@return function
+function setfenv(f, table) end
+
+---Sets the metatable of the given table, and returns the table.
+---@param table table
+---@param metatable table|nil
+---@return table
+function setmetatable(table, metatable) end
+
+---Converts its argument to a number, or returns nil if it cannot be converted.
+---@param e any
+---@param base? integer
+---@return number|nil
+function tonumber(e, base) end
+
+---Converts its argument to a human-readable string.
+---@param v any
+---@return string
+function tostring(v) end
+
+---Returns the type of its only argument, coded as a string.
+---@param v any
+---@return "nil"|"number"|"string"|"boolean"|"table"|"function"|"thread"|"userdata"
+function type(v) end
+
+---Returns the elements of the given list.
+---@version 5.1, JIT
+---@param list table
+---@param i? integer
+---@param j? integer
+---@return any ...
+function unpack(list, i, j) end
+
+---Calls the function in protected mode with a custom message handler.
+---@version 5.1
+---@param f function
+---@param msgh function
+---@return boolean success
+---@return any ...
+function xpcall(f, msgh) end
+
+---Calls the function in protected mode with a custom message handler.
+---@version >5.2, JIT
+---@param f function
+---@param msgh function
+---@param ... any
+---@return boolean success
+---@return any ...
+function xpcall(f, msgh, ...) end
+
+---Emits a warning with a message composed by the concatenation of all its arguments.
+---@version >5.4
+---@param message string
+---@param ... string
+function warn(message, ...) end
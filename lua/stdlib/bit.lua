@@ -0,0 +1,76 @@
+---@meta
+
+---Bitwise operations on 32-bit integers, provided by LuaJIT.
+---@class bitlib
+---@version JIT
+bit = {}
+
+---Normalizes a number to the numeric range for bit operations and returns it.
+---@param x number
+---@return integer
+function bit.tobit(x) end
+
+---Converts its first argument to a hex string.
+---@param x number
+---@param n? integer
+---@return string
+function bit.tohex(x, n) end
+
+---Returns the bitwise not of its argument.
+---@param x number
+---@return integer
+function bit.bnot(x) end
+
+---Returns the bitwise and of all of its arguments.
+---@param x number
+---@param ... number
+---@return integer
+function bit.band(x, ...) end
+
+---Returns the bitwise or of all of its arguments.
+---@param x number
+---@param ... number
+---@return integer
+function bit.bor(x, ...) end
+
+---Returns the bitwise xor of all of its arguments.
+---@param x number
+---@param ... number
+---@return integer
+function bit.bxor(x, ...) end
+
+---Returns the bitwise logical left-shift of its first argument by the number of bits given by the second argument.
+---@param x number
+---@param n number
+---@return integer
+function bit.lshift(x, n) end
+
+---Returns the bitwise logical right-shift of its first argument by the number of bits given by the second argument.
+---@param x number
+---@param n number
+---@return integer
+function bit.rshift(x, n) end
+
+---Returns the bitwise arithmetic right-shift of its first argument by the number of bits given by the second
+---argument.
+---@param x number
+---@param n number
+---@return integer
+function bit.arshift(x, n) end
+
+---Returns the bitwise left rotation of its first argument by the number of bits given by the second argument.
+---@param x number
+---@param n number
+---@return integer
+function bit.rol(x, n) end
+
+---Returns the bitwise right rotation of its first argument by the number of bits given by the second argument.
+---@param x number
+---@param n number
+---@return integer
+function bit.ror(x, n) end
+
+---Swaps the bytes of its argument and returns it.
+---@param x number
+---@return integer
+function bit.bswap(x) end
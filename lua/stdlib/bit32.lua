@@ -0,0 +1,76 @@
+---@meta
+
+---Bitwise operations on unsigned 32-bit integers, provided by Lua 5.2.
+---@class bit32lib
+---@version 5.2
+bit32 = {}
+
+---Returns the number x shifted disp bits to the right, filling vacant bits with copies of the higher bit of x.
+---@param x integer
+---@param disp integer
+---@return integer
+function bit32.arshift(x, disp) end
+
+---Returns the bitwise and of its operands.
+---@param ... integer
+---@return integer
+function bit32.band(...) end
+
+---Returns the bitwise negation of x.
+---@param x integer
+---@return integer
+function bit32.bnot(x) end
+
+---Returns the bitwise or of its operands.
+---@param ... integer
+---@return integer
+function bit32.bor(...) end
+
+---Returns whether the bitwise and of its operands is different from zero.
+---@param ... integer
+---@return boolean
+function bit32.btest(...) end
+
+---Returns the bitwise exclusive or of its operands.
+---@param ... integer
+---@return integer
+function bit32.bxor(...) end
+
+---Returns the unsigned number formed by the bits field to field + width - 1 from n.
+---@param n integer
+---@param field integer
+---@param width? integer
+---@return integer
+function bit32.extract(n, field, width) end
+
+---Returns a copy of n with the bits field to field + width - 1 replaced by the value v.
+---@param n integer
+---@param v integer
+---@param field integer
+---@param width? integer
+---@return integer
+function bit32.replace(n, v, field, width) end
+
+---Returns the number x rotated disp bits to the left.
+---@param x integer
+---@param disp integer
+---@return integer
+function bit32.lrotate(x, disp) end
+
+---Returns the number x shifted disp bits to the left.
+---@param x integer
+---@param disp integer
+---@return integer
+function bit32.lshift(x, disp) end
+
+---Returns the number x rotated disp bits to the right.
+---@param x integer
+---@param disp integer
+---@return integer
+function bit32.rrotate(x, disp) end
+
+---Returns the number x shifted disp bits to the right.
+---@param x integer
+---@param disp integer
+---@return integer
+function bit32.rshift(x, disp) end
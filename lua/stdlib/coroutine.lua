@@ -0,0 +1,48 @@
+---@meta
+
+---Functions for manipulating coroutines.
+---@class coroutinelib
+coroutine = {}
+
+---Closes the coroutine, putting it in a dead state.
+---@version >5.4
+---@param co thread
+---@return boolean success
+---@return any error
+function coroutine.close(co) end
+
+---Creates a new coroutine with the given body.
+---@param f function
+---@return thread
+function coroutine.create(f) end
+
+---Returns whether the running coroutine can yield.
+---@version >5.3
+---@return boolean
+function coroutine.isyieldable() end
+
+---Starts or continues the execution of the coroutine.
+---@param co thread
+---@param ... any
+---@return boolean success
+---@return any ...
+function coroutine.resume(co, ...) end
+
+---Returns the running coroutine.
+---@return thread|nil
+function coroutine.running() end
+
+---Returns the status of the coroutine.
+---@param co thread
+---@return "running"|"suspended"|"normal"|"dead"
+function coroutine.status(co) end
+
+---Creates a new coroutine with the given body, and returns a function that resumes it each time it is called.
+---@param f function
+---@return fun(...): any
+function coroutine.wrap(f) end
+
+---Suspends the execution of the calling coroutine.
+---@param ... any
+---@return any ...
+function coroutine.yield(...) end
@@ -0,0 +1,119 @@
+---@meta
+
+---Functions for debugging and introspection.
+---@class debuglib
+debug = {}
+
+---Enters an interactive mode with the user.
+function debug.debug() end
+
+---Returns the environment of the given object.
+---@version 5.1, JIT
+---@param o any
+---@return table
+function debug.getfenv(o) end
+
+---Returns the current hook settings of the thread.
+---@param thread? thread
+---@return function hook
+---@return string mask
+---@return integer count
+function debug.gethook(thread) end
+
+---Returns a table with information about a function.
+---@param f integer|function
+---@param what? string
+---@return table
+function debug.getinfo(f, what) end
+
+---Returns the name and the value of the local variable with the given index.
+---@param level integer|function
+---@param index integer
+---@return string|nil name
+---@return any value
+function debug.getlocal(level, index) end
+
+---Returns the metatable of the given value.
+---@param value any
+---@return table|nil
+function debug.getmetatable(value) end
+
+---Returns the registry table.
+---@return table
+function debug.getregistry() end
+
+---Returns the name and the value of the upvalue with the given index of the function.
+---@param f function
+---@param up integer
+---@return string|nil name
+---@return any value
+function debug.getupvalue(f, up) end
+
+---Returns the Lua value associated with the userdata.
+---@version >5.2
+---@param u userdata
+---@param n? integer
+---@return any
+function debug.getuservalue(u, n) end
+
+---Sets the environment of the given object.
+---@version 5.1, JIT
+---@param object any
+---@param env table
+---@return any
+function debug.setfenv(object, env) end
+
+---Sets the given function as a hook.
+---@param hook function|nil
+---@param mask string
+---@param count? integer
+function debug.sethook(hook, mask, count) end
+
+---Assigns the value to the local variable with the given index.
+---@param level integer
+---@param index integer
+---@param value any
+---@return string|nil name
+function debug.setlocal(level, index, value) end
+
+---Sets the metatable for the given value.
+---@param value any
+---@param metatable table|nil
+---@return any
+function debug.setmetatable(value, metatable) end
+
+---Assigns the value to the upvalue with the given index of the function.
+---@param f function
+---@param up integer
+---@param value any
+---@return string|nil name
+function debug.setupvalue(f, up, value) end
+
+---Sets the given value as the Lua value associated with the userdata.
+---@version >5.2
+---@param udata userdata
+---@param value any
+---@param n? integer
+---@return userdata
+function debug.setuservalue(udata, value, n) end
+
+---Returns a string with a traceback of the call stack.
+---@param message? any
+---@param level? integer
+---@return string
+function debug.traceback(message, level) end
+
+---Returns a unique identifier for the upvalue with the given index of the function.
+---@version >5.2, JIT
+---@param f function
+---@param n integer
+---@return lightuserdata
+function debug.upvalueid(f, n) end
+
+---Makes the upvalue of one closure refer to an upvalue of another closure.
+---@version >5.2, JIT
+---@param f1 function
+---@param n1 integer
+---@param f2 function
+---@param n2 integer
+function debug.upvaluejoin(f1, n1, f2, n2) end
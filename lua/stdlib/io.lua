@@ -0,0 +1,115 @@
+---@meta
+
+---Input and output facilities.
+---@class iolib
+---@field stdin file The standard input.
+---@field stdout file The standard output.
+---@field stderr file The standard error.
+io = {}
+
+---A file handle, as returned by `io.open`.
+---@class file : userdata
+local file = {}
+
+---Equivalent to `file:close()`. Without a file, closes the default output file.
+---@param file? file
+---@return boolean|nil success
+---@return string|nil error_message
+function io.close(file) end
+
+---Equivalent to `io.output():flush()`.
+function io.flush() end
+
+---Opens the named file in text mode and sets it as the default input file, or returns the current default input
+---file.
+---@param file? string|file
+---@return file
+function io.input(file) end
+
+---Opens the named file in read mode and returns an iterator function that reads from it according to the given
+---formats.
+---@param filename? string
+---@param ... string|integer
+---@return fun(): any, any
+function io.lines(filename, ...) end
+
+---Opens a file in the mode specified in the string mode, or returns nil and an error message.
+---@param filename string
+---@param mode? string
+---@return file|nil
+---@return string|nil error_message
+function io.open(filename, mode) end
+
+---Opens the named file in text mode and sets it as the default output file, or returns the current default output
+---file.
+---@param file? string|file
+---@return file
+function io.output(file) end
+
+---Starts the program in a separated process and returns a file handle that can be used to read data from or write
+---data to it.
+---@param prog string
+---@param mode? "r"|"w"
+---@return file|nil
+---@return string|nil error_message
+function io.popen(prog, mode) end
+
+---Equivalent to `io.input():read(...)`.
+---@param ... string|integer
+---@return any ...
+function io.read(...) end
+
+---Returns a handle for a temporary file, which is removed when the program ends.
+---@return file
+function io.tmpfile() end
+
+---Checks whether the object is a valid file handle.
+---@param obj any
+---@return "file"|"closed file"|nil
+function io.type(obj) end
+
+---Equivalent to `io.output():write(...)`.
+---@param ... string|number
+---@return file|nil
+---@return string|nil error_message
+function io.write(...) end
+
+---Closes the file.
+---@return boolean|nil success
+---@return string|nil error_message
+function file:close() end
+
+---Saves any written data to the file.
+---@return file|nil
+---@return string|nil error_message
+function file:flush() end
+
+---Returns an iterator function that reads from the file according to the given formats.
+---@param ... string|integer
+---@return fun(): any, any
+function file:lines(...) end
+
+---Reads the file according to the given formats.
+---@param ... string|integer
+---@return any ...
+function file:read(...) end
+
+---Sets and gets the file position.
+---@param whence? "set"|"cur"|"end"
+---@param offset? integer
+---@return integer|nil offset
+---@return string|nil error_message
+function file:seek(whence, offset) end
+
+---Sets the buffering mode for the file.
+---@param mode "no"|"full"|"line"
+---@param size? integer
+---@return boolean|nil success
+---@return string|nil error_message
+function file:setvbuf(mode, size) end
+
+---Writes the value of each argument to the file.
+---@param ... string|number
+---@return file|nil
+---@return string|nil error_message
+function file:write(...) end
@@ -0,0 +1,30 @@
+---@meta
+
+---Functions that control the behavior of the LuaJIT compiler.
+---@class jitlib
+---@field arch string The target architecture name.
+---@field os string The target operating system name.
+---@field version string The LuaJIT version string.
+---@field version_num integer The LuaJIT version number.
+---@version JIT
+jit = {}
+
+---Turns the JIT compiler on, either for the whole program or for the given function.
+---@param func? function|boolean
+---@param recursive? boolean
+function jit.on(func, recursive) end
+
+---Turns the JIT compiler off, either for the whole program or for the given function.
+---@param func? function|boolean
+---@param recursive? boolean
+function jit.off(func, recursive) end
+
+---Flushes the whole cache of compiled code, or the code of the given function.
+---@param func? function|boolean
+---@param recursive? boolean
+function jit.flush(func, recursive) end
+
+---Returns the current status of the JIT compiler, followed by the CPU-specific features that are enabled.
+---@return boolean status
+---@return string ...
+function jit.status() end
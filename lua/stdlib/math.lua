@@ -0,0 +1,186 @@
+---@meta
+
+---Mathematical functions.
+---@class mathlib
+---@field huge number The float value `HUGE_VAL`, a value greater than any other numeric value.
+---@field pi number The value of π.
+---@field maxinteger integer The maximum value for an integer. Only available in Lua 5.3 and later.
+---@field mininteger integer The minimum value for an integer. Only available in Lua 5.3 and later.
+math = {}
+
+---Returns the absolute value of x.
+---@param x number
+---@return number
+function math.abs(x) end
+
+---Returns the arc cosine of x, in radians.
+---@param x number
+---@return number
+function math.acos(x) end
+
+---Returns the arc sine of x, in radians.
+---@param x number
+---@return number
+function math.asin(x) end
+
+---Returns the arc tangent of y/x, in radians.
+---@param y number
+---@param x? number
+---@return number
+function math.atan(y, x) end
+
+---Returns the arc tangent of y/x, in radians.
+---@version <5.2, JIT
+---@param y number
+---@param x number
+---@return number
+function math.atan2(y, x) end
+
+---Returns the smallest integral value greater than or equal to x.
+---@param x number
+---@return integer
+function math.ceil(x) end
+
+---Returns the cosine of x, in radians.
+---@param x number
+---@return number
+function math.cos(x) end
+
+---Returns the hyperbolic cosine of x.
+---@version <5.2, JIT
+---@param x number
+---@return number
+function math.cosh(x) end
+
+---Converts the angle x from radians to degrees.
+---@param x number
+---@return number
+function math.deg(x) end
+
+---Returns the value eˣ.
+---@param x number
+---@return number
+function math.exp(x) end
+
+---Returns the largest integral value less than or equal to x.
+---@param x number
+---@return integer
+function math.floor(x) end
+
+---Returns the remainder of the division of x by y that rounds the quotient towards zero.
+---@param x number
+---@param y number
+---@return number
+function math.fmod(x, y) end
+
+---Decomposes x into a normalized fraction and an exponent.
+---@version <5.2, JIT
+---@param x number
+---@return number m
+---@return integer e
+function math.frexp(x) end
+
+---Returns m·2ᵉ.
+---@version <5.2, JIT
+---@param m number
+---@param e integer
+---@return number
+function math.ldexp(m, e) end
+
+---Returns the logarithm of x in the given base, which defaults to e.
+---@param x number
+---@param base? integer
+---@return number
+function math.log(x, base) end
+
+---Returns the base-10 logarithm of x.
+---@version 5.1, JIT
+---@param x number
+---@return number
+function math.log10(x) end
+
+---Returns the argument with the maximum value.
+---@param x number
+---@param ... number
+---@return number
+function math.max(x, ...) end
+
+---Returns the argument with the minimum value.
+---@param x number
+---@param ... number
+---@return number
+function math.min(x, ...) end
+
+---Returns the integral part of x and the fractional part of x.
+---@param x number
+---@return integer
+---@return number
+function math.modf(x) end
+
+---Returns xʸ.
+---@version <5.2, JIT
+---@param x number
+---@param y number
+---@return number
+function math.pow(x, y) end
+
+---Converts the angle x from degrees to radians.
+---@param x number
+---@return number
+function math.rad(x) end
+
+---Returns a pseudo-random number.
+---@param m? integer
+---@param n? integer
+---@return number
+function math.random(m, n) end
+
+---Sets x as the seed for the pseudo-random generator.
+---@param x? integer
+function math.randomseed(x) end
+
+---Returns the sine of x, in radians.
+---@param x number
+---@return number
+function math.sin(x) end
+
+---Returns the hyperbolic sine of x.
+---@version <5.2, JIT
+---@param x number
+---@return number
+function math.sinh(x) end
+
+---Returns the square root of x.
+---@param x number
+---@return number
+function math.sqrt(x) end
+
+---Returns the tangent of x, in radians.
+---@param x number
+---@return number
+function math.tan(x) end
+
+---Returns the hyperbolic tangent of x.
+---@version <5.2, JIT
+---@param x number
+---@return number
+function math.tanh(x) end
+
+---Converts x to an integer, or returns nil if it cannot be converted.
+---@version >5.3
+---@param x any
+---@return integer|nil
+function math.tointeger(x) end
+
+---Returns `"integer"` if x is an integer, `"float"` if it is a float, or nil if it is not a number.
+---@version >5.3
+---@param x any
+---@return "integer"|"float"|nil
+function math.type(x) end
+
+---Returns true if integer m is below integer n when they are compared as unsigned integers.
+---@version >5.3
+---@param m integer
+---@param n integer
+---@return boolean
+function math.ult(m, n) end
@@ -0,0 +1,73 @@
+---@meta
+
+---Operating system facilities.
+---@class oslib
+os = {}
+
+---Returns an approximation of the amount of CPU time used by the program, in seconds.
+---@return number
+function os.clock() end
+
+---Returns a string or a table containing the date and time, formatted according to the given format.
+---@param format? string
+---@param time? integer
+---@return string|table
+function os.date(format, time) end
+
+---Returns the difference, in seconds, from time t1 to time t2.
+---@param t2 integer
+---@param t1 integer
+---@return number
+function os.difftime(t2, t1) end
+
+---Passes the command to be executed by an operating system shell.
+---@version 5.1, JIT
+---@param command? string
+---@return integer code
+function os.execute(command) end
+
+---Passes the command to be executed by an operating system shell.
+---@version >5.2
+---@param command? string
+---@return boolean|nil success
+---@return "exit"|"signal" reason
+---@return integer code
+function os.execute(command) end
+
+---Terminates the host program.
+---@param code? boolean|integer
+---@param close? boolean
+function os.exit(code, close) end
+
+---Returns the value of the process environment variable, or nil if it is not defined.
+---@param varname string
+---@return string|nil
+function os.getenv(varname) end
+
+---Deletes the file or empty directory with the given name.
+---@param filename string
+---@return boolean|nil success
+---@return string|nil error_message
+function os.remove(filename) end
+
+---Renames the given file or directory.
+---@param oldname string
+---@param newname string
+---@return boolean|nil success
+---@return string|nil error_message
+function os.rename(oldname, newname) end
+
+---Sets the current locale of the program.
+---@param locale string|nil
+---@param category? "all"|"collate"|"ctype"|"monetary"|"numeric"|"time"
+---@return string|nil
+function os.setlocale(locale, category) end
+
+---Returns the current time when called without arguments, or the time represented by the given table.
+---@param date? table
+---@return integer
+function os.time(date) end
+
+---Returns a string with a file name that can be used for a temporary file.
+---@return string
+function os.tmpname() end
@@ -0,0 +1,41 @@
+---@meta
+
+---Facilities for loading modules.
+---@class packagelib
+---@field config string A string describing some compile-time configurations for packages.
+---@field cpath string The path used by `require` to search for a C loader.
+---@field loaded table<string, any> A table used by `require` to control which modules are already loaded.
+---@field path string The path used by `require` to search for a Lua loader.
+---@field preload table<string, function> A table to store loaders for specific modules.
+package = {}
+
+---A table used by `require` to control how to load modules.
+---@version 5.1, JIT
+---@type function[]
+package.loaders = {}
+
+---A table used by `require` to control how to find modules.
+---@version >5.2
+---@type function[]
+package.searchers = {}
+
+---Dynamically links the host program with the C library.
+---@param libname string
+---@param funcname string
+---@return any
+function package.loadlib(libname, funcname) end
+
+---Searches for the given name in the given path.
+---@version >5.2, JIT
+---@param name string
+---@param path string
+---@param sep? string
+---@param rep? string
+---@return string|nil filename
+---@return string|nil error_message
+function package.searchpath(name, path, sep, rep) end
+
+---Sets a metatable for the module with its `__index` field referring to the global environment.
+---@version 5.1, JIT
+---@param module table
+function package.seeall(module) end
@@ -0,0 +1,38 @@
+// Package stdlib contains definitions of the Lua standard library, written as `---@meta` files using the annotation
+// grammar. Definitions that only exist in some Lua versions are marked with `---@version`.
+package stdlib
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.lua
+var files embed.FS
+
+// Versions is the list of Lua versions that the definitions cover.
+var Versions = []string{"5.1", "5.2", "5.3", "5.4", "LuaJIT"}
+
+// Files returns the contents of every definition file, keyed by file name.
+func Files() map[string]string {
+	contents := map[string]string{}
+	entries, _ := fs.ReadDir(files, ".")
+	for _, entry := range entries {
+		data, err := files.ReadFile(entry.Name())
+		if err != nil {
+			continue
+		}
+		contents[entry.Name()] = string(data)
+	}
+	return contents
+}
+
+// IsVersion returns whether the given string is a supported Lua version.
+func IsVersion(version string) bool {
+	for _, v := range Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+---@meta
+
+---Functions for string manipulation. Strings use this table as the `__index` of their metatable, so these
+---functions can be called as methods, as in `s:upper()`.
+---@class stringlib
+string = {}
+
+---Returns the internal numeric codes of the characters `s[i]`, `s[i+1]`, ..., `s[j]`.
+---@param s string
+---@param i? integer
+---@param j? integer
+---@return integer ...
+function string.byte(s, i, j) end
+
+---Returns a string with the given internal numeric character codes.
+---@param ... integer
+---@return string
+function string.char(...) end
+
+---Returns a string containing a binary representation of the given function.
+---@param f function
+---@param strip? boolean
+---@return string
+function string.dump(f, strip) end
+
+---Looks for the first match of the pattern in the string, and returns its start and end indices followed by its
+---captures, or nil if there is no match.
+---@param s string
+---@param pattern string
+---@param init? integer
+---@param plain? boolean
+---@return integer|nil start
+---@return integer|nil end
+---@return any ... captures
+function string.find(s, pattern, init, plain) end
+
+---Returns a formatted version of its variable number of arguments following the description given in the format
+---string.
+---@param s string
+---@param ... any
+---@return string
+function string.format(s, ...) end
+
+---Returns an iterator function that returns the next captures of the pattern each time it is called.
+---@param s string
+---@param pattern string
+---@param init? integer
+---@return fun(): string, any
+function string.gmatch(s, pattern, init) end
+
+---Returns a copy of the string in which all or the first n occurrences of the pattern have been replaced.
+---@param s string
+---@param pattern string
+---@param repl string|table|function
+---@param n? integer
+---@return string
+---@return integer count
+function string.gsub(s, pattern, repl, n) end
+
+---Returns the length of the string.
+---@param s string
+---@return integer
+function string.len(s) end
+
+---Returns a copy of the string with all uppercase letters changed to lowercase.
+---@param s string
+---@return string
+function string.lower(s) end
+
+---Looks for the first match of the pattern in the string, and returns its captures, or nil if there is no match.
+---@param s string
+---@param pattern string
+---@param init? integer
+---@return string|nil ... captures
+function string.match(s, pattern, init) end
+
+---Returns a binary string containing the values packed according to the format string.
+---@version >5.3
+---@param fmt string
+---@param ... any
+---@return string
+function string.pack(fmt, ...) end
+
+---Returns the size of a string resulting from `string.pack` with the given format.
+---@version >5.3
+---@param fmt string
+---@return integer
+function string.packsize(fmt) end
+
+---Returns a string that is the concatenation of n copies of the string, separated by sep.
+---@param s string
+---@param n integer
+---@param sep? string
+---@return string
+function string.rep(s, n, sep) end
+
+---Returns the string reversed.
+---@param s string
+---@return string
+function string.reverse(s) end
+
+---Returns the substring of the string that starts at i and continues until j.
+---@param s string
+---@param i integer
+---@param j? integer
+---@return string
+function string.sub(s, i, j) end
+
+---Returns the values packed in the string according to the format string.
+---@version >5.3
+---@param fmt string
+---@param s string
+---@param pos? integer
+---@return any ...
+function string.unpack(fmt, s, pos) end
+
+---Returns a copy of the string with all lowercase letters changed to uppercase.
+---@param s string
+---@return string
+function string.upper(s) end
@@ -0,0 +1,74 @@
+---@meta
+
+---Functions for table manipulation.
+---@class tablelib
+table = {}
+
+---Returns the elements of the list concatenated into a string, separated by sep.
+---@param list table
+---@param sep? string
+---@param i? integer
+---@param j? integer
+---@return string
+function table.concat(list, sep, i, j) end
+
+---Returns the largest positive numerical index of the table.
+---@version 5.1, JIT
+---@param table table
+---@return integer
+function table.maxn(table) end
+
+---Inserts the value at position pos in the list, shifting up the following elements. If pos is omitted, the value
+---is appended to the end of the list.
+---@param list table
+---@param pos integer|any The position to insert at, or the value to append
+---@param value? any
+function table.insert(list, pos, value) end
+
+---Moves elements from one table to another.
+---@version >5.3
+---@param a1 table
+---@param f integer
+---@param e integer
+---@param t integer
+---@param a2? table
+---@return table
+function table.move(a1, f, e, t, a2) end
+
+---Returns a new table with all arguments stored into keys 1, 2, etc. and with a field `n` with the total number of
+---arguments.
+---@version >5.2
+---@param ... any
+---@return table
+function table.pack(...) end
+
+---Removes the element at position pos from the list, shifting down the following elements, and returns it.
+---@param list table
+---@param pos? integer
+---@return any
+function table.remove(list, pos) end
+
+---Sorts the elements of the list in place.
+---@param list table
+---@param comp? fun(a: any, b: any): boolean
+function table.sort(list, comp) end
+
+---Returns the elements of the given list.
+---@version >5.2
+---@param list table
+---@param i? integer
+---@param j? integer
+---@return any ...
+function table.unpack(list, i, j) end
+
+---Removes all keys from the table.
+---@version JIT
+---@param tab table
+function table.clear(tab) end
+
+---Creates a new table with space preallocated for the given number of array and hash elements.
+---@version JIT
+---@param narray integer
+---@param nhash integer
+---@return table
+function table.new(narray, nhash) end
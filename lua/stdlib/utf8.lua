@@ -0,0 +1,45 @@
+---@meta
+
+---Basic support for UTF-8 encoding.
+---@class utf8lib
+---@field charpattern string The pattern which matches exactly one UTF-8 byte sequence.
+---@version >5.3
+utf8 = {}
+
+---Converts each integer to its corresponding UTF-8 byte sequence and returns a string with the concatenation of
+---all these sequences.
+---@param ... integer
+---@return string
+function utf8.char(...) end
+
+---Returns values so that the construction `for p, c in utf8.codes(s) do` iterates over every UTF-8 character in the
+---string.
+---@param s string
+---@param lax? boolean
+---@return fun(s: string, i: integer): integer, integer
+function utf8.codes(s, lax) end
+
+---Returns the code points of every character in the string between positions i and j.
+---@param s string
+---@param i? integer
+---@param j? integer
+---@param lax? boolean
+---@return integer ...
+function utf8.codepoint(s, i, j, lax) end
+
+---Returns the number of UTF-8 characters in the string between positions i and j, or nil and the position of the
+---first invalid byte.
+---@param s string
+---@param i? integer
+---@param j? integer
+---@param lax? boolean
+---@return integer|nil
+---@return integer|nil position
+function utf8.len(s, i, j, lax) end
+
+---Returns the position, in bytes, where the encoding of the n-th character of the string starts.
+---@param s string
+---@param n integer
+---@param i? integer
+---@return integer|nil
+function utf8.offset(s, n, i) end
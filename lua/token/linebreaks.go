@@ -39,6 +39,10 @@ func (f LineBreaks) ToProtocolPos(pos Pos) protocol.Position {
 		}
 		line++
 	}
+	// The last line does not end with a line break
+	if line == len(f) {
+		lineStart = lineEnd + 1
+	}
 	return protocol.Position{
 		Line:      uint32(line),
 		Character: uint32(pos - lineStart),
@@ -80,7 +80,18 @@ const (
 	VARARG
 
 	// Annotation
+	DOC_ALIAS
 	DOC_CLASS
+	DOC_DEPRECATED
+	DOC_DIAGNOSTIC
+	DOC_FIELD
+	DOC_META
+	DOC_PARAM
+	DOC_RETURN
+	DOC_TYPE
+	DOC_VERSION
+	PIPE
+	QUESTION
 )
 
 func (t TokenType) String() string {
@@ -200,7 +211,18 @@ var TokenStr = map[TokenType]string{
 	VARARG:    "vararg",
 
 	// Annotation
-	DOC_CLASS: "@class",
+	DOC_ALIAS:      "@alias",
+	DOC_CLASS:      "@class",
+	DOC_DEPRECATED: "@deprecated",
+	DOC_DIAGNOSTIC: "@diagnostic",
+	DOC_FIELD:      "@field",
+	DOC_META:       "@meta",
+	DOC_PARAM:      "@param",
+	DOC_RETURN:     "@return",
+	DOC_TYPE:       "@type",
+	DOC_VERSION:    "@version",
+	PIPE:           "pipe",
+	QUESTION:       "question",
 }
 
 var Reserved = map[string]TokenType{
@@ -227,5 +249,59 @@ var Reserved = map[string]TokenType{
 	"until":    UNTIL,
 	"while":    WHILE,
 
-	"@class": DOC_CLASS,
+	"@alias":      DOC_ALIAS,
+	"@class":      DOC_CLASS,
+	"@deprecated": DOC_DEPRECATED,
+	"@diagnostic": DOC_DIAGNOSTIC,
+	"@field":      DOC_FIELD,
+	"@meta":       DOC_META,
+	"@param":      DOC_PARAM,
+	"@return":     DOC_RETURN,
+	"@type":       DOC_TYPE,
+	"@version":    DOC_VERSION,
+}
+
+// fixedText is the source text of the tokens other than keywords that are always written the same way.
+var fixedText = map[TokenType]string{
+	ASSIGN:    "=",
+	POW:       "^",
+	EQUAL:     "==",
+	GEQ:       ">=",
+	GT:        ">",
+	LEN:       "#",
+	LEQ:       "<=",
+	LT:        "<",
+	MINUS:     "-",
+	NEQ:       "~=",
+	MOD:       "%",
+	PLUS:      "+",
+	SLASH:     "/",
+	MUL:       "*",
+	LPAREN:    "(",
+	RPAREN:    ")",
+	LBRACK:    "[",
+	RBRACK:    "]",
+	LBRACE:    "{",
+	RBRACE:    "}",
+	COLON:     ":",
+	COMMA:     ",",
+	CONCAT:    "..",
+	DOT:       ".",
+	LABEL:     "::",
+	SEMICOLON: ";",
+	VARARG:    "...",
+}
+
+// Text returns how the token is written if it is always written the same way, such as `end` or `(`, or an empty
+// string if it is not, such as for identifiers and numbers.
+func (t TokenType) Text() string {
+	if text, ok := fixedText[t]; ok {
+		return text
+	}
+	for word, typ := range Reserved {
+		if typ == t && !strings.HasPrefix(word, "@") {
+			return word
+		}
+	}
+	return ""
 }
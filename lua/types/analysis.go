@@ -0,0 +1,90 @@
+package types
+
+import (
+	"github.com/raiguard/luapls/lua/annotation"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+type analysisState int
+
+const (
+	stateBound analysisState = iota
+	stateInferring
+	stateInferred
+)
+
+// Analysis holds the semantic information of a single file: which variable every identifier refers to, and the
+// inferred type of every expression.
+type Analysis struct {
+	File       *ast.File
+	Block      *ast.Block // The block that this analysis was computed from
+	Variables  []*Variable
+	Bindings   map[*ast.Identifier]*Variable // Identifier -> the local variable it refers to
+	Globals    []*ast.Identifier             // Identifiers that refer to global variables
+	SelfParams map[ast.Node]*Variable        // Method -> its implicit `self` parameter
+	Requires   []Require
+	Types      map[ast.Node]Type
+	Returns    Type // The values returned by the main chunk, or nil if it does not return anything
+
+	Diagnostics []ast.Diagnostic // Reported by the linter of the environment
+
+	annotations    map[ast.Node][]annotation.Annotation  // Statement or function -> the annotations that describe it
+	calls          map[*ast.Identifier]*ast.FunctionCall // Identifier -> the call it is the callee of
+	writes         map[*ast.Identifier]bool              // Identifiers that are assigned to rather than read
+	shadows        map[*Variable]*Variable               // Variable -> the visible variable of the same name when it was declared
+	extendsClasses bool                                  // Inference added members to a class
+	checked        bool                                  // The file has been linted
+	state          analysisState
+}
+
+func newAnalysis(file *ast.File) *Analysis {
+	return &Analysis{
+		File:        file,
+		Block:       file.Block,
+		Bindings:    map[*ast.Identifier]*Variable{},
+		SelfParams:  map[ast.Node]*Variable{},
+		Types:       map[ast.Node]Type{},
+		annotations: map[ast.Node][]annotation.Annotation{},
+		calls:       map[*ast.Identifier]*ast.FunctionCall{},
+		writes:      map[*ast.Identifier]bool{},
+		shadows:     map[*Variable]*Variable{},
+	}
+}
+
+// TypeOf returns the type of the given node, or Unknown if it has not been inferred.
+func (a *Analysis) TypeOf(node ast.Node) Type {
+	if typ := a.Types[node]; typ != nil {
+		return typ
+	}
+	return &Unknown{}
+}
+
+// reset discards all inferred types so that they will be recomputed on the next access.
+func (a *Analysis) reset() {
+	a.Types = map[ast.Node]Type{}
+	a.Returns = nil
+	a.Diagnostics = nil
+	a.extendsClasses = false
+	a.checked = false
+	a.state = stateBound
+}
+
+// diagnosticList collects the diagnostics reported by a check.
+type diagnosticList []ast.Diagnostic
+
+func (d *diagnosticList) add(rng token.Range, severity protocol.DiagnosticSeverity, code string, message string) {
+	*d = append(*d, ast.Diagnostic{Message: message, Range: rng, Severity: severity, Code: code})
+}
+
+// addUnnecessary adds a diagnostic for code that has no effect, which editors display faded out.
+func (d *diagnosticList) addUnnecessary(rng token.Range, code string, message string) {
+	*d = append(*d, ast.Diagnostic{
+		Message:  message,
+		Range:    rng,
+		Severity: protocol.DiagnosticSeverityHint,
+		Code:     code,
+		Tags:     []protocol.DiagnosticTag{protocol.DiagnosticTagUnnecessary},
+	})
+}
@@ -0,0 +1,380 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/annotation"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+)
+
+// DefaultLuaVersion is the Lua version that is used if none is configured.
+const DefaultLuaVersion = "5.4"
+
+// fileAnnotations holds the annotations found in the comments of a single file.
+type fileAnnotations struct {
+	nodes        map[ast.Node][]annotation.Annotation // Node -> the annotations in its leading comments
+	docs         map[ast.Node]*Doc                    // Node -> the documentation in its leading comments
+	diagnostics  []ast.Diagnostic
+	suppressions []*suppression // `---@diagnostic` comments, in order
+	meta         bool           // The file is a `---@meta` definition file
+	declares     bool           // The file declares classes or aliases
+}
+
+// collectAnnotations parses every annotation comment in the file and registers the classes and aliases that it
+// declares.
+func (e *Environment) collectAnnotations(file *ast.File) {
+	e.removeTypesFrom(file)
+	fa := &fileAnnotations{nodes: map[ast.Node][]annotation.Annotation{}, docs: map[ast.Node]*Doc{}}
+	e.annotations[file.URI] = fa
+	// Nested statements share their leading trivia, so each comment is attributed to the outermost one only
+	seen := map[token.Pos]bool{}
+	// Suppression comments may appear anywhere, including after the last statement
+	for _, comment := range file.Comments {
+		content, ok := strings.CutPrefix(comment.Literal, "---")
+		if !ok || !strings.HasPrefix(strings.TrimSpace(content), "@diagnostic") {
+			continue
+		}
+		seen[comment.Pos] = true
+		a, diags := annotation.Parse(content)
+		fa.addDiagnostics(diags, comment.Pos+3)
+		if d, ok := a.(*annotation.Diagnostic); ok {
+			fa.suppressions = append(fa.suppressions, newSuppression(file, comment, d))
+		}
+	}
+	ast.WalkSemantic(file.Block, func(n ast.Node) bool {
+		if _, ok := n.(ast.Statement); !ok {
+			return true
+		}
+		var class *classDecl
+		var description []string
+		for _, trivia := range n.GetLeadingTrivia() {
+			// A blank line separates the description of a statement from unrelated comments above it
+			if trivia.Type == token.WHITESPACE && strings.Count(trivia.Literal, "\n") > 1 {
+				description = nil
+			}
+			if trivia.Type != token.COMMENT || seen[trivia.Pos] {
+				continue
+			}
+			seen[trivia.Pos] = true
+			content, ok := strings.CutPrefix(trivia.Literal, "---")
+			if !ok {
+				continue
+			}
+			if line, ok := descriptionLine(content); ok {
+				description = append(description, line)
+				continue
+			}
+			a, diags := annotation.Parse(content)
+			fa.addDiagnostics(diags, trivia.Pos+3)
+			if a == nil {
+				continue
+			}
+			fa.nodes[n] = append(fa.nodes[n], a)
+			switch a := a.(type) {
+			case *annotation.Alias:
+				fa.declares = true
+				rng := token.Range{Start: a.NameRange.Start + trivia.Pos + 3, End: a.NameRange.End + trivia.Pos + 3}
+				if e.Types[a.Name] == nil {
+					e.Types[a.Name] = &Named{Name: a.Name, URI: file.URI, Range: rng, node: n, alias: a.Type}
+				}
+			case *annotation.Class:
+				fa.declares = true
+				rng := token.Range{Start: a.NameRange.Start + trivia.Pos + 3, End: a.NameRange.End + trivia.Pos + 3}
+				named, _ := e.Types[a.Name].(*Named)
+				if named == nil {
+					named = &Named{Name: a.Name, URI: file.URI, Range: rng, node: n}
+					e.Types[a.Name] = named
+				}
+				if !named.IsClass() {
+					class = nil
+					continue
+				}
+				class = &classDecl{uri: file.URI, parents: a.Parents}
+				named.decls = append(named.decls, class)
+			case *annotation.Field:
+				fa.declares = true
+				if class != nil {
+					class.fields = append(class.fields, a)
+				}
+			case *annotation.Meta:
+				fa.meta = true
+			}
+		}
+		if doc := newDoc(description, fa.nodes[n]); doc != nil {
+			fa.docs[n] = doc
+		}
+		return true
+	})
+}
+
+// descriptionLine returns the text of a documentation comment that is not an annotation. Lines that only consist
+// of dashes are separators rather than descriptions.
+func descriptionLine(content string) (string, bool) {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "@") {
+		return "", false
+	}
+	if trimmed != "" && strings.Trim(trimmed, "-") == "" {
+		return "", false
+	}
+	return strings.TrimPrefix(strings.TrimRight(content, " \t\r"), " "), true
+}
+
+// addDiagnostics adds the diagnostics produced by parsing an annotation, whose ranges are relative to the given
+// offset.
+func (fa *fileAnnotations) addDiagnostics(diags []ast.Diagnostic, offset token.Pos) {
+	for _, diag := range diags {
+		diag.Range.Start += offset
+		diag.Range.End += offset
+		fa.diagnostics = append(fa.diagnostics, diag)
+	}
+}
+
+// removeTypesFrom removes the declarations of classes and aliases that the given file contributed.
+func (e *Environment) removeTypesFrom(file *ast.File) {
+	for name, typ := range e.Types {
+		named, ok := typ.(*Named)
+		if !ok {
+			continue
+		}
+		decls := named.decls[:0]
+		for _, decl := range named.decls {
+			if decl.uri != file.URI {
+				decls = append(decls, decl)
+			}
+		}
+		named.decls = decls
+		if named.URI == file.URI && len(decls) == 0 {
+			delete(e.Types, name)
+		}
+	}
+}
+
+// declaresTypes returns whether the given file declares any classes or aliases.
+func (e *Environment) declaresTypes(file *ast.File) bool {
+	fa := e.annotations[file.URI]
+	return fa != nil && fa.declares
+}
+
+// resetTypes discards the resolved members of every class so that they will be recomputed from their annotations
+// and the code that assigns to them.
+func (e *Environment) resetTypes() {
+	for _, typ := range e.Types {
+		if named, ok := typ.(*Named); ok {
+			named.Fields = nil
+			named.Parents = nil
+			named.Alias = nil
+			named.resolved = false
+		}
+	}
+}
+
+// nodeDoc returns the documentation in the leading comments of the given node, or nil if it has none.
+func (e *Environment) nodeDoc(file *ast.File, node ast.Node) *Doc {
+	fa := e.annotations[file.URI]
+	if fa == nil {
+		return nil
+	}
+	return fa.docs[node]
+}
+
+// nodeAnnotations returns the annotations in the leading comments of the given node.
+func (e *Environment) nodeAnnotations(file *ast.File, node ast.Node) []annotation.Annotation {
+	fa := e.annotations[file.URI]
+	if fa == nil {
+		return nil
+	}
+	return fa.nodes[node]
+}
+
+// isExcluded returns whether the given statement is restricted by a `@version` annotation to Lua versions other
+// than the configured one.
+func (e *Environment) isExcluded(file *ast.File, node ast.Node) bool {
+	for _, a := range e.nodeAnnotations(file, node) {
+		if version, ok := a.(*annotation.Version); ok && !versionMatches(version, e.luaVersion()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Environment) luaVersion() string {
+	if e.LuaVersion == "" {
+		return DefaultLuaVersion
+	}
+	return e.LuaVersion
+}
+
+// versionMatches returns whether the given Lua version satisfies the annotation. `>5.2` matches 5.2 and every later
+// version, and `<5.2` matches 5.2 and every earlier version. LuaJIT only matches an explicit `JIT`.
+func versionMatches(v *annotation.Version, version string) bool {
+	isJIT := version == "LuaJIT" || version == "JIT"
+	number, _ := strconv.ParseFloat(version, 64)
+	for _, constraint := range v.Versions {
+		if constraint.Version == "JIT" {
+			if isJIT {
+				return true
+			}
+			continue
+		}
+		if isJIT {
+			continue
+		}
+		want, err := strconv.ParseFloat(constraint.Version, 64)
+		if err != nil {
+			continue
+		}
+		switch constraint.Operator {
+		case token.GT:
+			if number >= want {
+				return true
+			}
+		case token.LT:
+			if number <= want {
+				return true
+			}
+		default:
+			if number == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupType returns the class or alias with the given name, or nil if it does not exist. Aliases are resolved to
+// the type that they stand for.
+func (e *Environment) lookupType(name string) Type {
+	e.loadPendingType(name)
+	named, ok := e.Types[name].(*Named)
+	if !ok {
+		return nil
+	}
+	e.resolveNamed(named)
+	if named.Alias != nil {
+		return named.Alias
+	}
+	return named
+}
+
+// resolveNamed converts the annotations that declare a class or alias to types. This is done lazily so that
+// classes may refer to classes declared later or in other files.
+func (e *Environment) resolveNamed(named *Named) {
+	if named.resolved {
+		return
+	}
+	named.resolved = true
+	if named.alias != nil {
+		// Guard against aliases that refer to themselves
+		named.Alias = &Unknown{}
+		named.Alias = e.resolveType(named.alias)
+		return
+	}
+	named.Fields = &Table{}
+	for _, decl := range named.decls {
+		for _, name := range decl.parents {
+			if parent, ok := e.lookupType(name).(*Named); ok && parent != named {
+				named.Parents = append(named.Parents, parent)
+			}
+		}
+		for _, field := range decl.fields {
+			typ := e.resolveType(field.Type)
+			if field.Optional {
+				typ = NewUnion(typ, &Nil{})
+			}
+			named.Fields.SetField(NameAndType{Name: field.Name, Type: typ})
+		}
+	}
+	// Methods are usually assigned to a class in the files that declare it
+	for _, decl := range named.decls {
+		if file := e.Files[decl.uri]; file != nil {
+			e.analysis(file)
+		}
+	}
+}
+
+// resolveType converts a type expression written in an annotation to a type. Unknown names resolve to Unknown.
+func (e *Environment) resolveType(expr annotation.TypeExpr) Type {
+	switch expr := expr.(type) {
+	case *annotation.ArrayType:
+		return &Table{Key: &Number{}, Value: e.resolveType(expr.Elem)}
+	case *annotation.FunctionType:
+		fn := &Function{Vararg: expr.Vararg != nil}
+		for _, param := range expr.Params {
+			typ := e.resolveType(param.Type)
+			if param.Optional {
+				typ = NewUnion(typ, &Nil{})
+			}
+			fn.Params = append(fn.Params, NameAndType{Name: param.Name, Type: typ})
+		}
+		fn.Return = e.resolveTypeList(expr.Returns)
+		return fn
+	case *annotation.LiteralType:
+		return &String{}
+	case *annotation.NamedType:
+		return e.resolveNamedType(expr)
+	case *annotation.TableLiteralType:
+		table := &Table{}
+		for _, field := range expr.Fields {
+			typ := e.resolveType(field.Type)
+			if field.Optional {
+				typ = NewUnion(typ, &Nil{})
+			}
+			table.SetField(NameAndType{Name: field.Name, Type: typ})
+		}
+		return table
+	case *annotation.UnionType:
+		types := make([]Type, len(expr.Types))
+		for i, member := range expr.Types {
+			types[i] = e.resolveType(member)
+		}
+		return NewUnion(types...)
+	}
+	return &Unknown{}
+}
+
+// resolveTypeList converts a list of types, such as the return values of a function, to a single type. The result
+// is nil if the list is empty.
+func (e *Environment) resolveTypeList(exprs []annotation.TypeExpr) Type {
+	switch len(exprs) {
+	case 0:
+		return nil
+	case 1:
+		return e.resolveType(exprs[0])
+	}
+	types := make([]Type, len(exprs))
+	for i, expr := range exprs {
+		types[i] = e.resolveType(expr)
+	}
+	return &Tuple{Types: types}
+}
+
+func (e *Environment) resolveNamedType(expr *annotation.NamedType) Type {
+	switch expr.Name {
+	case "any":
+		return &Any{}
+	case "boolean":
+		return &Boolean{}
+	case "function":
+		return &Function{Vararg: true, Return: &Any{}}
+	case "integer", "number":
+		return &Number{}
+	case "nil":
+		return &Nil{}
+	case "string":
+		return &String{}
+	case "table":
+		if len(expr.Args) == 2 {
+			return &Table{Key: e.resolveType(expr.Args[0]), Value: e.resolveType(expr.Args[1])}
+		}
+		return &Table{Key: &Any{}, Value: &Any{}}
+	case "unknown":
+		return &Unknown{}
+	}
+	if typ := e.lookupType(expr.Name); typ != nil {
+		return typ
+	}
+	return &Unknown{}
+}
@@ -0,0 +1,114 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotations(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected string
+	}{
+		{"param", "---@param a string\nlocal function f(a) local x = a end", "string"},
+		{"optional param", "---@param a? number\nlocal function f(a) local x = a end", "number|nil"},
+		{"return", "---@return string\nlocal function f() end local x = f()", "string"},
+		{"multiple returns", "---@return number\n---@return boolean\nlocal function f() end local _, x = f()", "boolean"},
+		{"function expression", "---@param a number\n---@return string\nlocal f = function(a) end local x = f", "function(a: number) → string"},
+		{"type", "---@type string|nil\nlocal x = nil", "string|nil"},
+		{"array", "---@type string[]\nlocal t = {} local x = t[1]", "string"},
+		{"generic table", "---@type table<string, boolean>\nlocal t = {} local x = t.foo", "boolean"},
+		{"function type", "---@type fun(a: number): string\nlocal x", "function(a: number) → string"},
+		{"alias", "---@alias Id string|number\n---@type Id\nlocal x", "string|number"},
+		{"class", "---@class Point\n---@field x number\nlocal p = {} local x = p", "Point"},
+		{"class field", "---@class Point\n---@field x number\n---@type Point\nlocal p local x = p.x", "number"},
+		{"class method", "---@class Point\nlocal Point = {} function Point:get() return 1 end\n---@type Point\nlocal p local x = p:get()", "number"},
+		{"class literal field", "---@class Point\nlocal Point = {y = 'foo'}\n---@type Point\nlocal p local x = p.y", "string"},
+		{"inherited field", "---@class A\n---@field a string\n---@class B : A\n---@type B\nlocal b local x = b.a", "string"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			assert.Equal(t, test.expected, typeOfLocal(t, env, file, "x"))
+		})
+	}
+}
+
+func TestStdlib(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected string
+	}{
+		{"function", "local x = tostring(1)", "string"},
+		{"library function", "local x = string.format('%d', 1)", "string"},
+		{"string method", "local s = 'foo' local x = s:upper()", "string"},
+		{"field", "local x = math.pi", "number"},
+		{"class", "local f = io.open('foo') local x = f", "file|nil"},
+		{"class method", "local f = io.open('foo') if f then local x = f:read('a') end", "any"},
+		{"ipairs", "for i in ipairs({}) do local x = i end", "number"},
+		{"version", "local x = table.unpack", "function(list: table, i: number|nil, j: number|nil) → any"},
+		{"type name", "local f = io.stdout if type(f) == 'userdata' then local x = f end", "file"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			assert.Equal(t, test.expected, typeOfLocal(t, env, file, "x"))
+			assert.Empty(t, diagnosticMessages(env, file))
+		})
+	}
+}
+
+func TestStdlibVersions(t *testing.T) {
+	tests := []struct {
+		version  string
+		input    string
+		expected string
+	}{
+		{"5.1", "local x = unpack", "function(list: table, i: number|nil, j: number|nil) → any"},
+		{"5.4", "local x = unpack", "unknown"},
+		{"5.1", "local x = table.unpack", "unknown"},
+		{"5.3", "local x = utf8.char(65)", "string"},
+		{"5.2", "local x = utf8", "unknown"},
+		{"LuaJIT", "local x = bit.band(1, 2)", "number"},
+		{"5.4", "local x = bit", "unknown"},
+		{"LuaJIT", "local x = table.unpack", "unknown"},
+	}
+	for _, test := range tests {
+		t.Run(test.version+" "+test.input, func(t *testing.T) {
+			env := NewEnvironment()
+			env.SetLuaVersion(test.version)
+			file := env.AddTransientFile("file:///test.lua", test.input)
+			env.CheckPhase1()
+			env.CheckPhase2()
+			assert.Equal(t, test.expected, typeOfLocal(t, env, file, "x"))
+		})
+	}
+}
+
+func TestLibraryDiagnostics(t *testing.T) {
+	env, _ := analyze(t, "")
+	for uri, file := range env.Files {
+		if env.IsLibrary(uri) {
+			assert.Empty(t, env.Diagnostics(file))
+			assert.Empty(t, env.annotations[uri].diagnostics, "annotation errors in %s", uri)
+			assert.Empty(t, file.Diagnostics, "syntax errors in %s", uri)
+		}
+	}
+}
+
+func TestAnnotationDiagnostics(t *testing.T) {
+	env, file := analyze(t, "---@class\nlocal x = 1")
+	assert.Equal(t, []string{"Expected identifier"}, diagnosticMessages(env, file))
+}
+
+func TestClassRecheck(t *testing.T) {
+	env, file := analyze(t, "---@class Point\n---@field x number\n---@type Point\nlocal p local x = p.x")
+	assert.Equal(t, "number", typeOfLocal(t, env, file, "x"))
+	_, replacement := analyze(t, "---@class Point\n---@field x string\n---@type Point\nlocal p local x = p.x")
+	file.Block = replacement.Block
+	env.CheckFile(file)
+	assert.Equal(t, "string", typeOfLocal(t, env, file, "x"))
+}
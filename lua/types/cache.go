@@ -0,0 +1,325 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/util"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Summary is what is cached about a file: the names that it defines for other files, so that it is only parsed once
+// one of them is needed, and its diagnostics, so that they can be shown without parsing it.
+type Summary struct {
+	LineBreaks  token.LineBreaks
+	Globals     []string         // The global variables that the file assigns
+	Types       []string         // The classes and aliases that the file declares
+	Reads       []string         // The global variables that the file reads
+	Requires    []protocol.URI   // The files that the file requires
+	Diagnostics []ast.Diagnostic // The diagnostics of the file, as returned by Report
+	Fingerprint string           // The fingerprint of the inputs that the diagnostics were computed from
+}
+
+// Cache stores the summaries of files between runs. Summaries are stored for the path of a file and the hash of its
+// contents, and must only be loaded for the same path and hash.
+type Cache interface {
+	Load(path string, hash string) *Summary // Returns nil if there is no summary for the contents
+	Store(path string, hash string, summary *Summary)
+}
+
+// pendingFile is a file that has not been parsed because its summary was in the cache.
+type pendingFile struct {
+	source
+	summary *Summary
+}
+
+func (e *Environment) addPending(src source, hash string, summary *Summary) {
+	e.pending[src.uri] = &pendingFile{src, summary}
+	e.setHash(src.uri, hash)
+	for _, name := range summary.Globals {
+		e.pendingGlobals[name] = append(e.pendingGlobals[name], src.uri)
+	}
+	for _, name := range summary.Types {
+		e.pendingTypes[name] = append(e.pendingTypes[name], src.uri)
+	}
+}
+
+func (e *Environment) removePending(uri protocol.URI) *pendingFile {
+	p := e.pending[uri]
+	if p == nil {
+		return nil
+	}
+	delete(e.pending, uri)
+	remove := func(index map[string][]protocol.URI, name string) {
+		index[name] = slices.DeleteFunc(index[name], func(other protocol.URI) bool { return other == uri })
+		if len(index[name]) == 0 {
+			delete(index, name)
+		}
+	}
+	for _, name := range p.summary.Globals {
+		remove(e.pendingGlobals, name)
+	}
+	for _, name := range p.summary.Types {
+		remove(e.pendingTypes, name)
+	}
+	return p
+}
+
+// loadPending parses the pending file with the given URI and binds it, like a module that is required for the first
+// time. It returns nil if there is no such file.
+func (e *Environment) loadPending(uri protocol.URI) *ast.File {
+	p := e.removePending(uri)
+	if p == nil {
+		return nil
+	}
+	result, err := parseSource(p.source, nil)
+	if err != nil {
+		e.log.Errorf("Failed to parse file %s: %s", p.path, err)
+		return nil
+	}
+	file := e.addParsedFile(result.file, result.hash, p.library)
+	e.collectAnnotations(file)
+	e.bindFile(file)
+	return file
+}
+
+// loadPendingGlobal parses the pending files that assign the given global, so that every definition of it is known.
+func (e *Environment) loadPendingGlobal(name string) {
+	for _, uri := range slices.Clone(e.pendingGlobals[name]) {
+		e.loadPending(uri)
+	}
+}
+
+// loadPendingType parses the pending files that declare the given class or alias, so that every declaration of it is
+// known.
+func (e *Environment) loadPendingType(name string) {
+	for _, uri := range slices.Clone(e.pendingTypes[name]) {
+		e.loadPending(uri)
+	}
+}
+
+// loadPendingWorkspace parses every pending workspace file, and returns them.
+func (e *Environment) loadPendingWorkspace() []*ast.File {
+	files := []*ast.File{}
+	for uri, p := range e.pending {
+		if p.library {
+			continue
+		}
+		if file := e.loadPending(uri); file != nil {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// loadOutdatedPending parses the pending workspace files whose cached diagnostics are not valid for the workspace as
+// it is now.
+func (e *Environment) loadOutdatedPending() {
+	outdated := []protocol.URI{}
+	for uri, p := range e.pending {
+		if !p.library && p.summary.Fingerprint != e.fileFingerprint(uri) {
+			outdated = append(outdated, uri)
+		}
+	}
+	// Fingerprints are computed before any file is parsed, since parsing a file changes what is known about it
+	for _, uri := range outdated {
+		e.loadPending(uri)
+	}
+}
+
+// loadPendingAffected parses the pending workspace files whose diagnostics may change because the given globals were
+// redefined or the given files changed, directly or through the files that they require. The files that are parsed
+// are added to affected.
+func (e *Environment) loadPendingAffected(globals map[string]bool, affected map[protocol.URI]bool) {
+	for changed := true; changed; {
+		changed = false
+		for uri, p := range e.pending {
+			if p.library {
+				continue
+			}
+			uses := slices.ContainsFunc(p.summary.Reads, func(name string) bool { return globals[name] }) ||
+				slices.ContainsFunc(p.summary.Requires, func(dep protocol.URI) bool { return affected[dep] })
+			if uses && e.loadPending(uri) != nil {
+				affected[uri] = true
+				changed = true
+			}
+		}
+	}
+}
+
+func (e *Environment) setHash(uri protocol.URI, hash string) {
+	if e.hashes[uri] != hash {
+		e.hashes[uri] = hash
+		e.fingerprint = ""
+	}
+}
+
+// workspaceFingerprint returns a hash of the settings that affect diagnostics, of the URIs of every file, and of the
+// contents of every file that declares a class or alias. Classes can be extended by any file, so the types of every
+// file may depend on them.
+func (e *Environment) workspaceFingerprint() string {
+	if e.fingerprint != "" {
+		return e.fingerprint
+	}
+	typeFiles := map[protocol.URI]bool{}
+	for _, typ := range e.Types {
+		if named, ok := typ.(*Named); ok {
+			for _, decl := range named.decls {
+				typeFiles[decl.uri] = true
+			}
+		}
+	}
+	for _, uris := range e.pendingTypes {
+		for _, uri := range uris {
+			typeFiles[uri] = true
+		}
+	}
+	h := sha256.New()
+	uris := make([]protocol.URI, 0, len(e.hashes))
+	for uri := range e.hashes {
+		uris = append(uris, uri)
+	}
+	slices.Sort(uris)
+	for _, uri := range uris {
+		if typeFiles[uri] {
+			fmt.Fprintf(h, "%s\x00%s\n", uri, e.hashes[uri])
+		} else {
+			fmt.Fprintf(h, "%s\n", uri)
+		}
+	}
+	fmt.Fprintf(h, "%q %q %q %q %q %v %v %v %v", e.RootPath, e.Roots, e.PackagePath, e.LuaVersion, e.Library,
+		e.AllowedGlobals, e.StrictGlobals, e.Severities, e.Linter)
+	e.fingerprint = hex.EncodeToString(h.Sum(nil))
+	return e.fingerprint
+}
+
+// fileFingerprint returns a hash of the workspace fingerprint and of the contents of the file and every file that
+// its diagnostics may depend on: the files that it requires and the files that define the globals that it reads,
+// along with the files that those depend on in turn. Cached diagnostics are only valid for the same fingerprint, so
+// editing a file only makes the diagnostics of the files that depend on it out of date.
+func (e *Environment) fileFingerprint(uri protocol.URI) string {
+	deps := map[protocol.URI]bool{}
+	stack := []protocol.URI{uri}
+	for len(stack) > 0 {
+		uri := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if deps[uri] {
+			continue
+		}
+		deps[uri] = true
+		requires, reads := e.fileInputs(uri)
+		stack = append(stack, requires...)
+		for _, name := range reads {
+			if global := e.Globals[name]; global != nil {
+				for _, def := range global.Defs {
+					stack = append(stack, def.File.URI)
+				}
+			}
+			stack = append(stack, e.pendingGlobals[name]...)
+		}
+	}
+	sorted := make([]protocol.URI, 0, len(deps))
+	for dep := range deps {
+		sorted = append(sorted, dep)
+	}
+	slices.Sort(sorted)
+	h := sha256.New()
+	fmt.Fprintln(h, e.workspaceFingerprint())
+	for _, dep := range sorted {
+		fmt.Fprintf(h, "%s\x00%s\n", dep, e.hashes[dep])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fileInputs returns the files that the file requires and the globals that it reads, from its summary if it is
+// pending.
+func (e *Environment) fileInputs(uri protocol.URI) ([]protocol.URI, []string) {
+	if p := e.pending[uri]; p != nil {
+		return p.summary.Requires, p.summary.Reads
+	}
+	return e.dependencies(uri), e.globalReads(uri)
+}
+
+// globalReads returns the sorted names of the globals that the file reads.
+func (e *Environment) globalReads(uri protocol.URI) []string {
+	a := e.analyses[uri]
+	if a == nil {
+		return nil
+	}
+	reads := []string{}
+	for _, ident := range a.Globals {
+		if name := ident.Token.Literal; !a.writes[ident] && !slices.Contains(reads, name) {
+			reads = append(reads, name)
+		}
+	}
+	slices.Sort(reads)
+	return reads
+}
+
+// CachedDiagnostics returns the summaries of the pending workspace files whose cached diagnostics are still valid.
+// Pending files whose diagnostics are out of date, because a file that they depend on or the configuration has
+// changed, are parsed instead, so that they are returned by FileList.
+func (e *Environment) CachedDiagnostics() map[protocol.URI]*Summary {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.loadOutdatedPending()
+	summaries := map[protocol.URI]*Summary{}
+	for uri, p := range e.pending {
+		if !p.library {
+			summaries[uri] = p.summary
+		}
+	}
+	return summaries
+}
+
+// SaveCache stores the summaries of every parsed file that has a path in the cache. The diagnostics of workspace
+// files are computed first if needed.
+func (e *Environment) SaveCache() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.Cache == nil {
+		return
+	}
+	globals := map[protocol.URI][]string{}
+	for name, global := range e.Globals {
+		for _, def := range global.Defs {
+			if uri := def.File.URI; !slices.Contains(globals[uri], name) {
+				globals[uri] = append(globals[uri], name)
+			}
+		}
+	}
+	types := map[protocol.URI][]string{}
+	for name, typ := range e.Types {
+		if named, ok := typ.(*Named); ok {
+			for _, decl := range named.decls {
+				types[decl.uri] = append(types[decl.uri], name)
+			}
+		}
+	}
+	for uri, file := range e.Files {
+		path, err := util.URIToPath(uri)
+		if err != nil || strings.HasPrefix(uri, StdlibURI) {
+			continue
+		}
+		summary := &Summary{
+			LineBreaks: file.LineBreaks,
+			Globals:    globals[uri],
+			Types:      types[uri],
+		}
+		slices.Sort(summary.Globals)
+		slices.Sort(summary.Types)
+		summary.Types = slices.Compact(summary.Types)
+		if !e.isLibrary(uri) {
+			summary.Diagnostics = e.report(file)
+			summary.Reads = e.globalReads(uri)
+			summary.Requires = e.dependencies(uri)
+			summary.Fingerprint = e.fileFingerprint(uri)
+		}
+		e.Cache.Store(path, e.hashes[uri], summary)
+	}
+}
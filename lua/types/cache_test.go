@@ -0,0 +1,123 @@
+package types
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/raiguard/luapls/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// memoryCache is a Cache that keeps its summaries in memory.
+type memoryCache map[string]*Summary
+
+func (c memoryCache) Load(path string, hash string) *Summary {
+	return c[path+"\x00"+hash]
+}
+
+func (c memoryCache) Store(path string, hash string, summary *Summary) {
+	c[path+"\x00"+hash] = summary
+}
+
+var cachedWorkspace = map[string]string{
+	"main.lua":  "local lib = require('lib')\nprint(lib.value, helper(), undefined)",
+	"lib.lua":   "---@class Lib\n---@field value number\nreturn {}",
+	"other.lua": "function helper() return 1 end",
+}
+
+// indexCached indexes the workspace in the directory with the cache, and returns the environment.
+func indexCached(t *testing.T, root string, cache Cache) *Environment {
+	env := newEnvironment()
+	env.RootPath = root
+	env.Cache = cache
+	require.NoError(t, env.Index(context.Background(), nil))
+	return env
+}
+
+func uriOf(t *testing.T, env *Environment, name string) protocol.URI {
+	uri, err := util.PathToURI(filepath.Join(env.RootPath, name))
+	require.NoError(t, err)
+	return uri
+}
+
+func TestCache(t *testing.T) {
+	root := writeFiles(t, cachedWorkspace)
+	cache := memoryCache{}
+	first := indexCached(t, root, cache)
+	assert.Empty(t, first.CachedDiagnostics())
+	expected := messages(first.Report(workspaceFile(t, first, "main.lua")))
+	assert.Equal(t, []string{"Undefined global 'undefined'"}, expected)
+	first.SaveCache()
+	assert.Len(t, cache, 3)
+
+	// Nothing has changed, so no file is parsed
+	second := indexCached(t, root, cache)
+	assert.Empty(t, discoveredFiles(t, second))
+	cached := second.CachedDiagnostics()
+	require.Len(t, cached, 3)
+	assert.Equal(t, expected, messages(cached[uriOf(t, second, "main.lua")].Diagnostics))
+	assert.Empty(t, discoveredFiles(t, second))
+
+	// Pending files are parsed once they are needed
+	main := second.File(uriOf(t, second, "main.lua"))
+	require.NotNil(t, main)
+	assert.Equal(t, expected, messages(second.Report(main)))
+	assert.Equal(t, []string{"lib.lua", "main.lua", "other.lua"}, discoveredFiles(t, second))
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	root := writeFiles(t, cachedWorkspace)
+	cache := memoryCache{}
+	first := indexCached(t, root, cache)
+	first.SaveCache()
+
+	// The changed file is parsed, and the diagnostics of the file that reads the global it defines are out of date.
+	// The module that file requires is parsed along with it.
+	require.NoError(t, os.WriteFile(filepath.Join(root, "other.lua"), []byte("function helper() return 1 end\nundefined = 1"), 0644))
+	second := indexCached(t, root, cache)
+	assert.Equal(t, []string{"other.lua"}, discoveredFiles(t, second))
+	assert.Empty(t, second.CachedDiagnostics())
+	assert.Empty(t, messages(second.Report(workspaceFile(t, second, "main.lua"))))
+	second.SaveCache()
+
+	// No file depends on the main file, so changing it leaves the diagnostics of the others valid
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.lua"), []byte("local lib = require('lib')\nprint(lib.value)"), 0644))
+	env := indexCached(t, root, cache)
+	cached := env.CachedDiagnostics()
+	assert.Len(t, cached, 1)
+	assert.Contains(t, cached, uriOf(t, env, "other.lua"))
+	assert.Equal(t, []string{"lib.lua", "main.lua"}, discoveredFiles(t, env))
+	env.SaveCache()
+
+	// A different configuration makes the cached diagnostics out of date as well
+	third := newEnvironment()
+	third.RootPath = root
+	third.Cache = cache
+	third.AllowedGlobals = []string{"extra"}
+	require.NoError(t, third.Index(context.Background(), nil))
+	assert.Empty(t, third.CachedDiagnostics())
+	assert.Len(t, discoveredFiles(t, third), 3)
+}
+
+func TestCachePendingUpdates(t *testing.T) {
+	root := writeFiles(t, cachedWorkspace)
+	cache := memoryCache{}
+	first := indexCached(t, root, cache)
+	first.SaveCache()
+
+	env := indexCached(t, root, cache)
+	require.Len(t, env.CachedDiagnostics(), 3)
+	// Defining the undefined global changes the diagnostics of main.lua, which must be parsed to report them
+	other := env.File(uriOf(t, env, "other.lua"))
+	affected := env.UpdateFile(other.URI, "function helper() return 1 end\nundefined = 1")
+	uris := []protocol.URI{}
+	for _, file := range affected {
+		uris = append(uris, file.URI)
+	}
+	assert.Contains(t, uris, uriOf(t, env, "main.lua"))
+	assert.Empty(t, messages(env.Report(env.File(uriOf(t, env, "main.lua")))))
+}
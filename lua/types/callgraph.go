@@ -0,0 +1,299 @@
+package types
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+)
+
+// Callable is a function that calls can be resolved to, or the main chunk of a file, which can only be a caller.
+type Callable struct {
+	File *ast.File
+	Node ast.Node  // The *ast.FunctionStatement or *ast.FunctionExpression, or nil for the main chunk
+	Name string    // The name that the function is declared or assigned with, such as `M.init` or `Player:greet`
+	Type *Function // nil for the main chunk
+}
+
+// MainChunk returns the main chunk of the file.
+func MainChunk(file *ast.File) *Callable {
+	return &Callable{File: file, Name: path.Base(string(file.URI))}
+}
+
+// Range returns the range of the whole function, or of the whole file for the main chunk.
+func (c *Callable) Range() token.Range {
+	if c.Node == nil {
+		return token.Range{Start: 0, End: len(c.File.Source)}
+	}
+	return ast.Range(c.Node)
+}
+
+// NameRange returns the range of the name of a function statement, or of the `function` keyword of a function
+// expression. For the main chunk, it is empty at the start of the file.
+func (c *Callable) NameRange() token.Range {
+	switch node := c.Node.(type) {
+	case *ast.FunctionStatement:
+		return ast.Range(node.Name)
+	case *ast.FunctionExpression:
+		return node.FuncTok.Range()
+	}
+	return token.Range{}
+}
+
+// IsMethod returns whether the function is declared with `:`.
+func (c *Callable) IsMethod() bool {
+	if fs, ok := c.Node.(*ast.FunctionStatement); ok {
+		ie, ok := fs.Name.(*ast.IndexExpression)
+		return ok && ie.LeftIndexer.Type() == token.COLON
+	}
+	return false
+}
+
+// Call is a call whose callee could be resolved to a function.
+type Call struct {
+	Caller *Callable
+	Callee *Callable
+	Range  token.Range // The range of the name of the callee in the file of the caller
+}
+
+// FunctionAt returns the function whose name, or `function` keyword, is at the given position. A reference to a
+// function, such as the name of a call, also returns the function it refers to. It returns nil if there is no function
+// at the position or it cannot be resolved.
+func (e *Environment) FunctionAt(file *ast.File, pos token.Pos) *Callable {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if file.Block == nil {
+		return nil
+	}
+	a := e.analysis(file)
+	path := ast.GetSemanticNode(file.Block, pos)
+	for _, node := range append(path.Parents, path.Node) {
+		if fs, ok := node.(*ast.FunctionStatement); ok && pos < fs.LeftParen.Pos() {
+			return e.callable(file, fs)
+		}
+	}
+	switch node := path.Node.(type) {
+	case *ast.FunctionExpression:
+		if pos < node.LeftParen.Pos() {
+			return e.callable(file, node)
+		}
+	case *ast.Identifier:
+		var expr ast.Node = node
+		if len(path.Parents) > 0 {
+			if ie, ok := path.Parents[len(path.Parents)-1].(*ast.IndexExpression); ok && ie.Inner == ast.Expression(node) && ie.LeftIndexer.Type() != token.LBRACK {
+				expr = ie
+			}
+		}
+		if fn, ok := First(a.TypeOf(expr)).(*Function); ok {
+			if def := e.functionDefs()[fn]; def != nil {
+				return def.named()
+			}
+		}
+	}
+	return nil
+}
+
+// IncomingCalls returns every call in the workspace that resolves to the given function, in the order of their
+// files and positions. Every file is parsed and analyzed, so once ctx is cancelled the search stops and ctx.Err() is
+// returned.
+func (e *Environment) IncomingCalls(ctx context.Context, callee *Callable) ([]Call, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if callee.Type == nil {
+		return nil, nil
+	}
+	for uri, p := range e.pending {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !p.library {
+			e.loadPending(uri)
+		}
+	}
+	files := make([]*ast.File, 0, len(e.Files))
+	for uri, file := range e.Files {
+		if !e.isLibrary(uri) && file.Block != nil {
+			files = append(files, file)
+		}
+	}
+	slices.SortFunc(files, func(a, b *ast.File) int { return strings.Compare(string(a.URI), string(b.URI)) })
+	calls := []Call{}
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		e.calls(file, file.Block, MainChunk(file), func(call *ast.FunctionCall, fn *Function) *Callable {
+			if fn == callee.Type {
+				return callee
+			}
+			return nil
+		}, &calls)
+	}
+	return calls, nil
+}
+
+// OutgoingCalls returns every call in the body of the given function, or at the top level of the main chunk, that
+// resolves to a function. Calls in nested functions are made by those functions instead.
+func (e *Environment) OutgoingCalls(caller *Callable) []Call {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var body *ast.Block
+	switch node := caller.Node.(type) {
+	case *ast.FunctionStatement:
+		body = &node.Body
+	case *ast.FunctionExpression:
+		body = &node.Body
+	case nil:
+		body = caller.File.Block
+	}
+	if body == nil {
+		return nil
+	}
+	e.analysis(caller.File)
+	defs := e.functionDefs()
+	calls := []Call{}
+	e.calls(caller.File, body, caller, func(call *ast.FunctionCall, fn *Function) *Callable {
+		if def := defs[fn]; def != nil {
+			return def.named()
+		}
+		return nil
+	}, &calls)
+	return slices.DeleteFunc(calls, func(call Call) bool { return call.Caller != caller })
+}
+
+// calls adds the calls in the block that resolve to a function to the list. The callers of calls in nested
+// functions are those functions.
+func (e *Environment) calls(file *ast.File, block *ast.Block, caller *Callable, resolve func(call *ast.FunctionCall, fn *Function) *Callable, calls *[]Call) {
+	a := e.analysis(file)
+	var nested func(node ast.Node, body *ast.Block)
+	visit := func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionStatement:
+			nested(node, &node.Body)
+			return false
+		case *ast.FunctionExpression:
+			nested(node, &node.Body)
+			return false
+		case *ast.FunctionCall:
+			fn, ok := First(a.TypeOf(node.Name)).(*Function)
+			if !ok {
+				break
+			}
+			if callee := resolve(node, fn); callee != nil {
+				*calls = append(*calls, Call{Caller: caller, Callee: callee, Range: calleeRange(node)})
+			}
+		}
+		return true
+	}
+	nested = func(node ast.Node, body *ast.Block) {
+		// The caller is only created once it makes a call, since naming a function expression takes a search
+		before := len(*calls)
+		e.calls(file, body, nil, resolve, calls)
+		if len(*calls) > before {
+			callable := e.callable(file, node)
+			for i := before; i < len(*calls); i++ {
+				if (*calls)[i].Caller == nil {
+					(*calls)[i].Caller = callable
+				}
+			}
+		}
+	}
+	ast.WalkSemantic(block, visit)
+}
+
+// calleeRange returns the range of the name that a call is made with, such as `m` in `obj:m()`.
+func calleeRange(call *ast.FunctionCall) token.Range {
+	if ie, ok := call.Name.(*ast.IndexExpression); ok && ie.LeftIndexer.Type() != token.LBRACK {
+		return ast.Range(ie.Inner)
+	}
+	return ast.Range(call.Name)
+}
+
+// functionDefs returns the unnamed function that every function type was created for, in the workspace files that
+// have been analyzed. Functions in libraries are left out, since they only declare what is defined elsewhere.
+func (e *Environment) functionDefs() map[*Function]*Callable {
+	defs := map[*Function]*Callable{}
+	for uri, a := range e.analyses {
+		if a.File != e.Files[uri] || e.isLibrary(uri) {
+			continue
+		}
+		for node, typ := range a.Types {
+			fn, ok := typ.(*Function)
+			if !ok {
+				continue
+			}
+			switch node.(type) {
+			case *ast.FunctionStatement, *ast.FunctionExpression:
+				defs[fn] = &Callable{File: a.File, Node: node, Type: fn}
+			}
+		}
+	}
+	return defs
+}
+
+// named returns the function after giving it its name, which is left out of the functions of functionDefs until
+// they are needed.
+func (c *Callable) named() *Callable {
+	if c.Name == "" {
+		c.Name = functionName(c.File, c.Node)
+	}
+	return c
+}
+
+// callable returns the function of the given function statement or expression.
+func (e *Environment) callable(file *ast.File, node ast.Node) *Callable {
+	fn, _ := e.analysis(file).Types[node].(*Function)
+	return &Callable{File: file, Node: node, Name: functionName(file, node), Type: fn}
+}
+
+// functionName returns the name of a function statement, or the name of the variable or field that a function
+// expression is assigned to. Functions that are passed to a call are named after the callee.
+func functionName(file *ast.File, node ast.Node) string {
+	if fs, ok := node.(*ast.FunctionStatement); ok {
+		return file.Text(ast.Range(fs.Name))
+	}
+	fe := node.(*ast.FunctionExpression)
+	path := ast.GetSemanticNode(file.Block, fe.Pos())
+	for i := len(path.Parents) - 1; i >= 0; i-- {
+		switch parent := path.Parents[i].(type) {
+		case *ast.Pair[ast.Expression], *ast.Punctuated[ast.Expression], *ast.Pair[ast.TableField], *ast.Punctuated[ast.TableField]:
+			continue
+		case *ast.LocalStatement:
+			if j := valueIndex(parent.Exps, fe); j >= 0 && j < len(parent.Names.Pairs) {
+				return parent.Names.Pairs[j].Node.Token.Literal
+			}
+		case *ast.AssignmentStatement:
+			if j := valueIndex(&parent.Exps, fe); j >= 0 && j < len(parent.Vars.Pairs) {
+				return file.Text(ast.Range(parent.Vars.Pairs[j].Node))
+			}
+		case *ast.TableSimpleKeyField:
+			return parent.Name.Token.Literal
+		case *ast.TableExpressionKeyField:
+			if key, ok := ConstantKey(parent.Name); ok && parent.Expr == ast.Expression(fe) {
+				return key
+			}
+		case *ast.FunctionCall:
+			if valueIndex(&parent.Args, fe) >= 0 {
+				return "function passed to " + file.Text(ast.Range(parent.Name))
+			}
+		}
+		return "function"
+	}
+	return "function"
+}
+
+// valueIndex returns the index of the expression in the list, or -1 if it is not in it.
+func valueIndex(exps *ast.Punctuated[ast.Expression], expr ast.Expression) int {
+	if exps == nil {
+		return -1
+	}
+	for i, pair := range exps.Pairs {
+		if pair.Node == expr {
+			return i
+		}
+	}
+	return -1
+}
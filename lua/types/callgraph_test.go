@@ -0,0 +1,91 @@
+package types
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionAt(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string // The cursor is at the `|`
+		expected string // The name of the function, or empty if there is none
+	}{
+		{"local function", "local function |f() end", "f"},
+		{"call", "local function f() end\n|f()", "f"},
+		{"reference", "local function f() end\nlocal g = |f", "f"},
+		{"field", "local M = {}\nfunction M.|f() end", "M.f"},
+		{"field prefix", "local M = {}\nfunction |M.f() end", "M.f"},
+		{"function keyword of statement", "|function f() end", "f"},
+		{"method call", "local T = {}\nfunction T:m() end\nT:|m()", "T:m"},
+		{"class method", "---@class Player\nlocal Player = {}\nfunction Player:greet() end\n---@type Player\nlocal p\np:|greet()", "Player:greet"},
+		{"function keyword", "local f = |function() end", "f"},
+		{"assigned", "local M = {}\nM.on_tick = |function() end", "M.on_tick"},
+		{"table field", "local t = {init = |function() end}", "init"},
+		{"argument", "on_event(1, |function() end)", "function passed to on_event"},
+		{"anonymous", "local t = {|function() end}", "function"},
+		{"dynamic call", "local t = {}\nt[k]()\n|t.x()", ""},
+		{"ambiguous", "local f = function() end\nif c then f = function() end end\n|f()", ""},
+		{"library function", "|print(1)", ""},
+		{"parameter list", "local function f|() end", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			pos := strings.Index(test.input, "|")
+			require.GreaterOrEqual(t, pos, 0)
+			env, file := analyze(t, test.input[:pos]+test.input[pos+1:])
+			fn := env.FunctionAt(file, pos)
+			if test.expected == "" {
+				assert.Nil(t, fn)
+				return
+			}
+			require.NotNil(t, fn)
+			assert.Equal(t, test.expected, fn.Name)
+		})
+	}
+}
+
+func TestIncomingCalls(t *testing.T) {
+	env := workspace(t, map[string]string{
+		"util.lua": "local M = {}\nfunction M.log(s) print(s) end\nfunction M.warn(s) M.log(s) end\nreturn M",
+		"main.lua": "local util = require('util')\nutil.log('a')\nlocal function on_tick() util.log('b') util.warn('c') end\nscript.on_event(1, function() util.log('d') end)",
+	})
+	util := workspaceFile(t, env, "util.lua")
+	log := env.FunctionAt(util, strings.Index(util.Source, "log"))
+	require.NotNil(t, log)
+	calls, err := env.IncomingCalls(context.Background(), log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"main.lua -> M.log at log",
+		"on_tick -> M.log at log",
+		"function passed to script.on_event -> M.log at log",
+		"M.warn -> M.log at log",
+	}, formatCalls(calls))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = env.IncomingCalls(ctx, log)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestOutgoingCalls(t *testing.T) {
+	input := "local function a() end\nlocal T = {}\nfunction T:b() end\nlocal function f()\n\ta()\n\tT:b()\n\tg()\n\tlocal h = function() a() end\nend\na()"
+	env, file := analyze(t, input)
+	f := env.FunctionAt(file, strings.Index(input, "f()"))
+	require.NotNil(t, f)
+	assert.Equal(t, []string{"f -> a at a", "f -> T:b at b"}, formatCalls(env.OutgoingCalls(f)))
+	assert.Equal(t, []string{"test.lua -> a at a"}, formatCalls(env.OutgoingCalls(MainChunk(file))))
+}
+
+// formatCalls describes every call as its caller, its callee and the name it is called with.
+func formatCalls(calls []Call) []string {
+	result := []string{}
+	for _, call := range calls {
+		result = append(result, call.Caller.Name+" -> "+call.Callee.Name+" at "+call.Caller.File.Text(call.Range))
+	}
+	return result
+}
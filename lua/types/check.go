@@ -0,0 +1,356 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/raiguard/luapls/lua/annotation"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Diagnostic codes reported by type checking.
+const (
+	CodeArgumentCount  = "argument-count"
+	CodeArgumentType   = "argument-type-mismatch"
+	CodeAssignmentType = "assignment-type-mismatch"
+	CodeReturnCount    = "return-count"
+	CodeReturnType     = "return-type-mismatch"
+	CodeUnknownField   = "unknown-field"
+)
+
+// checker compares the inferred types of a file against the types declared by its annotations.
+type checker struct {
+	env      *Environment
+	a        *Analysis
+	declared map[*Variable]Type // Local variable -> the type declared by its `@type` annotation
+	targets  map[ast.Node]bool  // Expressions that are assigned to rather than read
+	returns  *declaredReturns   // The return types declared by the enclosing function, if any
+	diags    diagnosticList
+}
+
+// declaredReturns are the types declared by the `@return` annotations of a function.
+type declaredReturns struct {
+	types  []Type
+	vararg bool // The last type is repeated for any number of values
+}
+
+// checkTypes reports calls, assignments, returns and field accesses that do not match their declared types.
+func (e *Environment) checkTypes(a *Analysis) []ast.Diagnostic {
+	if e.isLibrary(a.File.URI) || a.File.Block == nil {
+		return nil
+	}
+	c := checker{env: e, a: a, declared: map[*Variable]Type{}, targets: map[ast.Node]bool{}}
+	c.walk(a.File.Block)
+	return c.diags
+}
+
+func (c *checker) walk(node ast.Node) {
+	ast.WalkSemantic(node, func(node ast.Node) bool {
+		if stmt, ok := node.(ast.Statement); ok && c.env.isExcluded(c.a.File, stmt) {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.AssignmentStatement:
+			for _, pair := range node.Vars.Pairs {
+				c.targets[pair.Node] = true
+			}
+			c.assignment(node)
+		case *ast.FunctionCall:
+			c.call(node)
+		case *ast.FunctionExpression:
+			c.function(node, &node.Body)
+			return false
+		case *ast.FunctionStatement:
+			c.function(node, &node.Body)
+			return false
+		case *ast.IndexExpression:
+			if !c.targets[node] {
+				c.field(node)
+			}
+		case *ast.LocalStatement:
+			c.local(node)
+		case *ast.ReturnStatement:
+			c.returnStatement(node)
+		}
+		return true
+	})
+}
+
+// function checks the body of a function against its `@return` annotations.
+func (c *checker) function(node ast.Node, body *ast.Block) {
+	outer := c.returns
+	c.returns = nil
+	for _, a := range c.a.annotations[node] {
+		if ret, ok := a.(*annotation.Return); ok {
+			if c.returns == nil {
+				c.returns = &declaredReturns{}
+			}
+			for _, expr := range ret.Types {
+				c.returns.types = append(c.returns.types, c.env.resolveType(expr))
+			}
+			c.returns.vararg = ret.Vararg
+		}
+	}
+	c.walk(body)
+	c.returns = outer
+}
+
+func (c *checker) call(call *ast.FunctionCall) {
+	fn, ok := First(c.a.TypeOf(call.Name)).(*Function)
+	if !ok {
+		return
+	}
+	args, exact := c.values(&call.Args)
+	nodes := make([]ast.Node, len(call.Args.Pairs))
+	for i, pair := range call.Args.Pairs {
+		nodes[i] = pair.Node
+	}
+	// Method calls pass the object as the implicit first argument
+	if ie, ok := call.Name.(*ast.IndexExpression); ok && ie.LeftIndexer.Type() == token.COLON {
+		args = append([]Type{First(c.a.TypeOf(ie.Prefix))}, args...)
+		nodes = append([]ast.Node{ie.Prefix}, nodes...)
+	}
+	for i, param := range fn.Params {
+		if i >= len(args) {
+			if exact && !Assignable(&Nil{}, param.Type) {
+				c.addDiagnostic(call, CodeArgumentCount, fmt.Sprintf("Missing argument '%s' of type '%s'", param.Name, param.Type))
+			}
+			continue
+		}
+		if !Assignable(args[i], param.Type) {
+			c.addDiagnostic(valueNode(nodes, i), CodeArgumentType, fmt.Sprintf("Cannot pass '%s' to parameter '%s' of type '%s'", args[i], param.Name, param.Type))
+		}
+	}
+	if !fn.Vararg && len(args) > len(fn.Params) {
+		c.addDiagnostic(valueNode(nodes, len(fn.Params)), CodeArgumentCount, fmt.Sprintf("Expected %s but got %d", plural(len(fn.Params), "argument"), len(args)))
+	}
+}
+
+func (c *checker) local(stmt *ast.LocalStatement) {
+	types := c.declaredTypes(stmt)
+	if len(types) == 0 {
+		return
+	}
+	for i, pair := range stmt.Names.Pairs {
+		if v := c.a.Bindings[pair.Node]; v != nil && i < len(types) {
+			c.declared[v] = types[i]
+		}
+	}
+	if stmt.Exps != nil {
+		c.assignValues(stmt.Exps, types, func(i int) string { return stmt.Names.Pairs[i].Node.Token.Literal })
+	}
+}
+
+func (c *checker) assignment(stmt *ast.AssignmentStatement) {
+	types := c.declaredTypes(stmt)
+	for i, pair := range stmt.Vars.Pairs {
+		if i < len(types) {
+			continue
+		}
+		ident, ok := pair.Node.(*ast.Identifier)
+		if !ok {
+			types = append(types, nil)
+			continue
+		}
+		types = append(types, c.declared[c.a.Bindings[ident]])
+	}
+	c.assignValues(&stmt.Exps, types, func(i int) string {
+		if ident, ok := stmt.Vars.Pairs[i].Node.(*ast.Identifier); ok {
+			return ident.Token.Literal
+		}
+		return "field"
+	})
+}
+
+// assignValues checks the values of an expression list against the declared types of the variables they are
+// assigned to. Variables without a declared type have a nil type.
+func (c *checker) assignValues(exps *ast.Punctuated[ast.Expression], types []Type, name func(int) string) {
+	values, _ := c.values(exps)
+	nodes := make([]ast.Node, len(exps.Pairs))
+	for i, pair := range exps.Pairs {
+		nodes[i] = pair.Node
+	}
+	for i, typ := range types {
+		if typ == nil || i >= len(values) {
+			continue
+		}
+		if !Assignable(values[i], typ) {
+			c.addDiagnostic(valueNode(nodes, i), CodeAssignmentType, fmt.Sprintf("Cannot assign '%s' to '%s' of type '%s'", values[i], name(i), typ))
+		}
+	}
+}
+
+// declaredTypes returns the types declared by the `@type` annotations of a statement.
+func (c *checker) declaredTypes(stmt ast.Statement) []Type {
+	types := []Type{}
+	for _, a := range c.a.annotations[stmt] {
+		if a, ok := a.(*annotation.Type); ok {
+			for _, expr := range a.Types {
+				types = append(types, c.env.resolveType(expr))
+			}
+		}
+	}
+	return types
+}
+
+func (c *checker) returnStatement(stmt *ast.ReturnStatement) {
+	if c.returns == nil {
+		return
+	}
+	exps := stmt.Exps
+	if exps == nil {
+		exps = &ast.Punctuated[ast.Expression]{}
+	}
+	values, exact := c.values(exps)
+	nodes := make([]ast.Node, len(exps.Pairs))
+	for i, pair := range exps.Pairs {
+		nodes[i] = pair.Node
+	}
+	declared := c.returns.types
+	for i, value := range values {
+		if i >= len(declared) {
+			if !c.returns.vararg {
+				c.addDiagnostic(valueNode(nodes, i), CodeReturnCount, fmt.Sprintf("Expected %s but got %d", plural(len(declared), "return value"), len(values)))
+				return
+			}
+			if len(declared) == 0 {
+				return
+			}
+			if !Assignable(value, declared[len(declared)-1]) {
+				c.addDiagnostic(valueNode(nodes, i), CodeReturnType, fmt.Sprintf("Cannot return '%s' as '%s'", value, declared[len(declared)-1]))
+			}
+			continue
+		}
+		if !Assignable(value, declared[i]) {
+			c.addDiagnostic(valueNode(nodes, i), CodeReturnType, fmt.Sprintf("Cannot return '%s' as '%s'", value, declared[i]))
+		}
+	}
+	if !exact {
+		return
+	}
+	for i := len(values); i < len(declared); i++ {
+		if !Assignable(&Nil{}, declared[i]) {
+			c.addDiagnostic(stmt, CodeReturnCount, fmt.Sprintf("Missing return value of type '%s'", declared[i]))
+			return
+		}
+	}
+}
+
+// field reports reads of fields that do not exist on a class.
+func (c *checker) field(ie *ast.IndexExpression) {
+	class, ok := First(c.a.TypeOf(ie.Prefix)).(*Named)
+	if !ok || !class.IsClass() {
+		return
+	}
+	name, ok := fieldName(ie)
+	if !ok {
+		return
+	}
+	c.env.resolveNamed(class)
+	if _, ok := LookupField(class, name); !ok {
+		c.addDiagnostic(ie.Inner, CodeUnknownField, fmt.Sprintf("Unknown field '%s' on class '%s'", name, class.Name))
+	}
+}
+
+// values returns the inferred types of every value produced by the expression list. The second return value is
+// false if the number of values is not known, such as when the list ends with a call to an unknown function.
+func (c *checker) values(exps *ast.Punctuated[ast.Expression]) ([]Type, bool) {
+	types := []Type{}
+	for i, pair := range exps.Pairs {
+		typ := c.a.TypeOf(pair.Node)
+		if i < len(exps.Pairs)-1 {
+			types = append(types, First(typ))
+			continue
+		}
+		if tuple, ok := typ.(*Tuple); ok {
+			return append(types, tuple.Types...), true
+		}
+		switch pair.Node.(type) {
+		case *ast.FunctionCall, *ast.Vararg:
+			if _, ok := typ.(*Unknown); ok {
+				return types, false
+			}
+		}
+		types = append(types, typ)
+	}
+	return types, true
+}
+
+// valueNode returns the node that produced the i-th value of an expression list. Values past the end of the list
+// are produced by its last expression.
+func valueNode(nodes []ast.Node, i int) ast.Node {
+	return nodes[min(i, len(nodes)-1)]
+}
+
+func (c *checker) addDiagnostic(node ast.Node, code string, message string) {
+	c.diags.add(ast.Range(node), protocol.DiagnosticSeverityWarning, code, message)
+}
+
+// Assignable returns whether a value of the given type may be used where the target type is expected. Unknown and
+// any are compatible with everything, and the contents of tables and functions are not compared.
+func Assignable(value, target Type) bool {
+	if value == nil || target == nil {
+		return true
+	}
+	switch target.(type) {
+	case *Any, *Unknown:
+		return true
+	}
+	switch value := value.(type) {
+	case *Any, *Unknown:
+		return true
+	case *Tuple:
+		return Assignable(First(value), target)
+	case *Union:
+		for _, member := range value.Types {
+			if !Assignable(member, target) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, member := range members(target) {
+		if assignableMember(value, member) {
+			return true
+		}
+	}
+	return false
+}
+
+// assignableMember returns whether a value that is not a union may be used where the target type is expected.
+func assignableMember(value, target Type) bool {
+	switch target := target.(type) {
+	case *Any, *Unknown:
+		return true
+	case *Named:
+		switch value := value.(type) {
+		case *Named:
+			return value.inherits(target.Name)
+		case *Table:
+			// Tables are instantiated as classes by assigning them
+			return !target.inherits("userdata") && !target.inherits("thread")
+		}
+		return false
+	case *Table:
+		switch value := value.(type) {
+		case *Named:
+			return !value.inherits("userdata") && !value.inherits("thread")
+		case *Table:
+			return true
+		}
+		return false
+	case *Function:
+		_, ok := value.(*Function)
+		return ok
+	}
+	return sameKind(value, target)
+}
+
+// plural returns the count followed by the noun, which is made plural unless the count is one.
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -0,0 +1,51 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeChecks(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected []string
+	}{
+		{"matching call", "---@param s string\n---@param n? integer\nlocal function f(s, n) end f('a') f('a', 1)", nil},
+		{"argument type", "---@param s string\nlocal function f(s) end f(1)", []string{"Cannot pass 'number' to parameter 's' of type 'string'"}},
+		{"missing argument", "---@param s string\nlocal function f(s) end f()", []string{"Missing argument 's' of type 'string'"}},
+		{"too many arguments", "local function f(a) end f(1, 2)", []string{"Expected 1 argument but got 2"}},
+		{"vararg", "local function f(a, ...) end f(1, 2, 3)", nil},
+		{"unknown count", "local function f(a) end f(g())", nil},
+		{"method self", "local t = {} function t:f(a) end t:f(1) t.f(t, 1) t:f(1, 2)", []string{"Expected 2 arguments but got 3"}},
+		{"union argument", "---@param s string\nlocal function f(s) end\n---@type string|nil\nlocal x\nf(x)", []string{"Cannot pass 'string|nil' to parameter 's' of type 'string'"}},
+		{"stdlib", "local s = string.format('%d', 1):upper() table.insert({}, s) print(tostring(1), ('x'):rep(2))", nil},
+		{"typed local", "---@type string\nlocal s = 1", []string{"Cannot assign 'number' to 's' of type 'string'"}},
+		{"typed local reassigned", "---@type string\nlocal s = 'a'\ns = 'b'\ns = false", []string{"Cannot assign 'boolean' to 's' of type 'string'"}},
+		{"untyped local", "local s = 'a' s = 1", nil},
+		{"return type", "---@return string\nlocal function f() return 1 end", []string{"Cannot return 'number' as 'string'"}},
+		{"return count", "---@return string, integer\nlocal function f() if x then return 'a' end return 'a', 1, 2 end", []string{"Missing return value of type 'number'", "Expected 2 return values but got 3"}},
+		{"single return value", "---@return string\nlocal function f() return 'a', 1 end", []string{"Expected 1 return value but got 2"}},
+		{"optional return", "---@return string?\nlocal function f() return end", nil},
+		{"nested function return", "---@return string\nlocal function f() local g = function() return 1 end return 'a' end", nil},
+		{"unknown field", "---@class Point\n---@field x number\nlocal p = {}\nfunction p:len() end\nlocal a = p.x + p.y\np:len() p:scale()", []string{"Unknown field 'y' on class 'Point'", "Unknown field 'scale' on class 'Point'"}},
+		{"inherited field", "---@class A\n---@field a number\n---@class B : A\n---@type B\nlocal b\nlocal x = b.a", nil},
+		{"class argument", "---@class A\n---@class B : A\n---@param a A\nlocal function f(a) end\n---@type B\nlocal b\nf(b) f({}) f('a')", []string{"Cannot pass 'string' to parameter 'a' of type 'A'"}},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			messages := []string{}
+			for _, diag := range env.Diagnostics(file) {
+				if diag.Code != CodeUndefinedGlobal && !isUnnecessary(diag) {
+					messages = append(messages, diag.Message)
+				}
+			}
+			if test.expected == nil {
+				test.expected = []string{}
+			}
+			assert.Equal(t, test.expected, messages)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/util"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// discovery searches directories for Lua files. Symbolic links are followed, but every directory and file is only
+// visited once, even if it can be reached through several paths. Links are followed after everything else has been
+// visited, so that files are found through their real paths where possible.
+type discovery struct {
+	e       *Environment
+	visited map[string]bool // Real paths of the directories and files that were visited
+	links   []link
+}
+
+// link is a symbolic link that was found during the search, to be followed later.
+type link struct {
+	path  string
+	rules []ignoreRules
+}
+
+func (e *Environment) newDiscovery() *discovery {
+	return &discovery{e: e, visited: map[string]bool{}}
+}
+
+// AddDirectory parses every Lua file in the directory and its subdirectories that is not ignored, and returns the
+// files that were added.
+func (e *Environment) AddDirectory(dir string) []*ast.File {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.newDiscovery().addDirectory(dir)
+}
+
+// AddLibraries parses every Lua file in the library directories as a library file.
+func (e *Environment) AddLibraries() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.newDiscovery().addLibraries()
+}
+
+// workspaceRoots returns the directories that make up the workspace: the root path, the other workspace folders, and
+// the roots that modules are resolved from that are outside of them.
+func (e *Environment) workspaceRoots() []string {
+	dirs := []string{}
+	for _, dir := range append(append([]string{e.RootPath}, e.Folders...), e.roots()...) {
+		if dir == "" {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		contained := false
+		for _, other := range dirs {
+			if rel, err := filepath.Rel(other, dir); err == nil && !strings.HasPrefix(rel, "..") {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func (d *discovery) addDirectory(dir string) []*ast.File {
+	return d.add(d.directorySources(dir))
+}
+
+func (d *discovery) addLibraries() {
+	d.add(d.librarySources())
+}
+
+// directorySources returns the Lua files in the directory that are not ignored.
+func (d *discovery) directorySources(dir string) []source {
+	sources := []source{}
+	d.walk(dir, func(path string, uri protocol.URI) {
+		sources = append(sources, source{uri: uri, path: path})
+	})
+	return sources
+}
+
+// librarySources returns the Lua files in the library directories.
+func (d *discovery) librarySources() []source {
+	sources := []source{}
+	for _, dir := range d.e.Library {
+		d.walk(d.e.resolvePath(dir), func(path string, uri protocol.URI) {
+			sources = append(sources, source{uri: uri, path: path, library: true})
+		})
+	}
+	return sources
+}
+
+// add parses the sources that have not been added yet in parallel, and adds them to the environment. It returns the
+// files of every source, including those that had already been added.
+func (d *discovery) add(sources []source) []*ast.File {
+	files := []*ast.File{}
+	sources = slices.DeleteFunc(sources, func(src source) bool {
+		if existing := d.e.Files[src.uri]; existing != nil {
+			files = append(files, existing)
+			return true
+		}
+		return false
+	})
+	results, errs := parseSources(context.Background(), sources, nil, nil)
+	for _, err := range errs {
+		d.report(err)
+	}
+	for i, result := range results {
+		if result.file != nil {
+			files = append(files, d.e.addParsedFile(result.file, result.hash, sources[i].library))
+		}
+	}
+	return files
+}
+
+// walk calls fn for every Lua file in the directory and its subdirectories that is not ignored by the default
+// patterns, the configured patterns, or a `.gitignore` file.
+func (d *discovery) walk(root string, fn func(path string, uri protocol.URI)) {
+	patterns := parseIgnorePatterns(append(append([]string{}, defaultIgnores...), d.e.Ignore...))
+	d.walkDir(root, []ignoreRules{{base: root, patterns: patterns}}, fn)
+	for len(d.links) > 0 {
+		l := d.links[0]
+		d.links = d.links[1:]
+		info, err := os.Stat(l.path)
+		if err != nil {
+			d.report(fmt.Errorf("Broken symbolic link %s: %w", l.path, err))
+			continue
+		}
+		d.walkEntry(l.path, info.IsDir(), l.rules, fn)
+	}
+}
+
+func (d *discovery) walkDir(dir string, rules []ignoreRules, fn func(path string, uri protocol.URI)) {
+	if !d.visit(dir) {
+		return
+	}
+	gitignore, err := readGitignore(dir)
+	if err != nil {
+		d.report(err)
+	}
+	if len(gitignore) > 0 {
+		rules = append(rules[:len(rules):len(rules)], ignoreRules{base: dir, patterns: gitignore})
+	}
+	// ReadDir returns the entries that it could read along with the error
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		d.report(err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.Type()&fs.ModeSymlink != 0 {
+			d.links = append(d.links, link{path, rules})
+			continue
+		}
+		d.walkEntry(path, entry.IsDir(), rules, fn)
+	}
+}
+
+// walkEntry visits a single file or directory that was found in a directory.
+func (d *discovery) walkEntry(path string, isDir bool, rules []ignoreRules, fn func(path string, uri protocol.URI)) {
+	if isIgnored(rules, path, isDir) {
+		return
+	}
+	if isDir {
+		d.walkDir(path, rules, fn)
+		return
+	}
+	if !strings.HasSuffix(path, ".lua") || !d.visit(path) {
+		return
+	}
+	uri, err := util.PathToURI(path)
+	if err != nil {
+		d.report(err)
+		return
+	}
+	fn(path, uri)
+}
+
+// visit marks the real path of the directory or file as visited, and returns false if it was already visited.
+func (d *discovery) visit(path string) bool {
+	real, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		real, err = filepath.Abs(real)
+	}
+	if err != nil {
+		d.report(err)
+		return false
+	}
+	if d.visited[real] {
+		return false
+	}
+	d.visited[real] = true
+	return true
+}
+
+func (d *discovery) report(err error) {
+	d.e.log.Warningf("%s", err)
+	d.e.DiscoveryErrors = append(d.e.DiscoveryErrors, err)
+}
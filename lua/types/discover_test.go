@@ -0,0 +1,80 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/raiguard/luapls/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// discoveredFiles returns the paths of the workspace files in the environment, relative to the root path.
+func discoveredFiles(t *testing.T, env *Environment) []string {
+	paths := []string{}
+	for uri := range env.Files {
+		if env.IsLibrary(uri) {
+			continue
+		}
+		path, err := util.URIToPath(uri)
+		require.NoError(t, err)
+		rel, err := filepath.Rel(env.RootPath, path)
+		require.NoError(t, err)
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func TestDiscovery(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.lua":                  "",
+		"src/lib.lua":               "",
+		"src/generated/out.lua":     "",
+		"src/generated/keep.lua":    "",
+		"src/.gitignore":            "generated/*\n!generated/keep.lua\n",
+		".gitignore":                "# build output\n/build\n",
+		"build/bundle.lua":          "",
+		".git/hooks/hook.lua":       "",
+		"node_modules/pkg/init.lua": "",
+		"extra/root.lua":            "",
+	})
+	require.NoError(t, os.Symlink(filepath.Join(dir, "src"), filepath.Join(dir, "link")))
+	require.NoError(t, os.Symlink(dir, filepath.Join(dir, "src", "loop")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing.lua"), filepath.Join(dir, "broken.lua")))
+
+	env := NewEnvironment()
+	env.RootPath = dir
+	env.Init()
+
+	assert.Equal(t, []string{"extra/root.lua", "main.lua", "src/generated/keep.lua", "src/lib.lua"}, discoveredFiles(t, env))
+	require.Len(t, env.DiscoveryErrors, 1)
+	assert.Contains(t, env.DiscoveryErrors[0].Error(), "broken.lua")
+}
+
+func TestDiscoveryRoots(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"project/main.lua":   "local m = require('shared')",
+		"shared/shared.lua":  "return {}",
+		"folder/other.lua":   "",
+		"folder/ignored.lua": "",
+	})
+	env := NewEnvironment()
+	env.RootPath = filepath.Join(dir, "project")
+	env.Roots = []string{".", "../shared"}
+	env.Folders = []string{filepath.Join(dir, "folder")}
+	env.Ignore = []string{"ignored.lua"}
+	env.Init()
+
+	assert.Equal(t, []string{"../folder/other.lua", "../shared/shared.lua", "main.lua"}, discoveredFiles(t, env))
+	assert.Empty(t, env.DiscoveryErrors)
+}
+
+func TestDiscoveryMissingRoot(t *testing.T) {
+	env := NewEnvironment()
+	env.RootPath = filepath.Join(t.TempDir(), "missing")
+	env.Init()
+	assert.Len(t, env.DiscoveryErrors, 1)
+}
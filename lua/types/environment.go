@@ -1,139 +1,576 @@
 package types
 
 import (
-	"io/fs"
-	"os"
-	"path/filepath"
-	"strings"
+	"context"
+	"slices"
+	"sync"
 	"time"
 
-	"github.com/raiguard/luapls/lua/annotation"
 	"github.com/raiguard/luapls/lua/ast"
-	"github.com/raiguard/luapls/lua/parser"
-	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/lua/stdlib"
 	"github.com/raiguard/luapls/util"
 	"github.com/tliron/commonlog"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
+// StdlibURI is the prefix of the URIs of the standard library definition files.
+const StdlibURI = "luapls:///stdlib/"
+
+// Linter checks a file once its diagnostics are requested, using the checks of this package and any others. It is
+// called while the environment is locked, so it must only access the environment through the given view.
+type Linter interface {
+	Lint(v *View, a *Analysis) []ast.Diagnostic
+}
+
+// Environment holds every file of a workspace along with the results of analyzing them.
+//
+// The methods of an environment are safe to call from multiple goroutines. Files are never modified once they have
+// been added: a file whose contents change is replaced by a new file, so that files obtained earlier can still be
+// read safely. The exported maps must only be accessed directly while no other goroutine is using the environment.
 type Environment struct {
 	Files    map[protocol.URI]*ast.File
 	RootPath string
 
-	Types map[string]Type
+	Roots       []string // Directories that modules are resolved relative to, defaulting to RootPath
+	PackagePath []string // Templates for resolving modules, in the format of `package.path`
+	LuaVersion  string   // One of stdlib.Versions, defaulting to DefaultLuaVersion
+	Library     []string // Directories of definition files that are available to every file
+	Ignore      []string // Patterns of files and directories that are not parsed, in the format of `.gitignore`
+	Folders     []string // Workspace directories other than RootPath, in multi-root workspaces
+
+	DiscoveryErrors []error // Errors that occurred while searching directories for Lua files
+
+	AllowedGlobals []string // Globals that are defined outside of the workspace, such as by a host program
+	StrictGlobals  bool     // Report assignments to globals that are not allowed or defined by a library
+
+	Severities map[string]protocol.DiagnosticSeverity // Diagnostic code -> overridden severity, or SeverityOff
+	Linter     Linter                                 // The checks to run on every file, or nil to only report syntax errors
+
+	Cache Cache // Summaries of files from earlier runs, used by Index to avoid parsing files that have not changed
+
+	Types   map[string]Type
+	Globals map[string]*Global
+
+	analyses    map[protocol.URI]*Analysis
+	annotations map[protocol.URI]*fileAnnotations
+	libraries   map[protocol.URI]bool
+	replaced    map[protocol.URI]*Analysis // The analyses of files before they were last replaced by UpdateFile
+	hashes      map[protocol.URI]string    // The hashes of the contents of every file, including pending files
+	fingerprint string                     // The fingerprint of the workspace, or empty if it has changed since
 
+	// Files whose summaries were found in the cache are pending until they are needed. The names of the globals and
+	// types that they define are indexed so that they can be parsed once these are looked up.
+	pending        map[protocol.URI]*pendingFile
+	pendingGlobals map[string][]protocol.URI
+	pendingTypes   map[string][]protocol.URI
+
+	// mu guards every other field. Exported methods lock it and call unexported methods, which assume that it is held.
+	mu  sync.Mutex
 	log commonlog.Logger
 }
 
 func NewEnvironment() *Environment {
-	return &Environment{
-		Files: map[protocol.URI]*ast.File{},
-		Types: map[string]Type{},
-		log:   commonlog.GetLogger("luapls.environment"),
+	e := &Environment{
+		Files:       map[protocol.URI]*ast.File{},
+		Types:       map[string]Type{},
+		Globals:     map[string]*Global{},
+		analyses:    map[protocol.URI]*Analysis{},
+		annotations: map[protocol.URI]*fileAnnotations{},
+		libraries:   map[protocol.URI]bool{},
+		replaced:    map[protocol.URI]*Analysis{},
+		hashes:      map[protocol.URI]string{},
+
+		pending:        map[protocol.URI]*pendingFile{},
+		pendingGlobals: map[string][]protocol.URI{},
+		pendingTypes:   map[string][]protocol.URI{},
+
+		log: commonlog.GetLogger("luapls.environment"),
+	}
+	sources := []source{}
+	for name, content := range stdlib.Files() {
+		content := content
+		sources = append(sources, source{uri: StdlibURI + name, content: &content, library: true})
+	}
+	results, _ := parseSources(context.Background(), sources, nil, nil)
+	for _, result := range results {
+		e.addParsedFile(result.file, result.hash, true)
 	}
+	return e
 }
 
-// Init parses all Lua files in the root directory and builds the type graph.
+// Init parses all Lua files in the library directories and the workspace, and builds the type graph. Files are read
+// and parsed in parallel.
 func (e *Environment) Init() {
+	e.Index(context.Background(), nil)
+}
+
+// Index is like Init, but the environment can be used by other goroutines while files are read and parsed, and
+// between the files whose types are inferred, which is most of the work. Files that are added in the meantime, such
+// as files that are opened in an editor, take precedence over the files on disk.
+//
+// If the environment has a cache, files that have not changed since they were cached are not parsed until they are
+// needed. Their diagnostics can be retrieved with CachedDiagnostics.
+//
+// If progress is not nil, it is called with the number of files that have been parsed and the total number of files
+// after each one. If ctx is cancelled, the files that have not been parsed yet are skipped, the files that have been
+// are still checked, and ctx.Err() is returned.
+func (e *Environment) Index(ctx context.Context, progress func(parsed, total int)) error {
 	before := time.Now()
-	filepath.WalkDir(e.RootPath, func(path string, info fs.DirEntry, err error) error {
-		if !info.IsDir() && strings.HasSuffix(path, ".lua") {
-			uri, err := util.PathToURI(path)
-			if err != nil {
-				return err
-			}
-			e.AddFile(uri)
+	e.mu.Lock()
+	e.DiscoveryErrors = nil
+	d := e.newDiscovery()
+	sources := d.librarySources()
+	for _, root := range e.workspaceRoots() {
+		sources = append(sources, d.directorySources(root)...)
+	}
+	sources = slices.DeleteFunc(sources, func(src source) bool { return e.Files[src.uri] != nil || e.pending[src.uri] != nil })
+	cache := e.Cache
+	e.mu.Unlock()
+
+	var report func(parsed int)
+	if progress != nil {
+		report = func(parsed int) { progress(parsed, len(sources)) }
+	}
+	results, errs := parseSources(ctx, sources, cache, report)
+
+	e.mu.Lock()
+	for _, err := range errs {
+		d.report(err)
+	}
+	cached := 0
+	for i, result := range results {
+		switch {
+		case result.summary != nil:
+			e.addPending(sources[i], result.hash, result.summary)
+			cached++
+		case result.file != nil:
+			e.addParsedFile(result.file, result.hash, sources[i].library)
 		}
-		return nil
-	})
-	e.CheckPhase1()
+	}
+	e.log.Debugf("Parsed %d files in %s, %d were cached", len(sources)-cached, time.Since(before).String(), cached)
+	e.checkPhase1()
+	files := e.bindAll()
+	e.mu.Unlock()
+
+	// Inference is most of the work of checking, so the environment is unlocked between files. Every file has been
+	// bound, so the files that other goroutines analyze in the meantime know every global.
+	for _, file := range files {
+		e.mu.Lock()
+		e.analysis(file)
+		e.mu.Unlock()
+	}
 	e.log.Debugf("Initialization took %s", time.Since(before).String())
 
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.log.Debug("TYPES:")
 	for name := range e.Types {
 		e.log.Debug(name)
 	}
+	return ctx.Err()
+}
+
+// File returns the file with the given URI, or nil if it has not been added. Pending files are parsed first.
+func (e *Environment) File(uri protocol.URI) *ast.File {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if file := e.Files[uri]; file != nil {
+		return file
+	}
+	return e.loadPending(uri)
 }
 
+// FileList returns every file in the environment that has been parsed, in no particular order.
+func (e *Environment) FileList() []*ast.File {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	files := make([]*ast.File, 0, len(e.Files))
+	for _, file := range e.Files {
+		files = append(files, file)
+	}
+	return files
+}
+
+// AddFile reads and parses the file at the given URI, unless it has already been added.
 func (e *Environment) AddFile(uri protocol.URI) *ast.File {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.addFile(uri)
+}
+
+func (e *Environment) addFile(uri protocol.URI) *ast.File {
 	if existing := e.Files[uri]; existing != nil {
 		return existing
 	}
+	if file := e.loadPending(uri); file != nil {
+		return file
+	}
 	path, err := util.URIToPath(uri)
 	if err != nil {
 		e.log.Errorf("%s", err)
 		return nil
 	}
-	src, err := os.ReadFile(path)
+	timer := time.Now()
+	result, err := parseSource(source{uri: uri, path: path}, nil)
 	if err != nil {
 		e.log.Errorf("Failed to parse file %s: %s", path, err)
 		return nil
 	}
-	timer := time.Now()
-	file := util.Ptr(parser.New(string(src)).ParseFile())
 	e.log.Debugf("Parsed file '%s' in %s", path, time.Since(timer).String())
-	file.URI = uri
-	e.Files[uri] = file
-
-	return file
+	return e.addParsedFile(result.file, result.hash, false)
 }
 
+// AddTransientFile adds a file with the given contents, such as a file that is open in an editor but not saved,
+// unless it has already been added.
 func (e *Environment) AddTransientFile(uri protocol.URI, content string) *ast.File {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	if existing := e.Files[uri]; existing != nil {
 		return existing
 	}
-	path, err := util.URIToPath(uri)
-	if err != nil {
-		e.log.Errorf("%s", err)
-		return nil
+	result, _ := parseSource(source{uri: uri, content: &content}, nil)
+	return e.addParsedFile(result.file, result.hash, false)
+}
+
+// AddLibraryFile adds a file whose definitions are available to every other file, but which never reports
+// diagnostics.
+func (e *Environment) AddLibraryFile(uri protocol.URI, content string) *ast.File {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if existing := e.Files[uri]; existing != nil {
+		return existing
 	}
-	timer := time.Now()
-	file := util.Ptr(parser.New(content).ParseFile())
-	e.log.Debugf("Parsed file '%s' in %s", path, time.Since(timer).String())
-	file.URI = uri
-	e.Files[uri] = file
+	result, _ := parseSource(source{uri: uri, content: &content}, nil)
+	return e.addParsedFile(result.file, result.hash, true)
+}
 
+// addParsedFile adds a file that has already been parsed, unless a file with the same URI was added first. It takes
+// precedence over a pending file with the same URI.
+func (e *Environment) addParsedFile(file *ast.File, hash string, library bool) *ast.File {
+	if existing := e.Files[file.URI]; existing != nil {
+		return existing
+	}
+	e.removePending(file.URI)
+	e.Files[file.URI] = file
+	e.setHash(file.URI, hash)
+	if library {
+		e.libraries[file.URI] = true
+	}
 	return file
 }
 
+// UpdateFile replaces the file at the given URI with a file with the given contents, and checks it again. It returns
+// every file whose analysis may have changed as a result, like CheckFile, or nil if the file has not been added.
+func (e *Environment) UpdateFile(uri protocol.URI, content string) []*ast.File {
+	// Parsing does not need the environment, so other goroutines can use it in the meantime
+	result, _ := parseSource(source{uri: uri, content: &content}, nil)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.Files[uri] == nil {
+		return nil
+	}
+	if a := e.analyses[uri]; a != nil {
+		e.replaced[uri] = a
+	}
+	e.Files[uri] = result.file
+	e.setHash(uri, result.hash)
+	return e.checkFile(result.file)
+}
+
+// IsLibrary returns whether the file at the given URI is a library file.
+func (e *Environment) IsLibrary(uri protocol.URI) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.isLibrary(uri)
+}
+
+func (e *Environment) isLibrary(uri protocol.URI) bool {
+	return e.libraries[uri]
+}
+
+// Configure calls fn while the environment is locked, so that its settings can be changed while other goroutines are
+// using it. fn must not call the methods of the environment.
+func (e *Environment) Configure(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fn()
+	e.fingerprint = ""
+}
+
+// SetLuaVersion changes the Lua version that definitions are selected for, and rebinds every file if it changed.
+func (e *Environment) SetLuaVersion(version string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if version == e.LuaVersion {
+		return
+	}
+	e.LuaVersion = version
+	e.fingerprint = ""
+	e.resetTypes()
+	for uri := range e.analyses {
+		e.bindFile(e.Files[uri])
+	}
+}
+
+// Recheck rebinds and re-infers every file, such as after the configuration has changed. It returns every file that
+// was checked.
+func (e *Environment) Recheck() []*ast.File {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.loadOutdatedPending()
+	e.resetTypes()
+	files := []*ast.File{}
+	for uri := range e.analyses {
+		file := e.Files[uri]
+		e.bindFile(file)
+		files = append(files, file)
+	}
+	for _, file := range files {
+		e.analysis(file)
+	}
+	return files
+}
+
 // CheckPhase1 executes the first phase of type checking.
 // The first phase gathers a list of which types exist in the environment, but does not delve into details.
 func (e *Environment) CheckPhase1() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.checkPhase1()
+}
+
+func (e *Environment) checkPhase1() {
 	for _, file := range e.Files {
-		e.CheckFilePhase1(file)
+		e.collectAnnotations(file)
 	}
 }
 
 func (e *Environment) CheckFilePhase1(file *ast.File) {
-	ast.WalkSemantic(file.Block, func(n ast.Node) bool {
-		for _, trivia := range n.GetLeadingTrivia() {
-			if trivia.Type != token.COMMENT {
-				continue
-			}
-			content, ok := strings.CutPrefix(trivia.Literal, "---")
-			if !ok {
-				continue
-			}
-			a, diags := annotation.Parse(content)
-			for _, diag := range diags {
-				diag.Range.Start += trivia.Pos + 3
-				diag.Range.End += trivia.Pos + 3
-				file.Diagnostics = append(file.Diagnostics, diag)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.collectAnnotations(file)
+}
+
+// CheckPhase2 executes the second phase of type checking.
+// The second phase resolves every identifier to its declaration, then infers the type of every expression.
+func (e *Environment) CheckPhase2() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.checkPhase2()
+}
+
+func (e *Environment) checkPhase2() {
+	for _, file := range e.bindAll() {
+		e.analysis(file)
+	}
+}
+
+// bindAll binds every file, and returns them. All files must be bound before inference so that global variables
+// defined in any file are known.
+func (e *Environment) bindAll() []*ast.File {
+	files := make([]*ast.File, 0, len(e.Files))
+	for _, file := range e.Files {
+		e.bindFile(file)
+		files = append(files, file)
+	}
+	return files
+}
+
+// CheckFile re-runs all checks on a file after its contents have changed. It returns every file whose analysis
+// may have changed as a result: the file itself, the files that require it, directly or indirectly, and the files
+// that use the global variables it defines.
+func (e *Environment) CheckFile(file *ast.File) []*ast.File {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.checkFile(file)
+}
+
+func (e *Environment) checkFile(file *ast.File) []*ast.File {
+	definedGlobals := e.globalsDefinedIn(file)
+	typesChanged := e.declaresTypes(file)
+	if a := e.analyses[file.URI]; a != nil && a.extendsClasses {
+		typesChanged = true
+	}
+	e.collectAnnotations(file)
+	e.bindFile(file)
+	for name := range e.globalsDefinedIn(file) {
+		definedGlobals[name] = true
+	}
+	if typesChanged || e.declaresTypes(file) {
+		// Classes are shared by every file, so they must all be checked again
+		e.loadPendingWorkspace()
+		e.resetTypes()
+		files := []*ast.File{}
+		for uri, a := range e.analyses {
+			a.reset()
+			files = append(files, e.Files[uri])
+		}
+		for _, file := range files {
+			e.analysis(file)
+		}
+		return files
+	}
+
+	// Pending files are not analyzed, but the diagnostics that were cached for them may be out of date now
+	e.loadPendingAffected(definedGlobals, map[protocol.URI]bool{file.URI: true})
+
+	affected := map[protocol.URI]bool{file.URI: true}
+	queue := []protocol.URI{file.URI}
+	for len(queue) > 0 {
+		uri := queue[0]
+		queue = queue[1:]
+		for _, dependent := range e.dependents(uri) {
+			if !affected[dependent] {
+				affected[dependent] = true
+				queue = append(queue, dependent)
 			}
-			if a == nil {
-				continue
+		}
+	}
+	for uri, a := range e.analyses {
+		for _, ident := range a.Globals {
+			if definedGlobals[ident.Token.Literal] {
+				affected[uri] = true
+				break
 			}
-			class, ok := a.(*annotation.Class)
-			if !ok {
-				continue
+		}
+	}
+
+	files := []*ast.File{}
+	for uri := range affected {
+		if a := e.analyses[uri]; a != nil {
+			a.reset()
+		}
+	}
+	for uri := range affected {
+		if file := e.Files[uri]; file != nil {
+			e.analysis(file)
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// Analysis returns the semantic information for the given file, computing it if necessary.
+func (e *Environment) Analysis(file *ast.File) *Analysis {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.analysis(file)
+}
+
+func (e *Environment) analysis(file *ast.File) *Analysis {
+	if current := e.Files[file.URI]; current != nil && current != file {
+		// The file was obtained before it was replaced, so binding it again would undo the replacement
+		if a := e.replaced[file.URI]; a != nil && a.File == file {
+			return a
+		}
+		return e.analysis(current)
+	}
+	a := e.analyses[file.URI]
+	if a == nil || a.File != file || a.Block != file.Block {
+		a = e.bindFile(file)
+	}
+	if a.state == stateBound {
+		a.state = stateInferring
+		in := inferrer{env: e, a: a}
+		in.run()
+		a.state = stateInferred
+	}
+	return a
+}
+
+// Diagnostics returns every diagnostic for the given file: syntax errors, annotation errors, and the diagnostics of
+// the linter.
+func (e *Environment) Diagnostics(file *ast.File) []ast.Diagnostic {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.diagnostics(file)
+}
+
+func (e *Environment) diagnostics(file *ast.File) []ast.Diagnostic {
+	if e.isLibrary(file.URI) {
+		return nil
+	}
+	diagnostics := append([]ast.Diagnostic{}, file.Diagnostics...)
+	if fa := e.annotations[file.URI]; fa != nil {
+		diagnostics = append(diagnostics, fa.diagnostics...)
+	}
+	a := e.analysis(file)
+	// Files are linted once their diagnostics are requested rather than during inference, so that members added to
+	// classes by other files are known
+	if !a.checked && e.Linter != nil {
+		a.checked = true
+		a.Diagnostics = e.Linter.Lint(&View{e}, a)
+	}
+	return append(diagnostics, a.Diagnostics...)
+}
+
+// TypeOf returns the inferred type of the given node in the given file.
+func (e *Environment) TypeOf(file *ast.File, node ast.Node) Type {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.analysis(file).TypeOf(node)
+}
+
+func (e *Environment) bindFile(file *ast.File) *Analysis {
+	e.removeGlobalDefs(file)
+	a := newAnalysis(file)
+	b := binder{env: e, a: a}
+	b.block(file.Block, nil)
+	e.analyses[file.URI] = a
+	e.resolveRequires(a)
+	return a
+}
+
+func (e *Environment) addGlobalDef(name string, def GlobalDef) {
+	global := e.Globals[name]
+	if global == nil {
+		global = &Global{Name: name}
+		e.Globals[name] = global
+	}
+	global.Defs = append(global.Defs, def)
+}
+
+// globalsDefinedIn returns the names of the global variables that are assigned in the given file.
+func (e *Environment) globalsDefinedIn(file *ast.File) map[string]bool {
+	names := map[string]bool{}
+	for name, global := range e.Globals {
+		for _, def := range global.Defs {
+			if def.File.URI == file.URI {
+				names[name] = true
+				break
 			}
-			if e.Types[class.Name] != nil {
-				continue
+		}
+	}
+	return names
+}
+
+func (e *Environment) removeGlobalDefs(file *ast.File) {
+	for name, global := range e.Globals {
+		defs := global.Defs[:0]
+		for _, def := range global.Defs {
+			if def.File.URI != file.URI {
+				defs = append(defs, def)
 			}
-			// TODO: Narrow location to actual name
-			// TODO: Support multiple definition locations
-			e.Types[class.Name] = &Named{Name: class.Name, Range: trivia.Range()}
 		}
-		return true
-	})
+		global.Defs = defs
+		if len(defs) == 0 {
+			delete(e.Globals, name)
+		}
+	}
+}
+
+// globalType returns the union of every type assigned to the given global variable.
+func (e *Environment) globalType(name string) Type {
+	e.loadPendingGlobal(name)
+	global := e.Globals[name]
+	if global == nil {
+		return &Unknown{}
+	}
+	types := []Type{}
+	for _, def := range global.Defs {
+		if typ := e.analysis(def.File).Types[def.Ident]; typ != nil {
+			types = append(types, typ)
+		}
+	}
+	return NewUnion(types...)
 }
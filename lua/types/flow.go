@@ -0,0 +1,215 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/raiguard/luapls/lua/annotation"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/cfg"
+	"github.com/raiguard/luapls/lua/token"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Diagnostic codes reported by control-flow analysis.
+const (
+	CodeDuplicateLabel = "duplicate-label"
+	CodeGotoIntoScope  = "goto-into-scope"
+	CodeInvalidBreak   = "invalid-break"
+	CodeMissingReturn  = "missing-return"
+	CodeUndefinedLabel = "undefined-label"
+	CodeUnreachable    = "unreachable-code"
+)
+
+// labelScope is a block in which labels are visible, along with the index of the statement that is being checked.
+type labelScope struct {
+	block   *ast.Block
+	labels  map[string]int // Label name -> the index of the statement that declares it
+	current int
+}
+
+// checkFlow reports unreachable code, invalid `break` and `goto` statements, duplicate labels, and functions that
+// may end without returning the values declared by their `@return` annotations.
+func (e *Environment) checkFlow(a *Analysis) []ast.Diagnostic {
+	if e.isLibrary(a.File.URI) || a.File.Block == nil {
+		return nil
+	}
+	var diags diagnosticList
+	e.checkBody(a, &diags, nil, a.File.Block)
+	ast.WalkSemantic(a.File.Block, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionExpression:
+			e.checkBody(a, &diags, node, &node.Body)
+		case *ast.FunctionStatement:
+			e.checkBody(a, &diags, node, &node.Body)
+		}
+		return true
+	})
+	return diags
+}
+
+// checkBody checks a single function body, or the main chunk if fn is nil.
+func (e *Environment) checkBody(a *Analysis, diags *diagnosticList, fn ast.Node, body *ast.Block) {
+	g := cfg.New(body)
+	unreachable(diags, g, body)
+	for _, stmt := range g.InvalidBreaks {
+		diags.add(ast.Range(stmt), protocol.DiagnosticSeverityError, CodeInvalidBreak, "'break' outside of a loop")
+	}
+	checkGotos(diags, body, nil)
+	if fn != nil && g.FallsThrough() && e.requiresReturn(a, fn) {
+		var end token.Range
+		switch fn := fn.(type) {
+		case *ast.FunctionExpression:
+			end = fn.EndUnit.Range()
+		case *ast.FunctionStatement:
+			end = fn.EndTok.Range()
+		}
+		diags.add(end, protocol.DiagnosticSeverityWarning, CodeMissingReturn, "Not all code paths return a value")
+	}
+}
+
+// requiresReturn returns whether the function is annotated to return at least one value that may not be nil.
+func (e *Environment) requiresReturn(a *Analysis, fn ast.Node) bool {
+	for _, ann := range a.annotations[fn] {
+		ret, ok := ann.(*annotation.Return)
+		if !ok {
+			continue
+		}
+		for _, expr := range ret.Types {
+			if !Assignable(&Nil{}, e.resolveType(expr)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unreachable reports each run of consecutive statements in the block that can never be executed.
+func unreachable(diags *diagnosticList, g *cfg.Graph, block *ast.Block) {
+	var first, last ast.Statement
+	report := func() {
+		if first != nil {
+			diags.addUnnecessary(token.Range{Start: first.Pos(), End: last.End()}, CodeUnreachable, "Unreachable code")
+			first = nil
+		}
+	}
+	for _, pair := range block.Pairs {
+		stmt := pair.Node
+		if !g.IsReachable(stmt) {
+			if first == nil {
+				first = stmt
+			}
+			last = stmt
+			continue
+		}
+		report()
+		for _, body := range statementBodies(stmt) {
+			unreachable(diags, g, body)
+		}
+	}
+	report()
+}
+
+// statementBodies returns the blocks that are nested directly in the statement and belong to the same function.
+func statementBodies(stmt ast.Statement) []*ast.Block {
+	switch stmt := stmt.(type) {
+	case *ast.DoStatement:
+		return []*ast.Block{&stmt.Body}
+	case *ast.ForInStatement:
+		return []*ast.Block{&stmt.Body}
+	case *ast.ForStatement:
+		return []*ast.Block{&stmt.Body}
+	case *ast.IfStatement:
+		bodies := []*ast.Block{}
+		for _, clause := range stmt.Clauses {
+			bodies = append(bodies, &clause.Body)
+		}
+		return bodies
+	case *ast.RepeatStatement:
+		return []*ast.Block{&stmt.Body}
+	case *ast.WhileStatement:
+		return []*ast.Block{&stmt.Body}
+	}
+	return nil
+}
+
+// checkGotos reports duplicate labels, gotos without a visible label, and gotos that jump forward into the scope of
+// a local variable. A label at the end of a block is outside of the scope of the locals declared before it.
+func checkGotos(diags *diagnosticList, block *ast.Block, scopes []*labelScope) {
+	scope := &labelScope{block: block, labels: map[string]int{}}
+	for i, pair := range block.Pairs {
+		label, ok := pair.Node.(*ast.LabelStatement)
+		if !ok || label.Name == nil {
+			continue
+		}
+		name := label.Name.Token.Literal
+		if _, exists := scope.labels[name]; exists {
+			diags.add(ast.Range(label), protocol.DiagnosticSeverityError, CodeDuplicateLabel, fmt.Sprintf("Duplicate label '%s'", name))
+			continue
+		}
+		scope.labels[name] = i
+	}
+	scopes = append(scopes, scope)
+	for i, pair := range block.Pairs {
+		scope.current = i
+		if stmt, ok := pair.Node.(*ast.GotoStatement); ok {
+			checkGoto(diags, stmt, scopes)
+			continue
+		}
+		for _, body := range statementBodies(pair.Node) {
+			checkGotos(diags, body, scopes)
+		}
+	}
+}
+
+func checkGoto(diags *diagnosticList, stmt *ast.GotoStatement, scopes []*labelScope) {
+	if stmt.Name == nil {
+		return
+	}
+	name := stmt.Name.Token.Literal
+	for i := len(scopes) - 1; i >= 0; i-- {
+		scope := scopes[i]
+		target, ok := scope.labels[name]
+		if !ok {
+			continue
+		}
+		if target < scope.current || atEndOfBlock(scope.block, target) {
+			return
+		}
+		for _, pair := range scope.block.Pairs[scope.current+1 : target] {
+			for _, local := range declaredLocals(pair.Node) {
+				message := fmt.Sprintf("'goto %s' jumps into the scope of local '%s'", name, local.Token.Literal)
+				diags.add(ast.Range(stmt), protocol.DiagnosticSeverityError, CodeGotoIntoScope, message)
+				return
+			}
+		}
+		return
+	}
+	diags.add(ast.Range(stmt), protocol.DiagnosticSeverityError, CodeUndefinedLabel, fmt.Sprintf("No visible label '%s' for goto", name))
+}
+
+// atEndOfBlock returns whether the statement at the given index is only followed by other labels.
+func atEndOfBlock(block *ast.Block, index int) bool {
+	for _, pair := range block.Pairs[index+1:] {
+		if _, ok := pair.Node.(*ast.LabelStatement); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// declaredLocals returns the local variables that the statement declares in its enclosing block.
+func declaredLocals(stmt ast.Statement) []*ast.Identifier {
+	switch stmt := stmt.(type) {
+	case *ast.FunctionStatement:
+		if ident, ok := stmt.Name.(*ast.Identifier); ok && stmt.LocalTok != nil {
+			return []*ast.Identifier{ident}
+		}
+	case *ast.LocalStatement:
+		locals := []*ast.Identifier{}
+		for _, pair := range stmt.Names.Pairs {
+			locals = append(locals, pair.Node)
+		}
+		return locals
+	}
+	return nil
+}
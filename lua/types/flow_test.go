@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlow(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected []string
+	}{
+		{"reachable", "local x = 1 if x then return end print(x)", nil},
+		{"after return", "local function f() do return end print(1) print(2) end f()", []string{"Unreachable code"}},
+		{"after error", "error('x') print(1)", []string{"Unreachable code"}},
+		{"after break", "while true do break print(1) end", []string{"Unreachable code"}},
+		{"after goto", "goto skip print(1) ::skip:: print(2)", []string{"Unreachable code"}},
+		{"both branches", "if c then return else error('x') end print(1)", []string{"Unreachable code"}},
+		{"invalid break", "if c then break end", []string{"'break' outside of a loop"}},
+		{"break in loop", "for i = 1, 2 do if i then break end end", nil},
+		{"undefined label", "goto nowhere", []string{"No visible label 'nowhere' for goto"}},
+		{"label in other function", "::top:: local f = function() goto top end f() goto top", []string{"No visible label 'top' for goto"}},
+		{"nested label", "do ::inner:: end goto inner", []string{"No visible label 'inner' for goto"}},
+		{"enclosing label", "::top:: while c do goto top end", nil},
+		{"into local scope", "goto skip local x = 1 ::skip:: print(x)", []string{"Unreachable code", "'goto skip' jumps into the scope of local 'x'"}},
+		{"label at end of block", "while c do if c then goto continue end local x = 1 print(x) ::continue:: end", nil},
+		{"duplicate label", "::a:: ::a:: goto a", []string{"Duplicate label 'a'"}},
+		{"missing return", "---@return string\nlocal function f() if c then return 'a' end end f()", []string{"Not all code paths return a value"}},
+		{"all paths return", "---@return string\nlocal function f() if c then return 'a' end error('x') end f()", nil},
+		{"optional return", "---@return string?\nlocal function f() end f()", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			messages := []string{}
+			for _, diag := range env.Diagnostics(file) {
+				switch diag.Code {
+				case CodeDuplicateLabel, CodeGotoIntoScope, CodeInvalidBreak, CodeMissingReturn, CodeUndefinedLabel, CodeUnreachable:
+					messages = append(messages, diag.Message)
+				}
+			}
+			if test.expected == nil {
+				test.expected = []string{}
+			}
+			assert.Equal(t, test.expected, messages)
+		})
+	}
+}
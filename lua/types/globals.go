@@ -0,0 +1,88 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Diagnostic codes reported for global variables.
+const (
+	CodeUndefinedGlobal = "undefined-global"
+	CodeNewGlobal       = "new-global"
+)
+
+// checkGlobals reports reads of globals that are not defined anywhere, and, if strict globals are enabled,
+// assignments to globals that are not allowed or defined by a library.
+func (e *Environment) checkGlobals(a *Analysis) []ast.Diagnostic {
+	if e.isLibrary(a.File.URI) {
+		return nil
+	}
+	var diags diagnosticList
+	for _, ident := range a.Globals {
+		name := ident.Token.Literal
+		if e.isAllowedGlobal(name) {
+			continue
+		}
+		if a.writes[ident] {
+			if e.StrictGlobals && !e.isLibraryGlobal(name) {
+				diags.add(ast.Range(ident), protocol.DiagnosticSeverityWarning, CodeNewGlobal, fmt.Sprintf("Assignment to undeclared global '%s'", name))
+			}
+			continue
+		}
+		e.loadPendingGlobal(name)
+		if e.Globals[name] == nil {
+			diags.add(ast.Range(ident), protocol.DiagnosticSeverityWarning, CodeUndefinedGlobal, fmt.Sprintf("Undefined global '%s'", name))
+		}
+	}
+	return diags
+}
+
+// IsAllowedGlobal returns whether the given global is in the configured list of allowed globals.
+func (e *Environment) IsAllowedGlobal(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.isAllowedGlobal(name)
+}
+
+func (e *Environment) isAllowedGlobal(name string) bool {
+	return slices.Contains(e.AllowedGlobals, name)
+}
+
+// IsStdlibGlobal returns whether the given global is defined by the standard library.
+func (e *Environment) IsStdlibGlobal(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.isStdlibGlobal(name)
+}
+
+func (e *Environment) isStdlibGlobal(name string) bool {
+	global := e.Globals[name]
+	if global == nil {
+		return false
+	}
+	for _, def := range global.Defs {
+		if strings.HasPrefix(string(def.File.URI), StdlibURI) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLibraryGlobal returns whether the given global is defined by a library file, such as the standard library.
+func (e *Environment) isLibraryGlobal(name string) bool {
+	e.loadPendingGlobal(name)
+	global := e.Globals[name]
+	if global == nil {
+		return false
+	}
+	for _, def := range global.Defs {
+		if e.isLibrary(def.File.URI) {
+			return true
+		}
+	}
+	return false
+}
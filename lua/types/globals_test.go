@@ -0,0 +1,43 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobals(t *testing.T) {
+	env := workspace(t, map[string]string{
+		"main.lua":   "print(config.name, unknown, helper(), string.upper('a'))",
+		"config.lua": "config = { name = 'x' } function helper() end",
+		"legacy.lua": "local t = unpack({ 1 })",
+	})
+	main := workspaceFile(t, env, "main.lua")
+	assert.Equal(t, []string{"Undefined global 'unknown'"}, diagnosticMessages(env, main))
+	legacy := workspaceFile(t, env, "legacy.lua")
+	assert.Equal(t, []string{"Undefined global 'unpack'"}, diagnosticMessages(env, legacy))
+
+	env.AllowedGlobals = []string{"unknown"}
+	env.SetLuaVersion("5.1")
+	env.Recheck()
+	assert.Empty(t, diagnosticMessages(env, main))
+	assert.Empty(t, diagnosticMessages(env, legacy))
+}
+
+func TestStrictGlobals(t *testing.T) {
+	env := workspace(t, map[string]string{
+		"main.lua": "counter = 1 allowed = 2 _G = {} local x = counter",
+	})
+	main := workspaceFile(t, env, "main.lua")
+	assert.Empty(t, diagnosticMessages(env, main))
+
+	env.AllowedGlobals = []string{"allowed"}
+	env.StrictGlobals = true
+	env.Recheck()
+	assert.Equal(t, []string{"Assignment to undeclared global 'counter'"}, diagnosticMessages(env, main))
+	for _, diag := range env.Diagnostics(main) {
+		if !isUnnecessary(diag) {
+			assert.Equal(t, CodeNewGlobal, diag.Code)
+		}
+	}
+}
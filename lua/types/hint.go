@@ -0,0 +1,165 @@
+package types
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/annotation"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+)
+
+// HintKind describes what an inlay hint shows.
+type HintKind int
+
+const (
+	HintType      HintKind = iota // The type of a variable, shown after its name
+	HintParameter                 // The name of a parameter, shown before its argument
+	HintSelf                      // The implicit `self` parameter of a method, shown in its parameter list
+)
+
+// InlayHint is a label that is shown in the code without being part of it.
+type InlayHint struct {
+	Kind  HintKind
+	Pos   token.Pos // The position that the label is shown at
+	Label string
+}
+
+// HintOptions selects which inlay hints are shown.
+type HintOptions struct {
+	ParameterNames bool // The names of parameters at the arguments of calls
+	LocalTypes     bool // The inferred types of local variables that are not annotated
+	ImplicitSelf   bool // The `self` parameter of methods declared with `:`
+	Obvious        bool // Hints that repeat what the code already says, such as an argument named like its parameter
+}
+
+// DefaultHintOptions are the options used when none are configured.
+var DefaultHintOptions = HintOptions{ParameterNames: true, LocalTypes: true, ImplicitSelf: true}
+
+// InlayHints returns the inlay hints of the file within the given range, in the order of their positions.
+func (e *Environment) InlayHints(file *ast.File, rng token.Range, opts HintOptions) []InlayHint {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if file.Block == nil {
+		return nil
+	}
+	a := e.analysis(file)
+	hints := []InlayHint{}
+	ast.WalkSemantic(file.Block, func(node ast.Node) bool {
+		if node.End() < rng.Start || node.Pos() > rng.End {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.FunctionCall:
+			if opts.ParameterNames {
+				hints = append(hints, parameterHints(a, node, opts)...)
+			}
+		case *ast.LocalStatement:
+			if opts.LocalTypes {
+				hints = append(hints, localTypeHints(a, node, opts)...)
+			}
+		case *ast.FunctionStatement:
+			if opts.ImplicitSelf && a.SelfParams[node] != nil {
+				label := "self"
+				if len(node.Params.Pairs) > 0 || node.Vararg != nil {
+					label = "self, "
+				}
+				hints = append(hints, InlayHint{Kind: HintSelf, Pos: node.LeftParen.End(), Label: label})
+			}
+		}
+		return true
+	})
+	// Nodes that overlap the range can have hints outside of it
+	filtered := hints[:0]
+	for _, hint := range hints {
+		if rng.Start <= hint.Pos && hint.Pos <= rng.End {
+			filtered = append(filtered, hint)
+		}
+	}
+	// A call adds the hints of its arguments before those of the calls in its callee, such as in `f(a)(b)`
+	slices.SortStableFunc(filtered, func(a, b InlayHint) int { return a.Pos - b.Pos })
+	return filtered
+}
+
+// parameterHints returns a hint with the name of the parameter before every argument of the call. Arguments that
+// are passed to the vararg of the function have no hint.
+func parameterHints(a *Analysis, call *ast.FunctionCall, opts HintOptions) []InlayHint {
+	fn, ok := First(a.TypeOf(call.Name)).(*Function)
+	if !ok {
+		return nil
+	}
+	params := fn.Params
+	// Method calls pass the object as the implicit first argument
+	if ie, ok := call.Name.(*ast.IndexExpression); ok && ie.LeftIndexer.Type() == token.COLON {
+		if len(params) == 0 {
+			return nil
+		}
+		params = params[1:]
+	}
+	hints := []InlayHint{}
+	for i, pair := range call.Args.Pairs {
+		if i >= len(params) {
+			break
+		}
+		name := params[i].Name
+		if name == "" || !opts.Obvious && obviousArgument(pair.Node, name) {
+			continue
+		}
+		hints = append(hints, InlayHint{Kind: HintParameter, Pos: pair.Node.Pos(), Label: name + ":"})
+	}
+	return hints
+}
+
+// obviousArgument returns whether the argument already says the name of its parameter, because it is a variable or
+// field with that name, or a string with that value.
+func obviousArgument(arg ast.Expression, name string) bool {
+	switch arg := arg.(type) {
+	case *ast.Identifier:
+		return strings.EqualFold(arg.Token.Literal, name)
+	case *ast.IndexExpression:
+		if ident, ok := arg.Inner.(*ast.Identifier); ok && arg.LeftIndexer.Type() != token.LBRACK {
+			return strings.EqualFold(ident.Token.Literal, name)
+		}
+		if sl, ok := arg.Inner.(*ast.StringLiteral); ok {
+			return strings.EqualFold(sl.Value(), name)
+		}
+	case *ast.StringLiteral:
+		return strings.EqualFold(arg.Value(), name)
+	}
+	return false
+}
+
+// localTypeHints returns a hint with the inferred type after every name of a local statement that is not annotated
+// with `---@type`. Types that are unknown are not shown.
+func localTypeHints(a *Analysis, stmt *ast.LocalStatement, opts HintOptions) []InlayHint {
+	if stmt.Exps == nil {
+		return nil
+	}
+	for _, ann := range a.annotations[stmt] {
+		if _, ok := ann.(*annotation.Type); ok {
+			return nil
+		}
+	}
+	hints := []InlayHint{}
+	for i, pair := range stmt.Names.Pairs {
+		if !opts.Obvious && i < len(stmt.Exps.Pairs) && obviousValue(stmt.Exps.Pairs[i].Node) {
+			continue
+		}
+		typ := a.TypeOf(pair.Node)
+		switch typ.(type) {
+		case *Unknown, *Any:
+			continue
+		}
+		hints = append(hints, InlayHint{Kind: HintType, Pos: pair.Node.End(), Label: ": " + typ.String()})
+	}
+	return hints
+}
+
+// obviousValue returns whether the type of the value can be seen from how it is written.
+func obviousValue(value ast.Expression) bool {
+	switch value.(type) {
+	case *ast.BooleanLiteral, *ast.NilLiteral, *ast.NumberLiteral, *ast.StringLiteral, *ast.TableLiteral, *ast.FunctionExpression:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInlayHints(t *testing.T) {
+	fn := "local function f(a, b) end\n"
+	method := "local T = {}\nfunction T:m(a) end\n"
+	tests := []struct {
+		label    string
+		input    string
+		opts     HintOptions
+		expected string // The input with every hint inserted in brackets
+	}{
+		{"parameters", fn + "f(1, 'x')", DefaultHintOptions, fn + "f([a:]1, [b:]'x')"},
+		{"extra arguments", fn + "f(1, 2, 3)", DefaultHintOptions, fn + "f([a:]1, [b:]2, 3)"},
+		{"vararg", "local function f(a, ...) end\nf(1, 2)", DefaultHintOptions, "local function f(a, ...) end\nf([a:]1, 2)"},
+		{"unknown function", "g(1)", DefaultHintOptions, "g(1)"},
+		{"obvious arguments", fn + "local a = 1\nf(a, t.b)", DefaultHintOptions, fn + "local a = 1\nf(a, t.b)"},
+		{"obvious string", fn + "f('A', 1)", DefaultHintOptions, fn + "f('A', [b:]1)"},
+		{"obvious shown", fn + "local a = 1\nf(a)", HintOptions{ParameterNames: true, Obvious: true}, fn + "local a = 1\nf([a:]a)"},
+		{"method call", method + "T:m(1)", DefaultHintOptions, "local T = {}\nfunction T:m([self, ]a) end\nT:m([a:]1)"},
+		{"method called with dot", method + "T.m(T, 1)", HintOptions{ParameterNames: true}, method + "T.m([self:]T, [a:]1)"},
+		{"implicit self", "local T = {}\nfunction T:m() end", DefaultHintOptions, "local T = {}\nfunction T:m([self]) end"},
+		{"local type", "local x = tostring(1)", DefaultHintOptions, "local x[: string] = tostring([v:]1)"},
+		{"obvious local type", "local x, y = 1, {}", DefaultHintOptions, "local x, y = 1, {}"},
+		{"annotated local", "---@type string\nlocal x = g()", DefaultHintOptions, "---@type string\nlocal x = g()"},
+		{"unknown local type", "local x = g()", DefaultHintOptions, "local x = g()"},
+		{"disabled", fn + "local x = tostring(1)\nf(1)", HintOptions{}, fn + "local x = tostring(1)\nf(1)"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			hints := env.InlayHints(file, token.Range{Start: 0, End: len(test.input)}, test.opts)
+			output := test.input
+			for i := len(hints) - 1; i >= 0; i-- {
+				output = output[:hints[i].Pos] + "[" + hints[i].Label + "]" + output[hints[i].Pos:]
+			}
+			assert.Equal(t, test.expected, output)
+		})
+	}
+}
+
+func TestInlayHintsRange(t *testing.T) {
+	input := "local function f(a) end\nf(1)\nf(2)"
+	env, file := analyze(t, input)
+	hints := env.InlayHints(file, token.Range{Start: 30, End: len(input)}, DefaultHintOptions)
+	assert.Equal(t, []InlayHint{{Kind: HintParameter, Pos: 31, Label: "a:"}}, hints)
+}
@@ -0,0 +1,148 @@
+package types
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultIgnores are skipped in every workspace: version control and editor directories, and installed packages.
+var defaultIgnores = []string{".*/", "node_modules/"}
+
+// ignorePattern is a single pattern in the format of `.gitignore`.
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool // The pattern starts with `!`, and includes paths that an earlier pattern excluded
+	dirOnly bool // The pattern ends with `/`, and only matches directories
+}
+
+// ignoreRules are the patterns that apply to the files in a directory, relative to that directory.
+type ignoreRules struct {
+	base     string
+	patterns []ignorePattern
+}
+
+// parseIgnorePattern parses a single line of a `.gitignore` file. It returns false for blank lines and comments.
+//
+// A pattern that contains a `/` other than at its end is matched against the path relative to the base directory,
+// and any other pattern is matched against the name of every file and directory. `*` and `?` do not match `/`, and
+// `**` matches any number of directories.
+func parseIgnorePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+	pattern := ignorePattern{}
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		atSegmentStart := i == 0 || line[i-1] == '/'
+		switch {
+		case atSegmentStart && strings.HasPrefix(line[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case atSegmentStart && line[i:] == "**":
+			sb.WriteString(".*")
+			i++
+		case line[i] == '*':
+			sb.WriteString("[^/]*")
+		case line[i] == '?':
+			sb.WriteString("[^/]")
+		case line[i] == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			sb.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	pattern.re = re
+	return pattern, true
+}
+
+// parseIgnorePatterns parses every valid pattern in the list.
+func parseIgnorePatterns(lines []string) []ignorePattern {
+	patterns := []ignorePattern{}
+	for _, line := range lines {
+		if pattern, ok := parseIgnorePattern(line); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// readGitignore returns the patterns of the `.gitignore` file in the directory, or nil if there is none.
+func readGitignore(dir string) ([]ignorePattern, error) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return parseIgnorePatterns(lines), scanner.Err()
+}
+
+// isIgnored returns whether the path is excluded by the rules. Like in git, the last pattern that matches decides, and
+// the rules of deeper directories are checked after those of their parents.
+func isIgnored(rules []ignoreRules, path string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		rel, err := filepath.Rel(r.base, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, pattern := range r.patterns {
+			if pattern.dirOnly && !isDir {
+				continue
+			}
+			if pattern.re.MatchString(rel) {
+				ignored = !pattern.negate
+			}
+		}
+	}
+	return ignored
+}
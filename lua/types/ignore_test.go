@@ -0,0 +1,55 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnorePatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"build", "build", true, true},
+		{"build", "src/build", true, true},
+		{"build", "src/builder", true, false},
+		{"build/", "build", false, false},
+		{"/build", "src/build", true, false},
+		{"/build", "build", true, true},
+		{"src/gen", "src/gen", true, true},
+		{"src/gen", "lib/src/gen", true, false},
+		{"*.lua", "src/main.lua", false, true},
+		{"*_spec.lua", "spec/main_spec.lua", false, true},
+		{"src/*.lua", "src/a/main.lua", false, false},
+		{"**/gen", "a/b/gen", true, true},
+		{"src/**", "src/a/b.lua", false, true},
+		{"a/**/b", "a/b", true, true},
+		{"a/**/b", "a/x/y/b", true, true},
+		{"file?.lua", "file1.lua", false, true},
+		{"file[0-9].lua", "filex.lua", false, false},
+		{"file[!0-9].lua", "filex.lua", false, true},
+		{`\#notes`, "#notes", false, true},
+		{".*/", ".git", true, true},
+		{".*/", ".luacheckrc", false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.path, func(t *testing.T) {
+			rules := []ignoreRules{{base: "/root", patterns: parseIgnorePatterns([]string{test.pattern})}}
+			assert.Equal(t, test.ignored, isIgnored(rules, "/root/"+test.path, test.isDir))
+		})
+	}
+}
+
+func TestIgnoreNegation(t *testing.T) {
+	rules := []ignoreRules{
+		{base: "/root", patterns: parseIgnorePatterns([]string{"# generated files", "", "*.gen.lua", "!keep.gen.lua"})},
+		{base: "/root/sub", patterns: parseIgnorePatterns([]string{"keep.gen.lua"})},
+	}
+	assert.True(t, isIgnored(rules, "/root/a.gen.lua", false))
+	assert.False(t, isIgnored(rules, "/root/keep.gen.lua", false))
+	assert.True(t, isIgnored(rules, "/root/sub/keep.gen.lua", false))
+	assert.False(t, isIgnored(rules, "/root/a.lua", false))
+}
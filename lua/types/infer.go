@@ -0,0 +1,634 @@
+package types
+
+import (
+	"strconv"
+
+	"github.com/raiguard/luapls/lua/annotation"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/cfg"
+	"github.com/raiguard/luapls/lua/token"
+)
+
+// inferrer computes the type of every expression in a file. Function bodies are inferred by iterating their
+// control-flow graphs, so the type of a local variable is tracked separately at every point in the program.
+type inferrer struct {
+	env   *Environment
+	a     *Analysis
+	state *flowState
+	fn    *functionContext
+}
+
+type functionContext struct {
+	returns []Type
+}
+
+// maxVisits is the number of times a basic block may be re-inferred before its types are considered stable.
+const maxVisits = 8
+
+func (in *inferrer) run() {
+	in.state = newFlowState()
+	in.fn = &functionContext{}
+	in.body(in.a.Block)
+	in.a.Returns = joinReturns(in.fn.returns)
+}
+
+// body infers every statement in a function body, iterating until the types at the start of each basic block stop
+// changing.
+func (in *inferrer) body(block *ast.Block) {
+	g := cfg.New(block)
+	entry := in.state
+	states := map[*cfg.Block]*flowState{g.Entry: entry.copy()}
+	visits := map[*cfg.Block]int{}
+	queue := []*cfg.Block{g.Entry}
+	queued := map[*cfg.Block]bool{g.Entry: true}
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		queued[b] = false
+		visits[b]++
+		in.state = states[b].copy()
+		for _, node := range b.Nodes {
+			in.node(node)
+		}
+		out := in.state
+		for _, edge := range b.Succs {
+			edgeState := out
+			if edge.Cond != nil {
+				edgeState = in.refine(out, edge.Cond, edge.Kind == cfg.True)
+			}
+			if edgeState == nil {
+				continue
+			}
+			merged, changed := states[edge.To].join(edgeState)
+			states[edge.To] = merged
+			if changed && !queued[edge.To] && visits[edge.To] < maxVisits {
+				queue = append(queue, edge.To)
+				queued[edge.To] = true
+			}
+		}
+	}
+	// Unreachable code is still inferred so that every expression has a type
+	for _, b := range g.Blocks {
+		if visits[b] == 0 {
+			in.state = entry.copy()
+			for _, node := range b.Nodes {
+				in.node(node)
+			}
+		}
+	}
+	in.state = entry
+}
+
+// node infers a single node of a control-flow graph.
+func (in *inferrer) node(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.ForStatement:
+		in.expression(node.Start.Node)
+		in.expression(node.Finish.Node)
+		if node.Step != nil {
+			in.expression(node.Step.Node)
+		}
+		in.declare(node.Name, &Number{})
+	case *ast.ForInStatement:
+		values := in.iteratorValues(in.expand(&node.Exps), len(node.Names.Pairs))
+		for i, pair := range node.Names.Pairs {
+			in.declare(pair.Node, values[i])
+		}
+	case ast.Statement:
+		in.statement(node)
+	case ast.Expression:
+		in.expression(node)
+	}
+}
+
+// statement infers a simple statement. Compound statements are split up by the control-flow graph, so they are
+// never passed to this function.
+func (in *inferrer) statement(stmt ast.Statement) {
+	if in.env.isExcluded(in.a.File, stmt) {
+		return
+	}
+	switch stmt := stmt.(type) {
+	case *ast.AssignmentStatement:
+		values := in.values(&stmt.Exps, len(stmt.Vars.Pairs))
+		in.declaredTypes(stmt, &stmt.Exps, values)
+		for i, pair := range stmt.Vars.Pairs {
+			in.assign(pair.Node, values[i])
+		}
+	case *ast.FunctionCall:
+		in.expression(stmt)
+		if in.isGlobalCall(stmt, "assert") && len(stmt.Args.Pairs) > 0 {
+			if refined := in.refine(in.state, stmt.Args.Pairs[0].Node, true); refined != nil {
+				in.state = refined
+			}
+		}
+	case *ast.FunctionStatement:
+		in.functionStatement(stmt)
+	case *ast.LocalStatement:
+		values := in.values(stmt.Exps, len(stmt.Names.Pairs))
+		in.declaredTypes(stmt, stmt.Exps, values)
+		for i, pair := range stmt.Names.Pairs {
+			in.declare(pair.Node, values[i])
+		}
+	case *ast.ReturnStatement:
+		values := []Type{}
+		if stmt.Exps != nil {
+			values = in.expand(stmt.Exps)
+		}
+		in.fn.returns = append(in.fn.returns, &Tuple{Types: values})
+	}
+}
+
+// iteratorValues returns the types of the n variables of a generic for loop, given the values of its expression
+// list. The first variable can never be nil inside of the loop body.
+func (in *inferrer) iteratorValues(exps []Type, n int) []Type {
+	values := make([]Type, n)
+	for i := range values {
+		values[i] = &Unknown{}
+	}
+	if len(exps) == 0 {
+		return values
+	}
+	iterator, ok := exps[0].(*Function)
+	if !ok || iterator.Return == nil {
+		return values
+	}
+	returns := []Type{iterator.Return}
+	if tuple, ok := iterator.Return.(*Tuple); ok {
+		returns = tuple.Types
+	}
+	for i := range values {
+		if i < len(returns) {
+			values[i] = returns[i]
+		} else {
+			values[i] = &Nil{}
+		}
+	}
+	if truthy := Truthy(values[0]); truthy != nil {
+		values[0] = truthy
+	}
+	return values
+}
+
+// declaredTypes replaces the values of a local or assignment statement with the types declared by its `@type` or
+// `@class` annotations.
+func (in *inferrer) declaredTypes(stmt ast.Statement, exps *ast.Punctuated[ast.Expression], values []Type) {
+	for _, a := range in.a.annotations[stmt] {
+		switch a := a.(type) {
+		case *annotation.Class:
+			class, ok := in.env.lookupType(a.Name).(*Named)
+			if !ok || len(values) == 0 {
+				continue
+			}
+			// Fields of the table that the class is assigned from become members of the class
+			if table, ok := values[0].(*Table); ok && exps != nil && len(exps.Pairs) > 0 {
+				members := in.fieldTable(class)
+				for _, field := range table.Fields {
+					if members.Field(field.Name) == nil {
+						members.SetField(field)
+					}
+				}
+			}
+			values[0] = class
+		case *annotation.Type:
+			for i, expr := range a.Types {
+				if i < len(values) {
+					values[i] = in.env.resolveType(expr)
+				}
+			}
+		}
+	}
+}
+
+// fieldTable returns the table that fields assigned to a value of the given type are stored in, or nil if the type
+// cannot have fields assigned to it.
+func (in *inferrer) fieldTable(typ Type) *Table {
+	switch typ := typ.(type) {
+	case *Named:
+		if !typ.IsClass() {
+			return nil
+		}
+		in.env.resolveNamed(typ)
+		in.a.extendsClasses = true
+		return typ.Fields
+	case *Table:
+		return typ
+	}
+	return nil
+}
+
+// lookupField returns the type of the named field on the given type. Strings are indexed through the `string`
+// library, as they are at runtime.
+func (in *inferrer) lookupField(typ Type, name string) (Type, bool) {
+	if _, ok := typ.(*String); ok {
+		typ = in.env.globalType("string")
+	}
+	return LookupField(typ, name)
+}
+
+// isGlobalCall returns whether the call is to the global function with the given name.
+func (in *inferrer) isGlobalCall(call *ast.FunctionCall, name string) bool {
+	ident, ok := call.Name.(*ast.Identifier)
+	return ok && ident.Token.Literal == name && in.a.Bindings[ident] == nil
+}
+
+func (in *inferrer) functionStatement(stmt *ast.FunctionStatement) {
+	var self Type
+	var field string
+	var owner *Table
+	if ie, ok := stmt.Name.(*ast.IndexExpression); ok {
+		prefix := First(in.expression(ie.Prefix))
+		if ie.LeftIndexer.Type() == token.COLON {
+			self = prefix
+			// Methods on a class table are called on its instances
+			if table, ok := prefix.(*Table); ok && table.Instance != nil {
+				self = table.Instance
+			}
+		}
+		field, _ = fieldName(ie)
+		owner = in.fieldTable(prefix)
+	}
+
+	// The function type is created and stored before the body is inferred so recursive calls can see it
+	fn := in.reuseFunction(stmt)
+	switch name := stmt.Name.(type) {
+	case *ast.Identifier:
+		if v := in.a.Bindings[name]; v != nil && v.Def == name {
+			in.declare(name, fn)
+		} else {
+			in.assign(name, fn)
+		}
+	case *ast.IndexExpression:
+		in.a.Types[name] = fn
+		in.a.Types[name.Inner] = fn
+		if owner != nil && field != "" {
+			owner.SetField(NameAndType{Name: field, Def: name.Inner, Type: fn})
+		}
+	}
+	in.function(stmt, fn, &stmt.Params, stmt.Vararg, &stmt.Body, self)
+}
+
+// function infers the body of a function statement or expression and fills out the given function type.
+func (in *inferrer) function(node ast.Node, fn *Function, params *ast.Punctuated[*ast.Identifier], vararg *ast.Unit, body *ast.Block, self Type) {
+	outerState := in.state
+	in.state = outerState.copy()
+	paramTypes, returns := in.signature(node)
+	fn.Params = nil
+	if v := in.a.SelfParams[node]; v != nil {
+		if self == nil {
+			self = &Unknown{}
+		}
+		if declared := paramTypes["self"]; declared != nil {
+			self = declared
+		}
+		in.state.vars[v] = self
+		fn.Params = append(fn.Params, NameAndType{Name: "self", Type: self})
+	}
+	for _, pair := range params.Pairs {
+		typ := paramTypes[pair.Node.Token.Literal]
+		if typ == nil {
+			typ = &Unknown{}
+		}
+		in.declare(pair.Node, typ)
+		fn.Params = append(fn.Params, NameAndType{Name: pair.Node.Token.Literal, Def: pair.Node, Type: typ})
+	}
+	fn.Vararg = vararg != nil
+
+	// Recursive calls cannot know the return type yet
+	fn.Return = &Unknown{}
+	if returns != nil {
+		fn.Return = returns
+	}
+	outer := in.fn
+	in.fn = &functionContext{}
+	in.body(body)
+	if returns == nil {
+		fn.Return = joinReturns(in.fn.returns)
+	}
+	in.fn = outer
+	in.state = outerState
+}
+
+// signature returns the parameter and return types declared by the `@param` and `@return` annotations of a function.
+// The return type is nil if no return values are declared.
+func (in *inferrer) signature(node ast.Node) (map[string]Type, Type) {
+	params := map[string]Type{}
+	returns := []annotation.TypeExpr{}
+	for _, a := range in.a.annotations[node] {
+		switch a := a.(type) {
+		case *annotation.Param:
+			typ := in.env.resolveType(a.Type)
+			if a.Optional {
+				typ = NewUnion(typ, &Nil{})
+			}
+			params[a.Name] = typ
+		case *annotation.Return:
+			returns = append(returns, a.Types...)
+		}
+	}
+	return params, in.env.resolveTypeList(returns)
+}
+
+// reuseFunction returns the function type previously created for the given node, or creates a new one. Types are
+// reused when a node is inferred multiple times so that the types at the start of a loop can stabilize.
+func (in *inferrer) reuseFunction(node ast.Node) *Function {
+	fn, ok := in.a.Types[node].(*Function)
+	if !ok {
+		fn = &Function{}
+		in.a.Types[node] = fn
+	}
+	return fn
+}
+
+// joinReturns combines the values of every return statement in a function into a single type.
+func joinReturns(returns []Type) Type {
+	if len(returns) == 0 {
+		return nil
+	}
+	length := 0
+	for _, ret := range returns {
+		length = max(length, len(ret.(*Tuple).Types))
+	}
+	if length == 0 {
+		return nil
+	}
+	joined := make([]Type, length)
+	for i := range joined {
+		members := []Type{}
+		for _, ret := range returns {
+			values := ret.(*Tuple).Types
+			if i < len(values) {
+				members = append(members, values[i])
+			} else {
+				members = append(members, &Nil{})
+			}
+		}
+		joined[i] = NewUnion(members...)
+	}
+	if length == 1 {
+		return joined[0]
+	}
+	return &Tuple{Types: joined}
+}
+
+// declare sets the type of a newly declared local variable.
+func (in *inferrer) declare(ident *ast.Identifier, typ Type) {
+	if ident == nil {
+		return
+	}
+	in.a.Types[ident] = typ
+	if v := in.a.Bindings[ident]; v != nil {
+		in.state.vars[v] = typ
+	}
+}
+
+// assign records the assignment of a value to the given target expression.
+func (in *inferrer) assign(target ast.Expression, typ Type) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		in.a.Types[target] = typ
+		if v := in.a.Bindings[target]; v != nil {
+			in.state.vars[v] = typ
+		}
+	case *ast.IndexExpression:
+		prefix := First(in.expression(target.Prefix))
+		if target.LeftIndexer.Type() == token.LBRACK {
+			in.expression(target.Inner)
+		}
+		in.a.Types[target] = typ
+		table := in.fieldTable(prefix)
+		if table == nil {
+			return
+		}
+		name, ok := fieldName(target)
+		if !ok {
+			return
+		}
+		if field := table.Field(name); field != nil {
+			field.Type = NewUnion(field.Type, typ)
+		} else {
+			table.SetField(NameAndType{Name: name, Def: target.Inner, Type: typ})
+		}
+		in.a.Types[target.Inner] = table.Field(name).Type
+	default:
+		in.expression(target)
+	}
+}
+
+func (in *inferrer) expressions(exps *ast.Punctuated[ast.Expression]) {
+	for _, pair := range exps.Pairs {
+		in.expression(pair.Node)
+	}
+}
+
+// expand returns the types of every value produced by the expression list. Only the last expression may produce
+// multiple values.
+func (in *inferrer) expand(exps *ast.Punctuated[ast.Expression]) []Type {
+	types := []Type{}
+	for i, pair := range exps.Pairs {
+		typ := in.expression(pair.Node)
+		if tuple, ok := typ.(*Tuple); ok && i == len(exps.Pairs)-1 {
+			types = append(types, tuple.Types...)
+			continue
+		}
+		types = append(types, First(typ))
+	}
+	return types
+}
+
+// values returns the types of the first n values produced by the expression list. Missing values are nil.
+func (in *inferrer) values(exps *ast.Punctuated[ast.Expression], n int) []Type {
+	if exps == nil {
+		exps = &ast.Punctuated[ast.Expression]{}
+	}
+	types := in.expand(exps)
+	filler := Type(&Nil{})
+	if len(exps.Pairs) > 0 {
+		// Calls to unknown functions may return any number of values
+		if _, ok := in.a.Types[exps.Pairs[len(exps.Pairs)-1].Node].(*Unknown); ok {
+			filler = &Unknown{}
+		}
+	}
+	for len(types) < n {
+		types = append(types, filler)
+	}
+	return types[:n]
+}
+
+// expression infers the type of the given expression and caches it.
+func (in *inferrer) expression(expr ast.Expression) Type {
+	if expr == nil {
+		return &Unknown{}
+	}
+	typ := in.inferExpression(expr)
+	if typ == nil {
+		typ = &Unknown{}
+	}
+	in.a.Types[expr] = typ
+	return typ
+}
+
+func (in *inferrer) inferExpression(expr ast.Expression) Type {
+	switch expr := expr.(type) {
+	case *ast.BooleanLiteral:
+		return &Boolean{}
+	case *ast.FunctionCall:
+		callee := First(in.expression(expr.Name))
+		args := in.expand(&expr.Args)
+		switch {
+		case in.isGlobalCall(expr, "require"):
+			return in.env.requireType(in.a, expr)
+		case in.isGlobalCall(expr, "setmetatable") && len(args) >= 2:
+			setMetatable(args[0], args[1])
+			return args[0]
+		case in.isGlobalCall(expr, "getmetatable") && len(args) >= 1:
+			if table, ok := args[0].(*Table); ok && table.Metatable != nil {
+				return table.Metatable
+			}
+			return &Unknown{}
+		}
+		fn, ok := callee.(*Function)
+		if !ok {
+			fn = Metamethod(callee, "__call")
+		}
+		if fn == nil {
+			return &Unknown{}
+		}
+		if fn.Return == nil {
+			return &Tuple{}
+		}
+		return fn.Return
+	case *ast.FunctionExpression:
+		fn := in.reuseFunction(expr)
+		in.function(expr, fn, &expr.Params, expr.Vararg, &expr.Body, nil)
+		return fn
+	case *ast.Identifier:
+		if v := in.a.Bindings[expr]; v != nil {
+			return in.state.vars[v]
+		}
+		return in.env.globalType(expr.Token.Literal)
+	case *ast.IndexExpression:
+		prefix := First(in.expression(expr.Prefix))
+		if expr.LeftIndexer.Type() == token.LBRACK {
+			in.expression(expr.Inner)
+		}
+		var typ Type = &Unknown{}
+		if name, ok := fieldName(expr); ok {
+			if field, ok := in.lookupField(prefix, name); ok {
+				typ = field
+			}
+		}
+		if expr.LeftIndexer.Type() != token.LBRACK {
+			in.a.Types[expr.Inner] = typ
+		}
+		return typ
+	case *ast.InfixExpression:
+		left := First(in.expression(expr.Left))
+		right := First(in.expression(expr.Right))
+		return in.infix(expr.Operator.Type(), left, right)
+	case *ast.NilLiteral:
+		return &Nil{}
+	case *ast.NumberLiteral:
+		return &Number{}
+	case *ast.PrefixExpression:
+		right := First(in.expression(expr.Right))
+		if fn := Metamethod(right, prefixMetamethodEvents[expr.Operator.Type()]); fn != nil {
+			return metamethodResult(fn)
+		}
+		switch expr.Operator.Type() {
+		case token.NOT:
+			return &Boolean{}
+		case token.LEN, token.MINUS:
+			return &Number{}
+		}
+	case *ast.StringLiteral:
+		return &String{}
+	case *ast.TableLiteral:
+		return in.table(expr)
+	case *ast.Vararg:
+		return &Unknown{}
+	}
+	return &Unknown{}
+}
+
+func (in *inferrer) infix(op token.TokenType, left, right Type) Type {
+	if event := metamethodEvents[op]; event != "" {
+		fn := Metamethod(left, event)
+		if fn == nil {
+			fn = Metamethod(right, event)
+		}
+		if fn != nil {
+			if op == token.EQUAL || op == token.NEQ || op == token.LT || op == token.GT || op == token.LEQ || op == token.GEQ {
+				return &Boolean{}
+			}
+			return metamethodResult(fn)
+		}
+	}
+	switch op {
+	case token.PLUS, token.MINUS, token.MUL, token.SLASH, token.MOD, token.POW:
+		return &Number{}
+	case token.CONCAT:
+		return &String{}
+	case token.EQUAL, token.NEQ, token.LT, token.GT, token.LEQ, token.GEQ:
+		return &Boolean{}
+	case token.AND:
+		return NewUnion(Falsy(left), right)
+	case token.OR:
+		return NewUnion(Truthy(left), right)
+	}
+	return &Unknown{}
+}
+
+func (in *inferrer) table(tl *ast.TableLiteral) *Table {
+	table, ok := in.a.Types[tl].(*Table)
+	if !ok {
+		table = &Table{}
+	}
+	index := 1
+	for _, pair := range tl.Fields.Pairs {
+		switch field := pair.Node.(type) {
+		case *ast.TableArrayField:
+			typ := First(in.expression(field.Expr))
+			table.SetField(NameAndType{Name: ArrayFieldName(index), Def: field, Type: typ})
+			index++
+		case *ast.TableExpressionKeyField:
+			in.expression(field.Name)
+			typ := First(in.expression(field.Expr))
+			if name, ok := ConstantKey(field.Name); ok {
+				table.SetField(NameAndType{Name: name, Def: field.Name, Type: typ})
+			}
+		case *ast.TableSimpleKeyField:
+			typ := First(in.expression(field.Expr))
+			in.a.Types[&field.Name] = typ
+			table.SetField(NameAndType{Name: field.Name.Token.Literal, Def: &field.Name, Type: typ})
+		}
+	}
+	return table
+}
+
+// fieldName returns the name of the field that the index expression refers to, if it is constant.
+func fieldName(ie *ast.IndexExpression) (string, bool) {
+	if ie.LeftIndexer.Type() != token.LBRACK {
+		ident, ok := ie.Inner.(*ast.Identifier)
+		if !ok {
+			return "", false
+		}
+		return ident.Token.Literal, true
+	}
+	return ConstantKey(ie.Inner)
+}
+
+// ConstantKey returns the table key that the given expression evaluates to, if it is a literal.
+func ConstantKey(expr ast.Expression) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.StringLiteral:
+		return expr.Value(), true
+	case *ast.NumberLiteral:
+		return "[" + expr.Token.Literal + "]", true
+	}
+	return "", false
+}
+
+// ArrayFieldName returns the name of the field at the given one-based position in the array part of a table.
+func ArrayFieldName(index int) string {
+	return "[" + strconv.Itoa(index) + "]"
+}
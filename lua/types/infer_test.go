@@ -0,0 +1,95 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInference(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected string
+	}{
+		{"number", "local x = 3", "number"},
+		{"string", "local x = 'foo'", "string"},
+		{"boolean", "local x = true", "boolean"},
+		{"nil", "local x", "nil"},
+		{"arithmetic", "local a = 1 local x = a * 2 + 3", "number"},
+		{"concat", "local x = 1 .. 'foo'", "string"},
+		{"comparison", "local x = 1 < 2", "boolean"},
+		{"length", "local x = #'foo'", "number"},
+		{"not", "local x = not 1", "boolean"},
+		{"negate", "local x = -1", "number"},
+		{"or", "local a = nil local x = a or 'default'", "string"},
+		{"and", "local a = 'foo' local x = a and 1", "number"},
+		{"table", "local x = {first = 1, ['second'] = 'two', true}", "{first: number, second: string, [1]: boolean}"},
+		{"table field", "local t = {first = 1} local x = t.first", "number"},
+		{"table bracket field", "local t = {first = 1} local x = t['first']", "number"},
+		{"dynamic field", "local t = {} t.foo = 'bar' local x = t.foo", "string"},
+		{"function", "local x = function(a, b) return 1 end", "function(a: unknown, b: unknown) → number"},
+		{"vararg function", "local function x(...) end", "function(...)"},
+		{"call", "local function f() return 'foo' end local x = f()", "string"},
+		{"call no return", "local function f() end local x = f()", "nil"},
+		{"multiple returns", "local function f() return 1, 'two' end local _, x = f()", "string"},
+		{"union returns", "local function f(a) if a then return 1 end return 'one' end local x = f()", "number|string"},
+		{"method", "local t = {} function t.foo() return true end local x = t.foo()", "boolean"},
+		{"self", "local t = {n = 1} function t:get() return self.n end local x = t:get()", "number"},
+		{"recursive", "local function f() return f() end local x = f", "function() → unknown"},
+		{"global", "foo = 3 local x = foo", "number"},
+		{"numeric for", "for i = 1, 10 do local x = i end", "number"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			assert.Equal(t, test.expected, typeOfLocal(t, env, file, "x"))
+		})
+	}
+}
+
+func TestSelfReferentialTable(t *testing.T) {
+	env, file := analyze(t, "local x = {} x.self = x")
+	assert.Equal(t, "{self: {...}}", typeOfLocal(t, env, file, "x"))
+}
+
+// checks runs every check of the package, like the built-in rules of the linter.
+type checks struct{}
+
+func (checks) Lint(v *View, a *Analysis) []ast.Diagnostic {
+	diagnostics := []ast.Diagnostic{}
+	for _, check := range []func(*Analysis) []ast.Diagnostic{v.CheckRequires, v.CheckGlobals, v.CheckUnused, v.CheckFlow, v.CheckShadowing, v.CheckTypes} {
+		diagnostics = append(diagnostics, check(a)...)
+	}
+	return diagnostics
+}
+
+// newEnvironment returns an environment that runs every check of the package.
+func newEnvironment() *Environment {
+	env := NewEnvironment()
+	env.Linter = checks{}
+	return env
+}
+
+func analyze(t *testing.T, input string) (*Environment, *ast.File) {
+	env := newEnvironment()
+	file := env.AddTransientFile("file:///test.lua", input)
+	require.NotNil(t, file)
+	env.CheckPhase1()
+	env.CheckPhase2()
+	return env, file
+}
+
+// typeOfLocal returns the type of the last local variable with the given name.
+func typeOfLocal(t *testing.T, env *Environment, file *ast.File, name string) string {
+	a := env.Analysis(file)
+	for i := len(a.Variables) - 1; i >= 0; i-- {
+		if v := a.Variables[i]; v.Name == name && v.Def != nil {
+			return a.TypeOf(v.Def).String()
+		}
+	}
+	require.Failf(t, "variable not found", "no local named %s", name)
+	return ""
+}
@@ -0,0 +1,205 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/raiguard/luapls/lua/token"
+)
+
+// maxIndexDepth limits how many `__index` metatables are followed when looking up a field.
+const maxIndexDepth = 16
+
+// metamethodEvents maps operators to the metamethod that overrides them.
+var metamethodEvents = map[token.TokenType]string{
+	token.PLUS:   "__add",
+	token.MINUS:  "__sub",
+	token.MUL:    "__mul",
+	token.SLASH:  "__div",
+	token.MOD:    "__mod",
+	token.POW:    "__pow",
+	token.CONCAT: "__concat",
+	token.EQUAL:  "__eq",
+	token.NEQ:    "__eq",
+	token.LT:     "__lt",
+	token.GT:     "__lt",
+	token.LEQ:    "__le",
+	token.GEQ:    "__le",
+}
+
+// prefixMetamethodEvents maps prefix operators to the metamethod that overrides them.
+var prefixMetamethodEvents = map[token.TokenType]string{
+	token.MINUS: "__unm",
+	token.LEN:   "__len",
+}
+
+// LookupField returns the type of the named field on the given type, following `__index` metamethods. The second
+// return value is false if the field does not exist.
+func LookupField(typ Type, name string) (Type, bool) {
+	for depth := 0; depth < maxIndexDepth; depth++ {
+		if named, ok := typ.(*Named); ok {
+			return lookupClassField(named, name, depth)
+		}
+		table, ok := typ.(*Table)
+		if !ok {
+			return nil, false
+		}
+		if field := table.Field(name); field != nil {
+			return field.Type, true
+		}
+		if table.Value != nil && keyAccepts(table.Key, name) {
+			return table.Value, true
+		}
+		if table.Metatable == nil {
+			return nil, false
+		}
+		index := table.Metatable.Field("__index")
+		if index == nil {
+			return nil, false
+		}
+		typ = First(index.Type)
+		if fn, ok := typ.(*Function); ok {
+			// An `__index` function returning a table exposes the fields of that table
+			if fn.Return == nil {
+				return nil, false
+			}
+			typ = First(fn.Return)
+			if _, ok := typ.(*Table); !ok {
+				return typ, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// lookupClassField returns the type of the named field on the given class or one of the classes it inherits from.
+func lookupClassField(named *Named, name string, depth int) (Type, bool) {
+	if named.Alias != nil {
+		return LookupField(named.Alias, name)
+	}
+	if named.Fields != nil {
+		if field := named.Fields.Field(name); field != nil {
+			return field.Type, true
+		}
+	}
+	if depth >= maxIndexDepth {
+		return nil, false
+	}
+	for _, parent := range named.Parents {
+		if typ, ok := lookupClassField(parent, name, depth+1); ok {
+			return typ, true
+		}
+	}
+	return nil, false
+}
+
+// keyAccepts returns whether a field with the given name is a valid key of the given type. Numeric keys are named
+// `[n]`.
+func keyAccepts(key Type, name string) bool {
+	numeric := strings.HasPrefix(name, "[")
+	for _, member := range members(key) {
+		switch member.(type) {
+		case *Any, *Unknown:
+			return true
+		case *Number:
+			if numeric {
+				return true
+			}
+		case *String:
+			if !numeric {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldDecl returns the field that declares the named member of the given type, following `__index` metatables and
+// inheritance, along with the class that it belongs to, if any. Unlike LookupField, it returns nil for members that
+// are only known through the key type of a table or the return type of an `__index` function.
+func fieldDecl(typ Type, name string) (*NameAndType, *Named) {
+	for depth := 0; depth < maxIndexDepth; depth++ {
+		switch t := typ.(type) {
+		case *Named:
+			return classFieldDecl(t, name, depth)
+		case *Table:
+			if field := t.Field(name); field != nil {
+				return field, nil
+			}
+			if t.Metatable == nil {
+				return nil, nil
+			}
+			index := t.Metatable.Field("__index")
+			if index == nil {
+				return nil, nil
+			}
+			typ = First(index.Type)
+		default:
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+func classFieldDecl(named *Named, name string, depth int) (*NameAndType, *Named) {
+	if named.Alias != nil {
+		return fieldDecl(named.Alias, name)
+	}
+	if named.Fields != nil {
+		if field := named.Fields.Field(name); field != nil {
+			return field, named
+		}
+	}
+	if depth >= maxIndexDepth {
+		return nil, nil
+	}
+	for _, parent := range named.Parents {
+		if field, class := classFieldDecl(parent, name, depth+1); field != nil {
+			return field, class
+		}
+	}
+	return nil, nil
+}
+
+// Metamethod returns the metamethod of the given type for the given event, if there is one.
+func Metamethod(typ Type, event string) *Function {
+	field := metamethodField(typ, event)
+	if field == nil {
+		return nil
+	}
+	fn, _ := First(field.Type).(*Function)
+	return fn
+}
+
+// metamethodField returns the field of the metatable of the given type for the given event, if there is one.
+func metamethodField(typ Type, event string) *NameAndType {
+	table, ok := typ.(*Table)
+	if !ok || table.Metatable == nil {
+		return nil
+	}
+	return table.Metatable.Field(event)
+}
+
+// metamethodResult returns the first value returned by the given metamethod.
+func metamethodResult(fn *Function) Type {
+	if fn.Return == nil {
+		return &Nil{}
+	}
+	return First(fn.Return)
+}
+
+// setMetatable records that the given metatable has been assigned to the table. If the metatable indexes itself, as
+// in the common class idiom, the table is also recorded as an instance of it.
+func setMetatable(typ Type, mt Type) {
+	table, ok := typ.(*Table)
+	if !ok {
+		return
+	}
+	metatable, ok := mt.(*Table)
+	if !ok {
+		return
+	}
+	table.Metatable = metatable
+	if index := metatable.Field("__index"); index != nil && First(index.Type) == metatable {
+		metatable.Instance = table
+	}
+}
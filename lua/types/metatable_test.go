@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const classIdiom = `
+local Class = {}
+Class.__index = Class
+function Class.new(name)
+	local self = setmetatable({}, Class)
+	self.name = "foo"
+	return self
+end
+function Class:get_name()
+	return self.name
+end
+`
+
+func TestMetatables(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected string
+	}{
+		{"index table", "local t = setmetatable({}, {__index = {foo = 1}}) local x = t.foo", "number"},
+		{"index chain", "local base = {foo = 'bar'} local mid = setmetatable({}, {__index = base}) local t = setmetatable({}, {__index = mid}) local x = t.foo", "string"},
+		{"index function", "local t = setmetatable({}, {__index = function(t, k) return {foo = true} end}) local x = t.foo", "boolean"},
+		{"statement", "local t = {} setmetatable(t, {__index = {foo = 1}}) local x = t.foo", "number"},
+		{"own field wins", "local t = setmetatable({foo = 'own'}, {__index = {foo = 1}}) local x = t.foo", "string"},
+		{"getmetatable", "local mt = {foo = 1} local t = setmetatable({}, mt) local x = getmetatable(t).foo", "number"},
+		{"class method", classIdiom + "local obj = Class.new() local x = obj:get_name()", "string"},
+		{"class field", classIdiom + "local obj = Class.new() local x = obj.name", "string"},
+		{"add", "local v = setmetatable({}, {__add = function(a, b) return 'sum' end}) local x = v + 1", "string"},
+		{"add right", "local v = setmetatable({}, {__add = function(a, b) return 'sum' end}) local x = 1 + v", "string"},
+		{"concat", "local v = setmetatable({}, {__concat = function(a, b) return 1 end}) local x = v .. 'foo'", "number"},
+		{"len", "local v = setmetatable({}, {__len = function(a) return 'len' end}) local x = #v", "string"},
+		{"unm", "local v = setmetatable({}, {__unm = function(a) return true end}) local x = -v", "boolean"},
+		{"call", "local v = setmetatable({}, {__call = function(self, a) return 'called' end}) local x = v(1)", "string"},
+		{"eq", "local v = setmetatable({}, {__eq = function(a, b) return 1 end}) local x = v == v", "boolean"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			assert.Equal(t, test.expected, typeOfLocal(t, env, file, "x"))
+		})
+	}
+}
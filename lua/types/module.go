@@ -0,0 +1,215 @@
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/util"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Diagnostic codes reported for `require` calls.
+const (
+	CodeModuleNotFound  = "module-not-found"
+	CodeCircularRequire = "circular-require"
+)
+
+// DefaultPackagePath is the list of templates used to resolve modules if none are configured.
+var DefaultPackagePath = []string{"?.lua", "?/init.lua"}
+
+// Require is a call to `require` with a constant module name.
+type Require struct {
+	Call   *ast.FunctionCall
+	Arg    *ast.StringLiteral
+	Module string
+	URI    protocol.URI // Empty if the module could not be resolved
+}
+
+// ResolveModule returns the URI of the file that `require(name)` loads. Each template in the package path is tried
+// against each root in order, and the first file that exists is used. Files that exist on disk but have not been
+// loaded yet are added to the environment.
+func (e *Environment) ResolveModule(name string) (protocol.URI, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.resolveModule(name)
+}
+
+func (e *Environment) resolveModule(name string) (protocol.URI, bool) {
+	packagePath := e.PackagePath
+	if len(packagePath) == 0 {
+		packagePath = DefaultPackagePath
+	}
+	modulePath := strings.ReplaceAll(name, ".", "/")
+	for _, root := range e.roots() {
+		for _, template := range packagePath {
+			path := filepath.Join(root, strings.ReplaceAll(template, "?", modulePath))
+			uri, err := util.PathToURI(path)
+			if err != nil {
+				continue
+			}
+			if e.Files[uri] != nil || e.loadPending(uri) != nil {
+				return uri, true
+			}
+			if !util.FileExists(path) {
+				continue
+			}
+			if file := e.addFile(uri); file != nil {
+				e.collectAnnotations(file)
+				e.bindFile(file)
+				return uri, true
+			}
+		}
+	}
+	return "", false
+}
+
+// roots returns the absolute directories that modules are resolved relative to.
+func (e *Environment) roots() []string {
+	if len(e.Roots) == 0 {
+		return []string{e.RootPath}
+	}
+	roots := make([]string, len(e.Roots))
+	for i, root := range e.Roots {
+		roots[i] = e.resolvePath(root)
+	}
+	return roots
+}
+
+// resolvePath returns the path as an absolute path, treating relative paths as relative to RootPath.
+func (e *Environment) resolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(e.RootPath, path)
+}
+
+// resolveRequires finds every `require` call in the analysis and resolves its module.
+func (e *Environment) resolveRequires(a *Analysis) {
+	a.Requires = nil
+	for _, ident := range a.Globals {
+		if ident.Token.Literal != "require" {
+			continue
+		}
+		call, arg := requireCall(a, ident)
+		if call == nil {
+			continue
+		}
+		req := Require{Call: call, Arg: arg, Module: arg.Value()}
+		req.URI, _ = e.resolveModule(req.Module)
+		a.Requires = append(a.Requires, req)
+	}
+}
+
+// requireCall returns the call and module name argument if the identifier is the callee of a `require` call.
+func requireCall(a *Analysis, ident *ast.Identifier) (*ast.FunctionCall, *ast.StringLiteral) {
+	call := a.calls[ident]
+	if call == nil || len(call.Args.Pairs) != 1 {
+		return nil, nil
+	}
+	arg, ok := call.Args.Pairs[0].Node.(*ast.StringLiteral)
+	if !ok {
+		return nil, nil
+	}
+	return call, arg
+}
+
+// Dependencies returns the files that the given file requires.
+func (e *Environment) Dependencies(uri protocol.URI) []protocol.URI {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dependencies(uri)
+}
+
+func (e *Environment) dependencies(uri protocol.URI) []protocol.URI {
+	a := e.analyses[uri]
+	if a == nil {
+		return nil
+	}
+	deps := []protocol.URI{}
+	for _, req := range a.Requires {
+		if req.URI != "" {
+			deps = append(deps, req.URI)
+		}
+	}
+	return deps
+}
+
+// Dependents returns the files that directly require the given file.
+func (e *Environment) Dependents(uri protocol.URI) []protocol.URI {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dependents(uri)
+}
+
+func (e *Environment) dependents(uri protocol.URI) []protocol.URI {
+	dependents := []protocol.URI{}
+	for other, a := range e.analyses {
+		for _, req := range a.Requires {
+			if req.URI == uri {
+				dependents = append(dependents, other)
+				break
+			}
+		}
+	}
+	return dependents
+}
+
+// requiresTransitively returns whether the file at from requires the file at to, directly or indirectly.
+func (e *Environment) requiresTransitively(from, to protocol.URI) bool {
+	visited := map[protocol.URI]bool{}
+	stack := []protocol.URI{from}
+	for len(stack) > 0 {
+		uri := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if uri == to {
+			return true
+		}
+		if visited[uri] {
+			continue
+		}
+		visited[uri] = true
+		stack = append(stack, e.dependencies(uri)...)
+	}
+	return false
+}
+
+// checkRequires reports modules that could not be resolved and requires that form a cycle.
+func (e *Environment) checkRequires(a *Analysis) []ast.Diagnostic {
+	var diags diagnosticList
+	for _, req := range a.Requires {
+		if req.URI == "" {
+			diags.add(ast.Range(req.Arg), protocol.DiagnosticSeverityWarning, CodeModuleNotFound, fmt.Sprintf("Module '%s' not found", req.Module))
+			continue
+		}
+		if e.requiresTransitively(req.URI, a.File.URI) {
+			diags.add(ast.Range(req.Call), protocol.DiagnosticSeverityWarning, CodeCircularRequire, fmt.Sprintf("Circular require of module '%s'", req.Module))
+		}
+	}
+	return diags
+}
+
+// requireType returns the type of the value returned by the required module.
+func (e *Environment) requireType(a *Analysis, call *ast.FunctionCall) Type {
+	for _, req := range a.Requires {
+		if req.Call != call {
+			continue
+		}
+		file := e.Files[req.URI]
+		if file == nil {
+			return &Unknown{}
+		}
+		module := e.analysis(file)
+		if module.state != stateInferred {
+			// The module is still being inferred because of a circular require
+			return &Unknown{}
+		}
+		if module.Returns == nil {
+			// Modules that do not return anything are stored as `true` in `package.loaded`
+			return &Boolean{}
+		}
+		return First(module.Returns)
+	}
+	return &Unknown{}
+}
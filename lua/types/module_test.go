@@ -0,0 +1,144 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// workspace writes the given files to a temporary directory and initializes an environment in it.
+func workspace(t *testing.T, files map[string]string) *Environment {
+	env := newEnvironment()
+	env.RootPath = writeFiles(t, files)
+	env.Init()
+	return env
+}
+
+// writeFiles writes the given files to a temporary directory and returns its path.
+func writeFiles(t testing.TB, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func workspaceFile(t *testing.T, env *Environment, name string) *ast.File {
+	uri, err := util.PathToURI(filepath.Join(env.RootPath, name))
+	require.NoError(t, err)
+	file := env.Files[uri]
+	require.NotNil(t, file, "file %s was not loaded", name)
+	return file
+}
+
+// diagnosticMessages returns the messages of the diagnostics of the file. Unused code is covered by TestUnused, so it
+// is left out to keep the inputs of other tests short.
+func diagnosticMessages(env *Environment, file *ast.File) []string {
+	return messages(env.Diagnostics(file))
+}
+
+func messages(diagnostics []ast.Diagnostic) []string {
+	messages := []string{}
+	for _, diag := range diagnostics {
+		if !isUnnecessary(diag) {
+			messages = append(messages, diag.Message)
+		}
+	}
+	return messages
+}
+
+func isUnnecessary(diag ast.Diagnostic) bool {
+	return slices.Contains(diag.Tags, protocol.DiagnosticTagUnnecessary)
+}
+
+func TestRequire(t *testing.T) {
+	env := workspace(t, map[string]string{
+		"main.lua":           "local util = require('lib.util') local x = util.greet()  local y = require('lib')",
+		"lib/util.lua":       "local M = {} function M.greet() return 'hello' end return M",
+		"lib/init.lua":       "return 3",
+		"other/missing.lua":  "local m = require('does.not.exist')",
+		"cycle/a.lua":        "return require('cycle.b')",
+		"cycle/b.lua":        "return require('cycle.a')",
+		"cycle/consumer.lua": "local x = require('cycle.a')",
+	})
+
+	main := workspaceFile(t, env, "main.lua")
+	assert.Equal(t, "string", typeOfLocal(t, env, main, "x"))
+	assert.Equal(t, "number", typeOfLocal(t, env, main, "y"))
+	assert.Empty(t, diagnosticMessages(env, main))
+
+	util := workspaceFile(t, env, "lib/util.lua")
+	assert.ElementsMatch(t, []string{main.URI}, env.Dependents(util.URI))
+
+	missing := workspaceFile(t, env, "other/missing.lua")
+	assert.Equal(t, []string{"Module 'does.not.exist' not found"}, diagnosticMessages(env, missing))
+
+	a := workspaceFile(t, env, "cycle/a.lua")
+	assert.Equal(t, []string{"Circular require of module 'cycle.b'"}, diagnosticMessages(env, a))
+	consumer := workspaceFile(t, env, "cycle/consumer.lua")
+	assert.Empty(t, diagnosticMessages(env, consumer))
+}
+
+func TestLibraryAndIgnore(t *testing.T) {
+	env := newEnvironment()
+	env.RootPath = writeFiles(t, map[string]string{
+		"main.lua":            "print(host_api)",
+		"defs/host.lua":       "host_api = {}",
+		"vendor/dep.lua":      "print(undefined_global)",
+		"build/generated.lua": "print(undefined_global)",
+		"spec/main_spec.lua":  "print(undefined_global)",
+	})
+	env.Library = []string{"defs"}
+	env.Ignore = []string{"vendor/", "build", "*_spec.lua"}
+	env.Init()
+
+	assert.Equal(t, []string{}, diagnosticMessages(env, workspaceFile(t, env, "main.lua")))
+	uri, err := util.PathToURI(filepath.Join(env.RootPath, "defs/host.lua"))
+	require.NoError(t, err)
+	assert.True(t, env.IsLibrary(uri))
+	for _, name := range []string{"vendor/dep.lua", "build/generated.lua", "spec/main_spec.lua"} {
+		uri, err := util.PathToURI(filepath.Join(env.RootPath, name))
+		require.NoError(t, err)
+		assert.Nil(t, env.Files[uri], "%s should be ignored", name)
+	}
+}
+
+func TestRequireRoots(t *testing.T) {
+	env := workspace(t, map[string]string{
+		"main.lua":         "local x = require('foo')",
+		"src/foo/main.lua": "return 'main'",
+		"src/foo/init.lua": "return 1",
+		"lib/foo.lua":      "return true",
+	})
+	env.Roots = []string{"src", "lib"}
+	env.PackagePath = []string{"?/main.lua", "?.lua"}
+	main := workspaceFile(t, env, "main.lua")
+	env.CheckFile(main)
+	assert.Equal(t, "string", typeOfLocal(t, env, main, "x"))
+}
+
+func TestRecheckDependents(t *testing.T) {
+	env := workspace(t, map[string]string{
+		"main.lua":  "local x = require('lib')",
+		"lib.lua":   "return 1",
+		"other.lua": "local y = 1",
+	})
+	lib := workspaceFile(t, env, "lib.lua")
+	main := workspaceFile(t, env, "main.lua")
+	assert.Equal(t, "number", typeOfLocal(t, env, main, "x"))
+
+	_, replacement := analyze(t, "return 'changed'")
+	lib.Block = replacement.Block
+	affected := env.CheckFile(lib)
+	assert.ElementsMatch(t, []*ast.File{lib, main}, affected)
+	assert.Equal(t, "string", typeOfLocal(t, env, main, "x"))
+}
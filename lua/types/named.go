@@ -1,11 +1,33 @@
 package types
 
-import "github.com/raiguard/luapls/lua/token"
+import (
+	"github.com/raiguard/luapls/lua/annotation"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
 
-// Named represents a named type, constructed with `@class`.
+// Named represents a named type, constructed with `@class` or `@alias`.
 type Named struct {
 	Name  string
+	URI   protocol.URI // The file that declares the type
 	Range token.Range
+
+	Fields  *Table   // The members of a class, including methods assigned to it in code
+	Parents []*Named // The classes that a class inherits from
+	Alias   Type     // The type that an alias stands for, or nil for a class
+
+	node     ast.Node // The statement that the first declaration is attached to
+	decls    []*classDecl
+	alias    annotation.TypeExpr
+	resolved bool
+}
+
+// classDecl is the set of annotations that declare a class in a single file.
+type classDecl struct {
+	uri     protocol.URI
+	parents []string
+	fields  []*annotation.Field
 }
 
 func (n *Named) isType() {}
@@ -13,3 +35,28 @@ func (n *Named) isType() {}
 func (n *Named) String() string {
 	return n.Name
 }
+
+// IsClass returns whether the type was declared with `@class`.
+func (n *Named) IsClass() bool {
+	return n.alias == nil
+}
+
+// inherits returns whether the class is, or inherits from, the class with the given name.
+func (n *Named) inherits(name string) bool {
+	return n.inheritsDepth(name, 0)
+}
+
+func (n *Named) inheritsDepth(name string, depth int) bool {
+	if n.Name == name {
+		return true
+	}
+	if depth >= maxIndexDepth {
+		return false
+	}
+	for _, parent := range n.Parents {
+		if parent.inheritsDepth(name, depth+1) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package types
+
+import (
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+)
+
+// flowState holds the type of every local variable at a single point in the program.
+type flowState struct {
+	vars map[*Variable]Type
+}
+
+func newFlowState() *flowState {
+	return &flowState{vars: map[*Variable]Type{}}
+}
+
+func (s *flowState) copy() *flowState {
+	vars := make(map[*Variable]Type, len(s.vars))
+	for v, typ := range s.vars {
+		vars[v] = typ
+	}
+	return &flowState{vars: vars}
+}
+
+// join merges the other state into this one, returning the result and whether it differs from this state. Either
+// state may be nil, which represents a path that can never be taken.
+func (s *flowState) join(other *flowState) (*flowState, bool) {
+	if other == nil {
+		return s, false
+	}
+	if s == nil {
+		return other.copy(), true
+	}
+	merged := s.copy()
+	changed := false
+	for v, typ := range other.vars {
+		existing, ok := merged.vars[v]
+		if !ok {
+			merged.vars[v] = typ
+			changed = true
+			continue
+		}
+		union := NewUnion(existing, typ)
+		if !Identical(union, existing) {
+			merged.vars[v] = union
+			changed = true
+		}
+	}
+	return merged, changed
+}
+
+// narrow returns a copy of the state with the type of the variable passed through the given function. If the
+// function returns nil, the narrowing is impossible and the result is nil.
+func (s *flowState) narrow(v *Variable, fn func(Type) Type) *flowState {
+	if s == nil {
+		return nil
+	}
+	typ := s.vars[v]
+	if typ == nil {
+		typ = &Unknown{}
+	}
+	narrowed := fn(typ)
+	if narrowed == nil {
+		return nil
+	}
+	result := s.copy()
+	result.vars[v] = narrowed
+	return result
+}
+
+// refine returns the state that results from the given condition evaluating to a truthy or falsy value, or nil if
+// the condition can never have that outcome.
+func (in *inferrer) refine(s *flowState, cond ast.Expression, truthy bool) *flowState {
+	if s == nil {
+		return nil
+	}
+	switch cond := cond.(type) {
+	case *ast.Identifier:
+		v := in.a.Bindings[cond]
+		if v == nil {
+			return s
+		}
+		if truthy {
+			return s.narrow(v, Truthy)
+		}
+		return s.narrow(v, Falsy)
+	case *ast.PrefixExpression:
+		if cond.Operator.Type() == token.NOT {
+			return in.refine(s, cond.Right, !truthy)
+		}
+	case *ast.InfixExpression:
+		switch cond.Operator.Type() {
+		case token.AND:
+			if truthy {
+				return in.refine(in.refine(s, cond.Left, true), cond.Right, true)
+			}
+			merged, _ := in.refine(s, cond.Left, false).join(in.refine(in.refine(s, cond.Left, true), cond.Right, false))
+			return merged
+		case token.OR:
+			if truthy {
+				merged, _ := in.refine(s, cond.Left, true).join(in.refine(in.refine(s, cond.Left, false), cond.Right, true))
+				return merged
+			}
+			return in.refine(in.refine(s, cond.Left, false), cond.Right, false)
+		case token.EQUAL:
+			return in.refineEquality(s, cond.Left, cond.Right, truthy)
+		case token.NEQ:
+			return in.refineEquality(s, cond.Left, cond.Right, !truthy)
+		}
+	}
+	return s
+}
+
+// refineEquality narrows `x == nil` and `type(x) == "name"` comparisons, in either order.
+func (in *inferrer) refineEquality(s *flowState, left, right ast.Expression, equal bool) *flowState {
+	if _, ok := left.(*ast.Identifier); !ok {
+		if _, ok := left.(*ast.FunctionCall); !ok {
+			left, right = right, left
+		}
+	}
+	switch left := left.(type) {
+	case *ast.Identifier:
+		v := in.a.Bindings[left]
+		if _, ok := right.(*ast.NilLiteral); !ok || v == nil {
+			return s
+		}
+		if equal {
+			return s.narrow(v, func(typ Type) Type { return filterUnion(typ, canBeNil) })
+		}
+		return s.narrow(v, Truthy)
+	case *ast.FunctionCall:
+		str, ok := right.(*ast.StringLiteral)
+		if !ok || !in.isGlobalCall(left, "type") || len(left.Args.Pairs) != 1 {
+			return s
+		}
+		arg, ok := left.Args.Pairs[0].Node.(*ast.Identifier)
+		if !ok || in.a.Bindings[arg] == nil {
+			return s
+		}
+		name := str.Value()
+		return s.narrow(in.a.Bindings[arg], func(typ Type) Type {
+			if equal {
+				return narrowToTypeName(typ, name)
+			}
+			return filterUnion(typ, func(member Type) bool { return TypeName(member) != name })
+		})
+	}
+	return s
+}
+
+func canBeNil(typ Type) bool {
+	switch typ.(type) {
+	case *Nil, *Any, *Unknown:
+		return true
+	}
+	return false
+}
+
+// narrowToTypeName returns the members of the type whose `type()` is the given name. If the type is not known
+// precisely, the result is the basic type with that name.
+func narrowToTypeName(typ Type, name string) Type {
+	if narrowed := filterUnion(typ, func(member Type) bool { return TypeName(member) == name }); narrowed != nil {
+		return narrowed
+	}
+	switch name {
+	case "boolean":
+		return &Boolean{}
+	case "nil":
+		return &Nil{}
+	case "number":
+		return &Number{}
+	case "string":
+		return &String{}
+	case "table":
+		return &Table{}
+	}
+	return nil
+}
+
+// TypeName returns the name that Lua's `type()` function returns for values of the given type, or an empty string
+// if it could be anything.
+func TypeName(typ Type) string {
+	switch typ := typ.(type) {
+	case *Boolean:
+		return "boolean"
+	case *Function:
+		return "function"
+	case *Nil:
+		return "nil"
+	case *Number:
+		return "number"
+	case *String:
+		return "string"
+	case *Named:
+		if typ.Alias != nil {
+			return TypeName(typ.Alias)
+		}
+		// Classes that inherit from `userdata` or `thread` describe values of those types
+		for _, name := range []string{"userdata", "thread"} {
+			if typ.inherits(name) {
+				return name
+			}
+		}
+		return "table"
+	case *Table:
+		return "table"
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNarrowing(t *testing.T) {
+	const decl = "local x = nil if cond then x = 'foo' end "
+	tests := []struct {
+		label    string
+		input    string
+		expected string
+	}{
+		{"merge", decl + "local r = x", "string|nil"},
+		{"truthy", decl + "if x then local r = x end", "string"},
+		{"falsy", decl + "if x then else local r = x end", "nil"},
+		{"not", decl + "if not x then local r = x end", "nil"},
+		{"not nil", decl + "if x ~= nil then local r = x end", "string"},
+		{"is nil", decl + "if x == nil then local r = x end", "nil"},
+		{"reversed nil", decl + "if nil ~= x then local r = x end", "string"},
+		{"and", decl + "local y = x if x and y then local r = x end", "string"},
+		{"or", decl + "if not x or cond then else local r = x end", "string"},
+		{"elseif", decl + "if cond then elseif x then local r = x end", "string"},
+		{"type", "local x = 1 if cond then x = 'foo' end if type(x) == 'string' then local r = x end", "string"},
+		{"not type", "local x = 1 if cond then x = 'foo' end if type(x) ~= 'string' then local r = x end", "number"},
+		{"type unknown", "local function f(x) if type(x) == 'number' then local r = x end end", "number"},
+		{"assert", decl + "assert(x) local r = x", "string"},
+		{"assert not nil", decl + "assert(x ~= nil, 'message') local r = x", "string"},
+		{"return guard", decl + "if not x then return end local r = x", "string"},
+		{"error guard", decl + "if x == nil then error('missing x') end local r = x", "string"},
+		{"guard in else", decl + "if x then local _ = 1 else return end local r = x", "string"},
+		{"assignment", "local x = 1 x = 'foo' local r = x", "string"},
+		{"assignment in branch", "local x = 1 if cond then x = 'foo' end local r = x", "string|number"},
+		{"loop", "local x = nil while cond do local r = x x = 1 end", "nil|number"},
+		{"loop exit", "local x = nil while not x do x = 1 end local r = x", "number"},
+		{"repeat", "local x = nil repeat x = 'foo' until cond local r = x", "string"},
+		{"break", "local x = nil for i = 1, 10 do x = i break end local r = x", "nil|number"},
+		{"closure", decl + "if x then local f = function() local r = x end end", "string"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			assert.Equal(t, test.expected, typeOfLocal(t, env, file, "r"))
+		})
+	}
+}
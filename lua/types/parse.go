@@ -0,0 +1,104 @@
+package types
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/parser"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// source is a file that has been found but not parsed yet.
+type source struct {
+	uri     protocol.URI
+	path    string  // The path to read the file from if content is nil
+	content *string // The contents of the file, if they are already known
+	library bool
+}
+
+// parsed is the result of reading a source.
+type parsed struct {
+	file    *ast.File // Nil if the summary was found in the cache instead
+	hash    string    // The hash of the contents of the file
+	summary *Summary
+}
+
+// parseSources reads and parses the sources using one worker per CPU, and returns the results in the same order as
+// the sources. If cache is not nil, sources whose summaries are in it are not parsed. Sources that could not be read
+// are zero in the result, and their errors are returned. Once ctx is cancelled the remaining sources are skipped and
+// are zero as well.
+//
+// If progress is not nil, it is called with the number of sources that have been parsed after each one. Calls are
+// never concurrent.
+func parseSources(ctx context.Context, sources []source, cache Cache, progress func(parsed int)) ([]parsed, []error) {
+	results := make([]parsed, len(sources))
+	errs := make([]error, len(sources))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	workers := min(runtime.GOMAXPROCS(0), len(sources))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = parseSource(sources[i], cache)
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range sources {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := []error{}
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return results, failed
+}
+
+func parseSource(src source, cache Cache) (parsed, error) {
+	content := src.content
+	if content == nil {
+		data, err := os.ReadFile(src.path)
+		if err != nil {
+			return parsed{}, err
+		}
+		text := string(data)
+		content = &text
+	}
+	hash := hashContent(*content)
+	if cache != nil && src.path != "" {
+		if summary := cache.Load(src.path, hash); summary != nil {
+			return parsed{hash: hash, summary: summary}, nil
+		}
+	}
+	file := parser.New(*content).ParseFile()
+	file.URI = src.uri
+	return parsed{file: &file, hash: hash}, nil
+}
+
+// hashContent returns the hash that identifies the contents of a file in the cache.
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
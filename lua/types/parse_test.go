@@ -0,0 +1,195 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/raiguard/luapls/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSources(t *testing.T) {
+	first, second := "local a = 1", "local b = "
+	sources := []source{
+		{uri: "file:///a.lua", content: &first},
+		{uri: "file:///missing.lua", path: filepath.Join(t.TempDir(), "missing.lua")},
+		{uri: "file:///b.lua", content: &second},
+	}
+	results, errs := parseSources(context.Background(), sources, nil, nil)
+	require.Len(t, results, 3)
+	assert.Equal(t, "file:///a.lua", results[0].file.URI)
+	assert.Empty(t, results[0].file.Diagnostics)
+	assert.Equal(t, hashContent(first), results[0].hash)
+	assert.Nil(t, results[1].file)
+	assert.Equal(t, "file:///b.lua", results[2].file.URI)
+	assert.NotEmpty(t, results[2].file.Diagnostics)
+	assert.Len(t, errs, 1)
+}
+
+func TestUpdateFile(t *testing.T) {
+	env := workspace(t, map[string]string{
+		"main.lua": "local x = require('lib')",
+		"lib.lua":  "return 1",
+	})
+	lib := workspaceFile(t, env, "lib.lua")
+	main := workspaceFile(t, env, "main.lua")
+
+	affected := env.UpdateFile(lib.URI, "return 'changed'")
+	updated := env.File(lib.URI)
+	assert.NotSame(t, lib, updated)
+	assert.ElementsMatch(t, []string{lib.URI, main.URI}, []string{affected[0].URI, affected[1].URI})
+	assert.Equal(t, "string", typeOfLocal(t, env, main, "x"))
+	// Requests that obtained the file before it was replaced still see its previous contents
+	assert.NotEmpty(t, env.Analysis(lib).Types)
+	assert.Same(t, lib, env.Analysis(lib).File)
+
+	assert.Nil(t, env.UpdateFile("file:///missing.lua", "return 1"))
+}
+
+// TestConcurrentAccess is meant to be run with the race detector.
+func TestConcurrentAccess(t *testing.T) {
+	env := workspace(t, map[string]string{
+		"main.lua": "local lib = require('lib')\nprint(lib.value)",
+		"lib.lua":  "return { value = 1 }",
+	})
+	lib := workspaceFile(t, env, "lib.lua")
+	main := workspaceFile(t, env, "main.lua")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				env.UpdateFile(lib.URI, fmt.Sprintf("return { value = %d }", i*j))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				for _, file := range env.FileList() {
+					env.Report(file)
+				}
+				env.Analysis(main)
+				env.Dependents(lib.URI)
+				env.IsStdlibGlobal("print")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Empty(t, env.Report(env.File(main.URI)))
+}
+
+// BenchmarkInit initializes a workspace of many files, once with a single worker and once with one worker per CPU.
+func BenchmarkInit(b *testing.B) {
+	root := benchmarkWorkspace(b)
+	benchmarkWorkers(b, func() {
+		env := NewEnvironment()
+		env.RootPath = root
+		env.Init()
+	})
+}
+
+// BenchmarkParseSources only reads and parses the files of the workspace of BenchmarkInit, which is the part of
+// initialization that is done in parallel.
+func BenchmarkParseSources(b *testing.B) {
+	root := benchmarkWorkspace(b)
+	sources := NewEnvironment().newDiscovery().directorySources(root)
+	require.Len(b, sources, 500)
+	benchmarkWorkers(b, func() {
+		parseSources(context.Background(), sources, nil, nil)
+	})
+}
+
+// benchmarkWorkspace writes a workspace of many files to a temporary directory and returns its path.
+func benchmarkWorkspace(b *testing.B) string {
+	files := map[string]string{}
+	for i := 0; i < 500; i++ {
+		files[fmt.Sprintf("module%d.lua", i)] = fmt.Sprintf(benchmarkModule, i)
+	}
+	return writeFiles(b, files)
+}
+
+// benchmarkWorkers runs the benchmark once with a single worker and once with one worker per CPU.
+func benchmarkWorkers(b *testing.B, fn func()) {
+	for _, bench := range []struct {
+		name    string
+		workers int
+	}{{"sequential", 1}, {"parallel", runtime.GOMAXPROCS(0)}} {
+		workers := bench.workers
+		b.Run(bench.name, func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(workers))
+			for i := 0; i < b.N; i++ {
+				fn()
+			}
+		})
+	}
+}
+
+const benchmarkModule = `
+--- @class Module%[1]d
+local M = {}
+
+--- @param a number
+--- @param b number
+--- @return number
+function M.add(a, b)
+	local sum = a + b
+	for i = 1, 10 do
+		sum = sum + i
+	end
+	return sum
+end
+
+--- @param items string[]
+--- @return string
+function M.join(items)
+	local result = ""
+	for _, item in ipairs(items) do
+		if #result > 0 then
+			result = result .. ", " .. item
+		else
+			result = item
+		end
+	end
+	return result
+end
+
+return M
+`
+
+func TestIndex(t *testing.T) {
+	env := NewEnvironment()
+	env.RootPath = writeFiles(t, map[string]string{
+		"a.lua": "local a = 1",
+		"b.lua": "local b = 1",
+		"c.lua": "local c = 1",
+	})
+	// Files that are added while indexing, such as open files, take precedence over the files on disk
+	uri, err := util.PathToURI(filepath.Join(env.RootPath, "a.lua"))
+	require.NoError(t, err)
+	open := env.AddTransientFile(uri, "local a = 'open'")
+
+	reports := [][2]int{}
+	require.NoError(t, env.Index(context.Background(), func(parsed, total int) {
+		reports = append(reports, [2]int{parsed, total})
+	}))
+	assert.Equal(t, [][2]int{{1, 2}, {2, 2}}, reports)
+	assert.Same(t, open, env.File(uri))
+	assert.Equal(t, "string", typeOfLocal(t, env, open, "a"))
+	workspaceFile(t, env, "c.lua")
+}
+
+func TestIndexCancelled(t *testing.T) {
+	env := NewEnvironment()
+	env.RootPath = writeFiles(t, map[string]string{"a.lua": "local a = 1"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, env.Index(ctx, nil), context.Canceled)
+	assert.Empty(t, discoveredFiles(t, env))
+}
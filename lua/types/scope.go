@@ -0,0 +1,280 @@
+package types
+
+import (
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+)
+
+// VariableKind describes how a variable was introduced.
+type VariableKind int
+
+const (
+	KindLocal VariableKind = iota
+	KindParameter
+)
+
+// Variable is a single declaration of a local variable or function parameter.
+type Variable struct {
+	Name  string
+	Kind  VariableKind
+	Def   *ast.Identifier // nil for the implicit `self` parameter
+	Decl  ast.Node        // The statement or expression that introduced the variable
+	Scope *Scope
+	Refs  []*ast.Identifier // Every identifier, other than Def, that refers to this variable
+}
+
+// Scope is a lexical scope in which local variables may be declared.
+type Scope struct {
+	Parent *Scope
+	Node   ast.Node
+	Vars   map[string]*Variable
+}
+
+func (s *Scope) lookup(name string) *Variable {
+	for scope := s; scope != nil; scope = scope.Parent {
+		if v := scope.Vars[name]; v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// Global is a global variable that is assigned somewhere in the environment.
+type Global struct {
+	Name string
+	Defs []GlobalDef
+}
+
+// GlobalDef is a single assignment to a global variable.
+type GlobalDef struct {
+	File  *ast.File
+	Ident *ast.Identifier
+}
+
+// binder resolves every identifier in a file to the variable that it refers to.
+type binder struct {
+	env   *Environment
+	a     *Analysis
+	scope *Scope
+}
+
+func (b *binder) pushScope(node ast.Node) {
+	b.scope = &Scope{Parent: b.scope, Node: node, Vars: map[string]*Variable{}}
+}
+
+func (b *binder) popScope() {
+	b.scope = b.scope.Parent
+}
+
+func (b *binder) declare(ident *ast.Identifier, kind VariableKind, decl ast.Node) {
+	if ident == nil || ident.Token.Type != token.IDENT {
+		return
+	}
+	v := &Variable{Name: ident.Token.Literal, Kind: kind, Def: ident, Decl: decl, Scope: b.scope}
+	if prev := b.scope.lookup(v.Name); prev != nil {
+		b.a.shadows[v] = prev
+	}
+	b.scope.Vars[v.Name] = v
+	b.a.Variables = append(b.a.Variables, v)
+	b.a.Bindings[ident] = v
+}
+
+func (b *binder) declareSelf(decl ast.Node) {
+	v := &Variable{Name: "self", Kind: KindParameter, Decl: decl, Scope: b.scope}
+	b.scope.Vars[v.Name] = v
+	b.a.Variables = append(b.a.Variables, v)
+	b.a.SelfParams[decl] = v
+}
+
+func (b *binder) reference(ident *ast.Identifier, write bool) {
+	if ident == nil || ident.Token.Type != token.IDENT {
+		return
+	}
+	if write {
+		b.a.writes[ident] = true
+	}
+	if v := b.scope.lookup(ident.Token.Literal); v != nil {
+		v.Refs = append(v.Refs, ident)
+		b.a.Bindings[ident] = v
+		return
+	}
+	b.a.Globals = append(b.a.Globals, ident)
+	if write {
+		b.env.addGlobalDef(ident.Token.Literal, GlobalDef{File: b.a.File, Ident: ident})
+	}
+}
+
+func (b *binder) block(block *ast.Block, node ast.Node) {
+	b.pushScope(node)
+	b.statements(block)
+	b.popScope()
+}
+
+func (b *binder) statements(block *ast.Block) {
+	for _, pair := range block.Pairs {
+		b.statement(pair.Node)
+	}
+}
+
+func (b *binder) statement(stmt ast.Statement) {
+	if b.env.isExcluded(b.a.File, stmt) {
+		return
+	}
+	b.annotate(stmt)
+	switch stmt := stmt.(type) {
+	case *ast.AssignmentStatement:
+		b.expressions(&stmt.Exps)
+		for _, pair := range stmt.Vars.Pairs {
+			if ident, ok := pair.Node.(*ast.Identifier); ok {
+				b.reference(ident, true)
+			} else {
+				b.expression(pair.Node)
+			}
+		}
+	case *ast.DoStatement:
+		b.block(&stmt.Body, stmt)
+	case *ast.ForStatement:
+		b.expression(stmt.Start.Node)
+		b.expression(stmt.Finish.Node)
+		if stmt.Step != nil {
+			b.expression(stmt.Step.Node)
+		}
+		b.pushScope(stmt)
+		b.declare(stmt.Name, KindLocal, stmt)
+		b.block(&stmt.Body, stmt)
+		b.popScope()
+	case *ast.ForInStatement:
+		b.expressions(&stmt.Exps)
+		b.pushScope(stmt)
+		for _, pair := range stmt.Names.Pairs {
+			b.declare(pair.Node, KindLocal, stmt)
+		}
+		b.block(&stmt.Body, stmt)
+		b.popScope()
+	case *ast.FunctionCall:
+		b.expression(stmt)
+	case *ast.FunctionStatement:
+		isMethod := false
+		switch name := stmt.Name.(type) {
+		case *ast.Identifier:
+			if stmt.LocalTok != nil {
+				b.declare(name, KindLocal, stmt)
+			} else {
+				b.reference(name, true)
+			}
+		case *ast.IndexExpression:
+			b.expression(name)
+			isMethod = name.LeftIndexer.Type() == token.COLON
+		}
+		b.function(stmt, &stmt.Params, &stmt.Body, isMethod)
+	case *ast.IfStatement:
+		for _, clause := range stmt.Clauses {
+			if clause.Condition != nil {
+				b.expression(clause.Condition)
+			}
+			b.block(&clause.Body, clause)
+		}
+	case *ast.LocalStatement:
+		if stmt.Exps != nil {
+			b.expressions(stmt.Exps)
+		}
+		for _, pair := range stmt.Names.Pairs {
+			b.declare(pair.Node, KindLocal, stmt)
+		}
+	case *ast.RepeatStatement:
+		// The condition of a repeat statement can see the locals declared in its body
+		b.pushScope(stmt)
+		b.statements(&stmt.Body)
+		b.expression(stmt.Condition)
+		b.popScope()
+	case *ast.ReturnStatement:
+		if stmt.Exps != nil {
+			b.expressions(stmt.Exps)
+		}
+	case *ast.WhileStatement:
+		b.expression(stmt.Condition)
+		b.block(&stmt.Body, stmt)
+	}
+}
+
+// annotate records the annotations of the given statement. Annotations written above a local or assignment
+// statement also describe the function expressions that it assigns.
+func (b *binder) annotate(stmt ast.Statement) {
+	annotations := b.env.nodeAnnotations(b.a.File, stmt)
+	if len(annotations) == 0 {
+		return
+	}
+	b.a.annotations[stmt] = annotations
+	var exps *ast.Punctuated[ast.Expression]
+	switch stmt := stmt.(type) {
+	case *ast.AssignmentStatement:
+		exps = &stmt.Exps
+	case *ast.LocalStatement:
+		exps = stmt.Exps
+	case *ast.ReturnStatement:
+		exps = stmt.Exps
+	}
+	if exps == nil {
+		return
+	}
+	for _, pair := range exps.Pairs {
+		if fn, ok := pair.Node.(*ast.FunctionExpression); ok {
+			b.a.annotations[fn] = annotations
+		}
+	}
+}
+
+func (b *binder) function(node ast.Node, params *ast.Punctuated[*ast.Identifier], body *ast.Block, isMethod bool) {
+	b.pushScope(node)
+	if isMethod {
+		b.declareSelf(node)
+	}
+	for _, pair := range params.Pairs {
+		b.declare(pair.Node, KindParameter, node)
+	}
+	b.statements(body)
+	b.popScope()
+}
+
+func (b *binder) expressions(exps *ast.Punctuated[ast.Expression]) {
+	for _, pair := range exps.Pairs {
+		b.expression(pair.Node)
+	}
+}
+
+func (b *binder) expression(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.FunctionCall:
+		if ident, ok := expr.Name.(*ast.Identifier); ok {
+			b.a.calls[ident] = expr
+		}
+		b.expression(expr.Name)
+		b.expressions(&expr.Args)
+	case *ast.FunctionExpression:
+		b.function(expr, &expr.Params, &expr.Body, false)
+	case *ast.Identifier:
+		b.reference(expr, false)
+	case *ast.IndexExpression:
+		b.expression(expr.Prefix)
+		if expr.LeftIndexer.Type() == token.LBRACK {
+			b.expression(expr.Inner)
+		}
+	case *ast.InfixExpression:
+		b.expression(expr.Left)
+		b.expression(expr.Right)
+	case *ast.PrefixExpression:
+		b.expression(expr.Right)
+	case *ast.TableLiteral:
+		for _, pair := range expr.Fields.Pairs {
+			switch field := pair.Node.(type) {
+			case *ast.TableArrayField:
+				b.expression(field.Expr)
+			case *ast.TableExpressionKeyField:
+				b.expression(field.Name)
+				b.expression(field.Expr)
+			case *ast.TableSimpleKeyField:
+				b.expression(field.Expr)
+			}
+		}
+	}
+}
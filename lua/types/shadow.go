@@ -0,0 +1,55 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Diagnostic codes reported for shadowed and redefined locals.
+const (
+	CodeRedefinedLocalFunction = "redefined-local-function"
+	CodeShadowedLocal          = "shadowed-local"
+)
+
+// checkShadowing reports locals that hide other variables and local functions that are defined twice. Names that
+// start with `_` are never reported.
+func (e *Environment) checkShadowing(a *Analysis) []ast.Diagnostic {
+	if e.isLibrary(a.File.URI) || a.File.Block == nil {
+		return nil
+	}
+	var diags diagnosticList
+	for _, v := range a.Variables {
+		prev := a.shadows[v]
+		if prev == nil || v.Def == nil || strings.HasPrefix(v.Name, "_") {
+			continue
+		}
+		switch {
+		case prev.Scope == v.Scope && prev.Kind == KindLocal:
+			// Redeclaring a local in the same scope is a common idiom, but defining a local function twice is not
+			if isLocalFunction(v) && isLocalFunction(prev) {
+				message := fmt.Sprintf("Local function '%s' is already defined on line %d", v.Name, a.line(prev))
+				diags.add(ast.Range(v.Def), protocol.DiagnosticSeverityWarning, CodeRedefinedLocalFunction, message)
+			}
+		default:
+			kind := "local"
+			if prev.Kind == KindParameter {
+				kind = "parameter"
+			}
+			message := fmt.Sprintf("Local '%s' shadows the %s on line %d", v.Name, kind, a.line(prev))
+			diags.add(ast.Range(v.Def), protocol.DiagnosticSeverityWarning, CodeShadowedLocal, message)
+		}
+	}
+	return diags
+}
+
+// line returns the one-based line number that the variable is declared on.
+func (a *Analysis) line(v *Variable) int {
+	var node ast.Node = v.Def
+	if node == nil {
+		node = v.Decl
+	}
+	return int(a.File.LineBreaks.ToProtocolPos(node.Pos()).Line) + 1
+}
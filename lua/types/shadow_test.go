@@ -0,0 +1,63 @@
+package types
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestShadowing(t *testing.T) {
+	codes := []string{CodeRedefinedLocalFunction, CodeShadowedLocal}
+	tests := []struct {
+		label    string
+		input    string
+		expected []string
+	}{
+		{"unrelated", "local x = 1 do local y = x print(y) end", nil},
+		{"outer local", "local x = 1 do local x = 2 print(x) end print(x)", []string{"Local 'x' shadows the local on line 1"}},
+		{"parameter", "local function f(a)\nlocal a = 1 return a end return f", []string{"Local 'a' shadows the parameter on line 1"}},
+		{"loop variable", "for i = 1, 2 do for i = 1, 2 do print(i) end end", []string{"Local 'i' shadows the local on line 1"}},
+		{"redeclared in same scope", "local x = 1 print(x) local x = 2 print(x)", nil},
+		{"declared later", "do local x = 1 print(x) end local x = 2 print(x)", nil},
+		{"underscore", "for _ in pairs({}) do for _ in pairs({}) do end end", nil},
+		{"local function", "local function f() end\nlocal function f() end return f", []string{"Local function 'f' is already defined on line 1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			shadowing := []string{}
+			for _, diag := range env.Diagnostics(file) {
+				if slices.Contains(codes, diag.Code) {
+					shadowing = append(shadowing, diag.Message)
+				}
+			}
+			if test.expected == nil {
+				test.expected = []string{}
+			}
+			assert.Equal(t, test.expected, shadowing)
+		})
+	}
+}
+
+func TestSeverities(t *testing.T) {
+	env, file := analyze(t, "local x = 1 do local x = 2 print(x) end print(x) local function f() end print(f) local function f() end return f")
+	env.Severities = map[string]protocol.DiagnosticSeverity{
+		CodeShadowedLocal:          protocol.DiagnosticSeverityError,
+		CodeRedefinedLocalFunction: SeverityOff,
+	}
+	diagnostics := env.Report(file)
+	if assert.Len(t, diagnostics, 1) {
+		assert.Equal(t, CodeShadowedLocal, diagnostics[0].Code)
+		assert.Equal(t, protocol.DiagnosticSeverityError, diagnostics[0].Severity)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	severity, ok := ParseSeverity("Hint")
+	assert.True(t, ok)
+	assert.Equal(t, protocol.DiagnosticSeverityHint, severity)
+	_, ok = ParseSeverity("fatal")
+	assert.False(t, ok)
+}
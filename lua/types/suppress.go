@@ -0,0 +1,161 @@
+package types
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/annotation"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// CodeUnusedSuppression is reported for `---@diagnostic` comments that do not suppress anything.
+const CodeUnusedSuppression = "unused-suppression"
+
+// SeverityOff is used in Environment.Severities to hide every diagnostic with a code.
+const SeverityOff protocol.DiagnosticSeverity = 0
+
+// severityNames are the names that ParseSeverity accepts.
+var severityNames = map[string]protocol.DiagnosticSeverity{
+	"error":       protocol.DiagnosticSeverityError,
+	"warning":     protocol.DiagnosticSeverityWarning,
+	"information": protocol.DiagnosticSeverityInformation,
+	"hint":        protocol.DiagnosticSeverityHint,
+	"off":         SeverityOff,
+}
+
+// ParseSeverity returns the severity with the given name: one of `error`, `warning`, `information`, `hint` or `off`.
+func ParseSeverity(name string) (protocol.DiagnosticSeverity, bool) {
+	severity, ok := severityNames[strings.ToLower(name)]
+	return severity, ok
+}
+
+// suppression is a single `---@diagnostic` comment.
+type suppression struct {
+	action string
+	codes  []string // Empty to suppress every diagnostic that has a code
+	rng    token.Range
+	line   int
+	end    int // For `disable`, the line of the `enable` comment that ends the region, or -1
+	used   bool
+}
+
+func newSuppression(file *ast.File, comment token.Token, d *annotation.Diagnostic) *suppression {
+	return &suppression{
+		action: d.Action,
+		codes:  d.Codes,
+		rng:    comment.Range(),
+		line:   int(file.LineBreaks.ToProtocolPos(comment.Pos).Line),
+		end:    -1,
+	}
+}
+
+// matches returns whether the suppression applies to a diagnostic with the given code on the given line.
+func (s *suppression) matches(code string, line int) bool {
+	if code == "" || (len(s.codes) > 0 && !slices.Contains(s.codes, code)) {
+		return false
+	}
+	switch s.action {
+	case "disable-next-line":
+		return line == s.line+1
+	case "disable-line":
+		return line == s.line
+	case "disable":
+		return line >= s.line && (s.end < 0 || line < s.end)
+	}
+	return false
+}
+
+// closedBy returns whether an `enable` comment with the given codes ends this `disable` region. A region ends at the
+// next `enable` that lists no codes, or one of the codes that the region disabled.
+func (s *suppression) closedBy(codes []string) bool {
+	if len(codes) == 0 || len(s.codes) == 0 {
+		return true
+	}
+	for _, code := range codes {
+		if slices.Contains(s.codes, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Suppress removes the diagnostics that are disabled by `---@diagnostic` comments in the file, and reports the
+// comments that did not suppress anything. Diagnostics without a code, such as syntax errors, are never suppressed.
+func (e *Environment) Suppress(file *ast.File, diagnostics []ast.Diagnostic) []ast.Diagnostic {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.suppress(file, diagnostics)
+}
+
+func (e *Environment) suppress(file *ast.File, diagnostics []ast.Diagnostic) []ast.Diagnostic {
+	fa := e.annotations[file.URI]
+	if fa == nil || len(fa.suppressions) == 0 {
+		return diagnostics
+	}
+	for i, s := range fa.suppressions {
+		s.used = false
+		if s.action != "disable" {
+			continue
+		}
+		s.end = -1
+		for _, next := range fa.suppressions[i+1:] {
+			if next.action == "enable" && s.closedBy(next.codes) {
+				s.end = next.line
+				break
+			}
+		}
+	}
+	filtered := []ast.Diagnostic{}
+	for _, diag := range diagnostics {
+		line := int(file.LineBreaks.ToProtocolPos(diag.Range.Start).Line)
+		suppressed := false
+		for _, s := range fa.suppressions {
+			if s.matches(diag.Code, line) {
+				s.used = true
+				suppressed = true
+			}
+		}
+		if !suppressed {
+			filtered = append(filtered, diag)
+		}
+	}
+	for _, s := range fa.suppressions {
+		if !s.used && s.action != "enable" && slices.Contains(annotation.DiagnosticActions, s.action) {
+			filtered = append(filtered, ast.Diagnostic{
+				Message:  "Unused diagnostic suppression",
+				Range:    s.rng,
+				Severity: protocol.DiagnosticSeverityWarning,
+				Code:     CodeUnusedSuppression,
+			})
+		}
+	}
+	return filtered
+}
+
+// Report returns the diagnostics of the file that should be shown to the user, with suppression comments and the
+// configured severities applied.
+func (e *Environment) Report(file *ast.File) []ast.Diagnostic {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.report(file)
+}
+
+func (e *Environment) report(file *ast.File) []ast.Diagnostic {
+	diagnostics := e.suppress(file, e.diagnostics(file))
+	if len(e.Severities) == 0 {
+		return diagnostics
+	}
+	filtered := []ast.Diagnostic{}
+	for _, diag := range diagnostics {
+		if severity, ok := e.Severities[diag.Code]; ok && diag.Code != "" {
+			if severity == SeverityOff {
+				continue
+			}
+			diag.Severity = severity
+		}
+		filtered = append(filtered, diag)
+	}
+	return filtered
+}
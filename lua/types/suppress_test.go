@@ -0,0 +1,38 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuppress(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected []string
+	}{
+		{"none", "print(a)", []string{"Undefined global 'a'"}},
+		{"next line", "---@diagnostic disable-next-line: undefined-global\nprint(a)\nprint(b)", []string{"Undefined global 'b'"}},
+		{"same line", "print(a) ---@diagnostic disable-line: undefined-global\nprint(b)", []string{"Undefined global 'b'"}},
+		{"other code", "---@diagnostic disable-next-line: unknown-field\nprint(a)", []string{"Undefined global 'a'", "Unused diagnostic suppression"}},
+		{"all codes", "---@diagnostic disable-next-line\nprint(a)", []string{}},
+		{"region", "print(a)\n---@diagnostic disable: undefined-global\nprint(b)\n---@diagnostic enable: undefined-global\nprint(c)", []string{"Undefined global 'a'", "Undefined global 'c'"}},
+		{"file", "---@diagnostic disable: undefined-global\nprint(a)\nprint(b)", []string{}},
+		{"unused region", "---@diagnostic disable: undefined-global\nlocal x = 1\n---@diagnostic enable", []string{"Unused diagnostic suppression"}},
+		{"trailing", "print(a)\n---@diagnostic disable-line: undefined-global", []string{"Undefined global 'a'", "Unused diagnostic suppression"}},
+		{"syntax errors", "---@diagnostic disable\nlocal = 1", []string{"Unused diagnostic suppression"}},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			messages := []string{}
+			for _, diag := range env.Suppress(file, env.Diagnostics(file)) {
+				if diag.Code != "" && !isUnnecessary(diag) {
+					messages = append(messages, diag.Message)
+				}
+			}
+			assert.Equal(t, test.expected, messages)
+		})
+	}
+}
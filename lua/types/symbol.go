@@ -0,0 +1,359 @@
+package types
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/annotation"
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/token"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// SymbolKind describes what a name refers to.
+type SymbolKind int
+
+const (
+	SymbolLocal SymbolKind = iota
+	SymbolParameter
+	SymbolUpvalue // A local variable of an enclosing function
+	SymbolGlobal
+	SymbolField
+	SymbolMethod
+	SymbolClass
+	SymbolAlias
+	SymbolModule   // The name of a module passed to `require`
+	SymbolOperator // An operator that is overridden by a metamethod
+)
+
+var symbolKindNames = []string{"local", "parameter", "upvalue", "global", "field", "method", "class", "alias", "module", "operator"}
+
+func (k SymbolKind) String() string {
+	return symbolKindNames[k]
+}
+
+// Symbol describes the declaration that a name in a file refers to.
+type Symbol struct {
+	Kind   SymbolKind
+	Name   string // For operators, the name of the metamethod
+	Owner  string // For fields and methods, the name of the class or table that they belong to, if it is known
+	Type   Type
+	Range  token.Range  // The range of the name in the file
+	Doc    *Doc         // nil if the declaration is not documented
+	Module protocol.URI // For modules, the file that is loaded, or empty if it could not be resolved
+}
+
+// Doc is the documentation of a declaration, written in the comments above it.
+type Doc struct {
+	Description string
+	Deprecated  *annotation.Deprecated // nil if the declaration is not deprecated
+}
+
+// newDoc creates the documentation from the description lines and annotations of a declaration. It returns nil if
+// there is nothing to document.
+func newDoc(description []string, annotations []annotation.Annotation) *Doc {
+	doc := &Doc{Description: strings.TrimSpace(strings.Join(description, "\n"))}
+	for _, a := range annotations {
+		if deprecated, ok := a.(*annotation.Deprecated); ok {
+			doc.Deprecated = deprecated
+		}
+	}
+	if doc.Description == "" && doc.Deprecated == nil {
+		return nil
+	}
+	return doc
+}
+
+// SymbolAt returns the symbol at the given position in the file, or nil if there is none. Besides names in code,
+// this includes the names of classes and aliases in annotations, module names passed to `require`, and operators
+// that are overridden by metamethods.
+func (e *Environment) SymbolAt(file *ast.File, pos token.Pos) *Symbol {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.symbolAt(file, pos)
+}
+
+func (e *Environment) symbolAt(file *ast.File, pos token.Pos) *Symbol {
+	if file.Block == nil {
+		return nil
+	}
+	a := e.analysis(file)
+	file = a.File
+	for _, comment := range file.Comments {
+		if comment.Pos <= pos && pos < comment.End() {
+			return e.annotationSymbol(comment, pos)
+		}
+	}
+	path := ast.GetSemanticNode(file.Block, pos)
+	switch node := path.Node.(type) {
+	case *ast.Identifier:
+		return e.identifierSymbol(a, path, node)
+	case *ast.InfixExpression:
+		if node.Operator.Pos() <= pos && pos < node.Operator.End() {
+			left, right := First(a.TypeOf(node.Left)), First(a.TypeOf(node.Right))
+			return e.operatorSymbol(&node.Operator, metamethodEvents[node.Operator.Type()], left, right)
+		}
+	case *ast.PrefixExpression:
+		if node.Operator.Pos() <= pos && pos < node.Operator.End() {
+			return e.operatorSymbol(&node.Operator, prefixMetamethodEvents[node.Operator.Type()], First(a.TypeOf(node.Right)))
+		}
+	case *ast.StringLiteral:
+		for _, req := range a.Requires {
+			if req.Arg == node {
+				return &Symbol{Kind: SymbolModule, Name: req.Module, Type: a.TypeOf(req.Call), Range: ast.Range(node), Module: req.URI}
+			}
+		}
+	}
+	return nil
+}
+
+// identifierSymbol returns the symbol that the identifier at the end of the path refers to.
+func (e *Environment) identifierSymbol(a *Analysis, path ast.NodePath, ident *ast.Identifier) *Symbol {
+	name := ident.Token.Literal
+	symbol := &Symbol{Name: name, Type: a.TypeOf(ident), Range: ast.Range(ident)}
+	var parent ast.Node
+	if len(path.Parents) > 0 {
+		parent = path.Parents[len(path.Parents)-1]
+	}
+	switch parent := parent.(type) {
+	case *ast.IndexExpression:
+		if parent.Inner == ident && parent.LeftIndexer.Type() != token.LBRACK {
+			e.fieldSymbol(a, symbol, parent)
+			return symbol
+		}
+	case *ast.TableSimpleKeyField:
+		if &parent.Name == ident {
+			symbol.Kind = SymbolField
+			return symbol
+		}
+	}
+
+	v := a.Bindings[ident]
+	if v == nil {
+		symbol.Kind = SymbolGlobal
+		symbol.Doc = e.globalDoc(name)
+		return symbol
+	}
+	switch {
+	case v.Def != ident && isUpvalue(v, path):
+		symbol.Kind = SymbolUpvalue
+	case v.Kind == KindParameter:
+		symbol.Kind = SymbolParameter
+	default:
+		symbol.Kind = SymbolLocal
+	}
+	if v.Kind == KindParameter {
+		symbol.Doc = paramDoc(a, v)
+	} else {
+		symbol.Doc = e.nodeDoc(a.File, v.Decl)
+	}
+	return symbol
+}
+
+// fieldSymbol fills out the symbol of the field that the index expression refers to.
+func (e *Environment) fieldSymbol(a *Analysis, symbol *Symbol, ie *ast.IndexExpression) {
+	symbol.Kind = SymbolField
+	prefix := First(a.TypeOf(ie.Prefix))
+	if _, ok := prefix.(*String); ok {
+		prefix = e.globalType("string")
+	}
+	field, class := fieldDecl(prefix, symbol.Name)
+	if ie.LeftIndexer.Type() == token.COLON || isMethod(symbol.Type) {
+		symbol.Kind = SymbolMethod
+	}
+	if class != nil {
+		symbol.Owner = class.Name
+	} else {
+		symbol.Owner = expressionName(ie.Prefix)
+	}
+	if field != nil && field.Def != nil {
+		if file, stmt := e.declaringStatement(a.File, field.Def); stmt != nil {
+			symbol.Doc = e.nodeDoc(file, stmt)
+		}
+	}
+	if symbol.Doc == nil && class != nil {
+		symbol.Doc = classFieldDoc(class, symbol.Name)
+	}
+}
+
+// operatorSymbol returns the symbol of an operator if one of its operands overrides it with a metamethod.
+func (e *Environment) operatorSymbol(op *ast.Unit, event string, operands ...Type) *Symbol {
+	if event == "" {
+		return nil
+	}
+	for _, operand := range operands {
+		field := metamethodField(operand, event)
+		if field == nil {
+			continue
+		}
+		symbol := &Symbol{Kind: SymbolOperator, Name: event, Type: First(field.Type), Range: op.Range()}
+		if field.Def != nil {
+			if file, stmt := e.declaringStatement(nil, field.Def); stmt != nil {
+				symbol.Doc = e.nodeDoc(file, stmt)
+			}
+		}
+		return symbol
+	}
+	return nil
+}
+
+// annotationSymbol returns the symbol of the class or alias whose name is at the given position in a comment.
+func (e *Environment) annotationSymbol(comment token.Token, pos token.Pos) *Symbol {
+	if !strings.HasPrefix(comment.Literal, "---") {
+		return nil
+	}
+	offset := pos - comment.Pos
+	start, end := offset, offset
+	for start > 0 && isTypeNameChar(comment.Literal[start-1]) {
+		start--
+	}
+	for end < len(comment.Literal) && isTypeNameChar(comment.Literal[end]) {
+		end++
+	}
+	if start == end || start > 0 && comment.Literal[start-1] == '@' {
+		return nil
+	}
+	name := comment.Literal[start:end]
+	e.loadPendingType(name)
+	named, ok := e.Types[name].(*Named)
+	if !ok {
+		return nil
+	}
+	e.resolveNamed(named)
+	symbol := &Symbol{Kind: SymbolClass, Name: name, Type: named, Range: token.Range{Start: comment.Pos + start, End: comment.Pos + end}}
+	if !named.IsClass() {
+		symbol.Kind = SymbolAlias
+	}
+	if file := e.Files[named.URI]; file != nil && named.node != nil {
+		symbol.Doc = e.nodeDoc(file, named.node)
+	}
+	return symbol
+}
+
+func isTypeNameChar(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// globalDoc returns the documentation of the first definition of the global that is documented.
+func (e *Environment) globalDoc(name string) *Doc {
+	e.loadPendingGlobal(name)
+	global := e.Globals[name]
+	if global == nil {
+		return nil
+	}
+	for _, def := range global.Defs {
+		if stmt := statementOf(def.File, def.Ident); stmt != nil {
+			if doc := e.nodeDoc(def.File, stmt); doc != nil {
+				return doc
+			}
+		}
+	}
+	return nil
+}
+
+// paramDoc returns the description of a parameter from the `@param` annotation of its function.
+func paramDoc(a *Analysis, v *Variable) *Doc {
+	for _, ann := range a.annotations[v.Decl] {
+		if param, ok := ann.(*annotation.Param); ok && param.Name == v.Name && param.Description != "" {
+			return &Doc{Description: param.Description}
+		}
+	}
+	return nil
+}
+
+// classFieldDoc returns the description of a field from the `@field` annotation that declares it.
+func classFieldDoc(class *Named, name string) *Doc {
+	for _, decl := range class.decls {
+		for _, field := range decl.fields {
+			if field.Name == name && field.Description != "" {
+				return &Doc{Description: field.Description}
+			}
+		}
+	}
+	return nil
+}
+
+// declaringStatement returns the statement that contains the given node along with its file. The given file is
+// searched first, as it usually declares the node, and every other file is searched if it does not.
+func (e *Environment) declaringStatement(file *ast.File, node ast.Node) (*ast.File, ast.Statement) {
+	if file != nil {
+		if stmt := statementOf(file, node); stmt != nil {
+			return file, stmt
+		}
+	}
+	for _, other := range e.Files {
+		if other == file {
+			continue
+		}
+		if stmt := statementOf(other, node); stmt != nil {
+			return other, stmt
+		}
+	}
+	return nil, nil
+}
+
+// statementOf returns the innermost statement of the file that contains the given node, or nil if the node is not
+// part of the file.
+func statementOf(file *ast.File, node ast.Node) ast.Statement {
+	if file.Block == nil {
+		return nil
+	}
+	path := ast.GetSemanticNode(file.Block, node.Pos())
+	nodes := append(path.Parents, path.Node)
+	i := slices.Index(nodes, node)
+	if i < 0 {
+		return nil
+	}
+	for ; i >= 0; i-- {
+		if stmt, ok := nodes[i].(ast.Statement); ok {
+			return stmt
+		}
+	}
+	return nil
+}
+
+// isUpvalue returns whether the identifier at the end of the path refers to the variable from within a function
+// nested inside of the scope that declares it.
+func isUpvalue(v *Variable, path ast.NodePath) bool {
+	ident := path.Node
+	for i := len(path.Parents) - 1; i >= 0; i-- {
+		parent := path.Parents[i]
+		if parent == v.Scope.Node {
+			return false
+		}
+		switch parent := parent.(type) {
+		case *ast.FunctionExpression:
+			return true
+		case *ast.FunctionStatement:
+			// The name of a function statement is outside of the function
+			if ident.Pos() >= parent.Name.End() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isMethod returns whether the type is a function that takes `self` as its first parameter.
+func isMethod(typ Type) bool {
+	fn, ok := First(typ).(*Function)
+	return ok && len(fn.Params) > 0 && fn.Params[0].Name == "self"
+}
+
+// expressionName returns the name of a variable or a chain of fields, such as `foo.bar`, or an empty string if the
+// expression is more complex.
+func expressionName(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		return expr.Token.Literal
+	case *ast.IndexExpression:
+		inner, ok := expr.Inner.(*ast.Identifier)
+		if !ok || expr.LeftIndexer.Type() == token.LBRACK {
+			return ""
+		}
+		if prefix := expressionName(expr.Prefix); prefix != "" {
+			return prefix + "." + inner.Token.Literal
+		}
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolAt(t *testing.T) {
+	tests := []struct {
+		label       string
+		input       string // The cursor is at the `|`
+		kind        SymbolKind
+		name        string
+		typ         string
+		description string
+	}{
+		{"local", "local x = 1 print(|x)", SymbolLocal, "x", "number", ""},
+		{"local declaration", "local |x = 'foo'", SymbolLocal, "x", "string", ""},
+		{"documented local", "--- The answer\nlocal x = 42 print(|x)", SymbolLocal, "x", "number", "The answer"},
+		{"multi-line description", "--- First\n---\n--- Second\n---@type number\nlocal |x", SymbolLocal, "x", "number", "First\n\nSecond"},
+		{"separated comment", "--- Unrelated\n\n--- Related\nlocal |x = 1", SymbolLocal, "x", "number", "Related"},
+		{"parameter", "---@param a string The name\nlocal function f(a) return |a end", SymbolParameter, "a", "string", "The name"},
+		{"self", "local t = {} function t:get() return |self end", SymbolParameter, "self", "{get: function(self: {...}) → {...}}", ""},
+		{"upvalue", "local x = 1 local function f() return |x end", SymbolUpvalue, "x", "number", ""},
+		{"recursive upvalue", "local function f() return |f() end", SymbolUpvalue, "f", "function() → unknown", ""},
+		{"not an upvalue", "local t = {} function |t.foo() end", SymbolLocal, "t", "{foo: function()}", ""},
+		{"global", "--- Says hello\nfunction greet() end |greet()", SymbolGlobal, "greet", "function()", "Says hello"},
+		{"stdlib global", "|print('foo')", SymbolGlobal, "print", "function(...)", "Converts every argument to a string and prints them to `stdout`."},
+		{"field", "local t = {}\n--- The count\nt.count = 1 print(t.|count)", SymbolField, "count", "number", "The count"},
+		{"table key", "local t = {|count = 1}", SymbolField, "count", "number", ""},
+		{"method", "local t = {}\n--- Gets it\nfunction t:get() return 1 end t:|get()", SymbolMethod, "get", "function(self: {get: function}) → number", "Gets it"},
+		{"class field", "---@class Point\n---@field x number The x coordinate\n---@type Point\nlocal p print(p.|x)", SymbolField, "x", "number", "The x coordinate"},
+		{"class in annotation", "--- A point\n---@class Point\n---@field x number\n---@type |Point\nlocal p", SymbolClass, "Point", "Point", "A point"},
+		{"class declaration", "---@class |Point\nlocal p = {}", SymbolClass, "Point", "Point", ""},
+		{"alias", "---@alias Id string\n---@param id |Id\nlocal function f(id) end", SymbolAlias, "Id", "Id", ""},
+		{"operator", "local v = setmetatable({}, {__add = function(a, b) return 'sum' end}) local x = v |+ 1", SymbolOperator, "__add", "function(a: unknown, b: unknown) → string", ""},
+		{"prefix operator", "local v = setmetatable({}, {__len = function(a) return 1 end}) local x = |#v", SymbolOperator, "__len", "function(a: unknown) → number", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			symbol := symbolAtCursor(t, test.input)
+			require.NotNil(t, symbol)
+			assert.Equal(t, test.kind, symbol.Kind)
+			assert.Equal(t, test.name, symbol.Name)
+			assert.Equal(t, test.typ, symbol.Type.String())
+			description := ""
+			if symbol.Doc != nil {
+				description = symbol.Doc.Description
+			}
+			assert.Equal(t, test.description, description)
+		})
+	}
+}
+
+func TestSymbolAtNothing(t *testing.T) {
+	tests := []struct {
+		label string
+		input string
+	}{
+		{"keyword", "|local x = 1"},
+		{"plain operator", "local x = 1 |+ 2"},
+		{"plain string", "local x = |'foo'"},
+		{"annotation keyword", "---@|class Point\nlocal p = {}"},
+		{"unknown type", "---@type |Foo\nlocal x"},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			assert.Nil(t, symbolAtCursor(t, test.input))
+		})
+	}
+}
+
+func TestSymbolDeprecated(t *testing.T) {
+	symbol := symbolAtCursor(t, "---@deprecated Use bar instead\nlocal function foo() end |foo()")
+	require.NotNil(t, symbol)
+	require.NotNil(t, symbol.Doc)
+	require.NotNil(t, symbol.Doc.Deprecated)
+	assert.Equal(t, "Use bar instead", symbol.Doc.Deprecated.Message)
+}
+
+func TestSymbolModule(t *testing.T) {
+	main := "local util = require('foo.util')"
+	env := workspace(t, map[string]string{
+		"main.lua":     main,
+		"foo/util.lua": "return {answer = 42}",
+	})
+	file := workspaceFile(t, env, "main.lua")
+	symbol := env.SymbolAt(file, strings.Index(main, "foo.util"))
+	require.NotNil(t, symbol)
+	assert.Equal(t, SymbolModule, symbol.Kind)
+	assert.Equal(t, "foo.util", symbol.Name)
+	assert.Equal(t, "{answer: number}", symbol.Type.String())
+	assert.Equal(t, workspaceFile(t, env, "foo/util.lua").URI, symbol.Module)
+}
+
+// symbolAtCursor returns the symbol at the position of the `|` in the input, which is removed before parsing.
+func symbolAtCursor(t *testing.T, input string) *Symbol {
+	pos := strings.Index(input, "|")
+	require.GreaterOrEqual(t, pos, 0)
+	env, file := analyze(t, input[:pos]+input[pos+1:])
+	return env.SymbolAt(file, pos)
+}
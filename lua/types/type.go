@@ -17,12 +17,25 @@ type (
 	Boolean  struct{}
 	Function struct {
 		Params []NameAndType
+		Vararg bool
 		Return Type
 	}
+	Nil    struct{}
 	Number struct{}
 	String struct{}
 	Table  struct {
-		Fields []NameAndType
+		Fields    []NameAndType
+		Key       Type // The type of the keys of a table declared as `table<K, V>` or `V[]`
+		Value     Type // The type of the values of a table declared as `table<K, V>` or `V[]`
+		Metatable *Table
+		Instance  *Table // If this table is used as a metatable for other tables, the latest of those tables
+	}
+	// Tuple is the result of an expression that produces multiple values, such as a function call.
+	Tuple struct {
+		Types []Type
+	}
+	Union struct {
+		Types []Type
 	}
 	Unknown struct{}
 )
@@ -30,43 +43,43 @@ type (
 func (a *Any) isType()      {}
 func (b *Boolean) isType()  {}
 func (f *Function) isType() {}
+func (n *Nil) isType()      {}
 func (n *Number) isType()   {}
 func (s *String) isType()   {}
 func (t *Table) isType()    {}
+func (t *Tuple) isType()    {}
+func (u *Union) isType()    {}
 func (u *Unknown) isType()  {}
 
-func (b *Any) String() string     { return "any" }
-func (b *Boolean) String() string { return "boolean" }
-func (f *Function) String() string {
-	output := "function("
-	for _, param := range f.Params {
-		output = output + param.String() + ", "
-	}
-	if len(f.Params) > 0 {
-		output = output[0 : len(output)-2]
-	}
-	output = output + ")"
-	if f.Return != nil {
-		output = output + " → " + f.Return.String()
-	}
-	return output
-}
-func (n *Number) String() string { return "number" }
-func (s *String) String() string { return "string" }
-func (t *Table) String() string {
-	var sb strings.Builder
-	sb.WriteByte('{')
-	for i := 0; i < len(t.Fields); i++ {
-		if i > 0 {
-			fmt.Fprint(&sb, ", ")
+func (b *Any) String() string      { return "any" }
+func (b *Boolean) String() string  { return "boolean" }
+func (f *Function) String() string { return format(f, map[Type]bool{}) }
+func (n *Nil) String() string      { return "nil" }
+func (n *Number) String() string   { return "number" }
+func (s *String) String() string   { return "string" }
+func (t *Table) String() string    { return format(t, map[Type]bool{}) }
+func (t *Tuple) String() string    { return format(t, map[Type]bool{}) }
+func (u *Union) String() string    { return format(u, map[Type]bool{}) }
+func (u *Unknown) String() string  { return "unknown" }
+
+// Field returns the field with the given name, or nil if it does not exist.
+func (t *Table) Field(name string) *NameAndType {
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i]
 		}
-		fmt.Fprintf(&sb, "%s", &t.Fields[i])
 	}
-	sb.WriteByte('}')
+	return nil
+}
 
-	return sb.String()
+// SetField adds the given field to the table, or overwrites the type of an existing field.
+func (t *Table) SetField(field NameAndType) {
+	if existing := t.Field(field.Name); existing != nil {
+		existing.Type = field.Type
+		return
+	}
+	t.Fields = append(t.Fields, field)
 }
-func (u *Unknown) String() string { return "unknown" }
 
 type NameAndType struct {
 	Name string
@@ -75,9 +88,98 @@ type NameAndType struct {
 }
 
 func (n *NameAndType) String() string {
+	return n.format(map[Type]bool{})
+}
+
+func (n *NameAndType) format(seen map[Type]bool) string {
 	typ := n.Type
 	if typ == nil {
 		typ = &Unknown{}
 	}
-	return fmt.Sprintf("%s: %s", n.Name, n.Type)
+	return fmt.Sprintf("%s: %s", n.Name, format(typ, seen))
+}
+
+// format converts the given type to a string. Composite types that have already been seen are abbreviated, so
+// self-referential tables do not recurse infinitely.
+func format(typ Type, seen map[Type]bool) string {
+	switch typ := typ.(type) {
+	case *Function:
+		if seen[typ] {
+			return "function"
+		}
+		seen[typ] = true
+		defer delete(seen, typ)
+		output := "function("
+		for _, param := range typ.Params {
+			output = output + param.format(seen) + ", "
+		}
+		if typ.Vararg {
+			output = output + "..., "
+		}
+		if len(typ.Params) > 0 || typ.Vararg {
+			output = output[0 : len(output)-2]
+		}
+		output = output + ")"
+		if typ.Return != nil {
+			output = output + " → " + format(typ.Return, seen)
+		}
+		return output
+	case *Table:
+		if seen[typ] {
+			return "{...}"
+		}
+		seen[typ] = true
+		defer delete(seen, typ)
+		if typ.Value != nil && len(typ.Fields) == 0 {
+			return formatMap(typ, seen)
+		}
+		var sb strings.Builder
+		sb.WriteByte('{')
+		for i := 0; i < len(typ.Fields); i++ {
+			if i > 0 {
+				fmt.Fprint(&sb, ", ")
+			}
+			fmt.Fprint(&sb, typ.Fields[i].format(seen))
+		}
+		if typ.Value != nil {
+			if len(typ.Fields) > 0 {
+				fmt.Fprint(&sb, ", ")
+			}
+			fmt.Fprintf(&sb, "[%s]: %s", format(typ.Key, seen), format(typ.Value, seen))
+		}
+		sb.WriteByte('}')
+		return sb.String()
+	case *Tuple:
+		parts := make([]string, len(typ.Types))
+		for i, member := range typ.Types {
+			parts[i] = format(member, seen)
+		}
+		return strings.Join(parts, ", ")
+	case *Union:
+		parts := make([]string, len(typ.Types))
+		for i, member := range typ.Types {
+			parts[i] = format(member, seen)
+		}
+		return strings.Join(parts, "|")
+	}
+	return typ.String()
+}
+
+// formatMap converts a table that only has a key and value type to a string, such as `string[]` or
+// `table<string, number>`.
+func formatMap(table *Table, seen map[Type]bool) string {
+	_, anyKey := table.Key.(*Any)
+	_, anyValue := table.Value.(*Any)
+	if anyKey && anyValue {
+		return "table"
+	}
+	if _, ok := table.Key.(*Number); ok {
+		value := format(table.Value, seen)
+		switch table.Value.(type) {
+		case *Function, *Union:
+			value = "(" + value + ")"
+		}
+		return value + "[]"
+	}
+	return fmt.Sprintf("table<%s, %s>", format(table.Key, seen), format(table.Value, seen))
 }
@@ -0,0 +1,162 @@
+package types
+
+// NewUnion returns the union of the given types. Nested unions are flattened and duplicate members are removed. If
+// only one distinct type remains, it is returned directly.
+func NewUnion(types ...Type) Type {
+	members := []Type{}
+	var add func(typ Type)
+	add = func(typ Type) {
+		if typ == nil {
+			return
+		}
+		if union, ok := typ.(*Union); ok {
+			for _, member := range union.Types {
+				add(member)
+			}
+			return
+		}
+		for _, member := range members {
+			if Identical(member, typ) {
+				return
+			}
+		}
+		members = append(members, typ)
+	}
+	for _, typ := range types {
+		add(typ)
+	}
+	switch len(members) {
+	case 0:
+		return &Unknown{}
+	case 1:
+		return members[0]
+	}
+	// Unknown and any absorb everything else
+	for _, member := range members {
+		switch member.(type) {
+		case *Any, *Unknown:
+			return member
+		}
+	}
+	return &Union{Types: members}
+}
+
+// Identical returns whether a and b represent the same type. Primitive types are compared by kind, while tables and
+// functions are compared by identity.
+func Identical(a, b Type) bool {
+	switch a := a.(type) {
+	case *Any, *Boolean, *Nil, *Number, *String, *Unknown:
+		return sameKind(a, b)
+	case *Named:
+		b, ok := b.(*Named)
+		return ok && a.Name == b.Name
+	case *Tuple:
+		b, ok := b.(*Tuple)
+		if !ok || len(a.Types) != len(b.Types) {
+			return false
+		}
+		for i := range a.Types {
+			if !Identical(a.Types[i], b.Types[i]) {
+				return false
+			}
+		}
+		return true
+	case *Union:
+		b, ok := b.(*Union)
+		if !ok || len(a.Types) != len(b.Types) {
+			return false
+		}
+		for _, member := range a.Types {
+			if !unionContains(b, member) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func sameKind(a, b Type) bool {
+	switch a.(type) {
+	case *Any:
+		_, ok := b.(*Any)
+		return ok
+	case *Boolean:
+		_, ok := b.(*Boolean)
+		return ok
+	case *Nil:
+		_, ok := b.(*Nil)
+		return ok
+	case *Number:
+		_, ok := b.(*Number)
+		return ok
+	case *String:
+		_, ok := b.(*String)
+		return ok
+	case *Unknown:
+		_, ok := b.(*Unknown)
+		return ok
+	}
+	return false
+}
+
+func unionContains(u *Union, typ Type) bool {
+	for _, member := range u.Types {
+		if Identical(member, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// First returns the first value of the given type. This is used when an expression that may produce multiple values
+// is used in a context that only accepts one.
+func First(typ Type) Type {
+	if tuple, ok := typ.(*Tuple); ok {
+		if len(tuple.Types) == 0 {
+			return &Nil{}
+		}
+		return tuple.Types[0]
+	}
+	return typ
+}
+
+// Truthy returns the subset of the given type that may be truthy, or nil if it is never truthy.
+func Truthy(typ Type) Type {
+	return filterUnion(typ, func(member Type) bool {
+		_, isNil := member.(*Nil)
+		return !isNil
+	})
+}
+
+// Falsy returns the subset of the given type that may be falsy, or nil if it is never falsy.
+func Falsy(typ Type) Type {
+	return filterUnion(typ, func(member Type) bool {
+		switch member.(type) {
+		case *Nil, *Boolean, *Any, *Unknown:
+			return true
+		}
+		return false
+	})
+}
+
+func filterUnion(typ Type, keep func(Type) bool) Type {
+	kept := []Type{}
+	for _, member := range members(typ) {
+		if keep(member) {
+			kept = append(kept, member)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return NewUnion(kept...)
+}
+
+// members returns the members of the given union, or the type itself if it is not a union.
+func members(typ Type) []Type {
+	if union, ok := typ.(*Union); ok {
+		return union.Types
+	}
+	return []Type{typ}
+}
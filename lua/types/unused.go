@@ -0,0 +1,324 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/cfg"
+)
+
+// Diagnostic codes reported for unused code.
+const (
+	CodeUnusedAssignment = "unused-assignment"
+	CodeUnusedFunction   = "unused-function"
+	CodeUnusedLabel      = "unused-label"
+	CodeUnusedLocal      = "unused-local"
+	CodeUnusedParameter  = "unused-parameter"
+)
+
+// unusedChecker finds local variables, parameters, labels and assigned values that are never used.
+type unusedChecker struct {
+	a          *Analysis
+	functionOf map[*ast.Identifier]ast.Node // Identifier -> the function that contains it, or nil for the main chunk
+	bodies     []functionBody
+	labels     map[ast.Node][]*ast.LabelStatement
+	gotos      map[ast.Node]map[string]bool
+	diags      diagnosticList
+}
+
+type functionBody struct {
+	node  ast.Node // The function, or nil for the main chunk
+	block *ast.Block
+}
+
+// event is a read or write of a local variable.
+type event struct {
+	v      *Variable
+	ident  *ast.Identifier
+	write  bool
+	report bool // The written value should be reported if it is never read
+}
+
+// checkUnused reports local variables, parameters and labels that are never used, and assignments whose value is
+// always overwritten before it is read. Names that start with `_` are never reported.
+func (e *Environment) checkUnused(a *Analysis) []ast.Diagnostic {
+	if e.isLibrary(a.File.URI) || a.File.Block == nil {
+		return nil
+	}
+	u := unusedChecker{
+		a:          a,
+		functionOf: map[*ast.Identifier]ast.Node{},
+		labels:     map[ast.Node][]*ast.LabelStatement{},
+		gotos:      map[ast.Node]map[string]bool{},
+	}
+	u.collect(a.File.Block, nil)
+	u.variables()
+	for _, body := range u.bodies {
+		u.deadStores(body)
+	}
+	u.unusedLabels()
+	return u.diags
+}
+
+// collect records which function every identifier, label and goto belongs to.
+func (u *unusedChecker) collect(block *ast.Block, fn ast.Node) {
+	u.bodies = append(u.bodies, functionBody{fn, block})
+	if u.gotos[fn] == nil {
+		u.gotos[fn] = map[string]bool{}
+	}
+	var visit func(node ast.Node) bool
+	visit = func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionExpression:
+			u.function(node, &node.Params, &node.Body)
+			return false
+		case *ast.FunctionStatement:
+			ast.WalkSemantic(node.Name, visit)
+			u.function(node, &node.Params, &node.Body)
+			return false
+		case *ast.ForStatement:
+			u.functionOf[node.Name] = fn
+		case *ast.GotoStatement:
+			if node.Name != nil {
+				u.gotos[fn][node.Name.Token.Literal] = true
+			}
+			return false
+		case *ast.Identifier:
+			u.functionOf[node] = fn
+		case *ast.LabelStatement:
+			u.labels[fn] = append(u.labels[fn], node)
+			return false
+		}
+		return true
+	}
+	ast.WalkSemantic(block, visit)
+}
+
+func (u *unusedChecker) function(fn ast.Node, params *ast.Punctuated[*ast.Identifier], body *ast.Block) {
+	for _, pair := range params.Pairs {
+		u.functionOf[pair.Node] = fn
+	}
+	u.collect(body, fn)
+}
+
+// isRead returns whether the identifier reads the value of its variable.
+func (u *unusedChecker) isRead(v *Variable, ident *ast.Identifier) bool {
+	if u.a.writes[ident] {
+		return false
+	}
+	// Recursive calls do not count as uses of a local function
+	if stmt, ok := v.Decl.(*ast.FunctionStatement); ok && v.Kind == KindLocal && stmt.Body.Pos() <= ident.Pos() && ident.Pos() < stmt.End() {
+		return false
+	}
+	return true
+}
+
+// hasReads returns whether the variable is read anywhere.
+func (u *unusedChecker) hasReads(v *Variable) bool {
+	for _, ref := range v.Refs {
+		if u.isRead(v, ref) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *unusedChecker) variables() {
+	for _, v := range u.a.Variables {
+		if v.Def == nil || strings.HasPrefix(v.Name, "_") || u.hasReads(v) {
+			continue
+		}
+		switch {
+		case v.Kind == KindParameter && v.Name == "self":
+			// An explicit `self` is kept so that the function can be called as a method
+		case v.Kind == KindParameter:
+			u.diags.addUnnecessary(ast.Range(v.Def), CodeUnusedParameter, fmt.Sprintf("Unused parameter '%s'", v.Name))
+		case isLocalFunction(v):
+			u.diags.addUnnecessary(ast.Range(v.Def), CodeUnusedFunction, fmt.Sprintf("Unused local function '%s'", v.Name))
+		default:
+			u.diags.addUnnecessary(ast.Range(v.Def), CodeUnusedLocal, fmt.Sprintf("Unused local '%s'", v.Name))
+		}
+	}
+}
+
+// isLocalFunction returns whether the variable was declared by a local function statement, or a local statement
+// that assigns it a function expression.
+func isLocalFunction(v *Variable) bool {
+	switch decl := v.Decl.(type) {
+	case *ast.FunctionStatement:
+		return true
+	case *ast.LocalStatement:
+		if decl.Exps == nil {
+			return false
+		}
+		for i, pair := range decl.Names.Pairs {
+			if pair.Node == v.Def && i < len(decl.Exps.Pairs) {
+				_, ok := decl.Exps.Pairs[i].Node.(*ast.FunctionExpression)
+				return ok
+			}
+		}
+	}
+	return false
+}
+
+// deadStores reports assignments to local variables whose value is overwritten on every path before it is read.
+// Variables that are captured by closures are skipped, because the closure may read them at any time.
+func (u *unusedChecker) deadStores(body functionBody) {
+	tracked := map[*Variable]bool{}
+	for _, v := range u.a.Variables {
+		if v.Def == nil || strings.HasPrefix(v.Name, "_") || u.functionOf[v.Def] != body.node || !u.hasReads(v) {
+			continue
+		}
+		captured := false
+		for _, ref := range v.Refs {
+			if u.functionOf[ref] != body.node {
+				captured = true
+				break
+			}
+		}
+		if !captured {
+			tracked[v] = true
+		}
+	}
+	if len(tracked) == 0 {
+		return
+	}
+
+	g := cfg.New(body.block)
+	events := map[*cfg.Block][]event{}
+	for _, b := range g.Blocks {
+		for _, node := range b.Nodes {
+			for _, ev := range u.events(node) {
+				if tracked[ev.v] {
+					events[b] = append(events[b], ev)
+				}
+			}
+		}
+	}
+
+	// Compute the variables that are live at the start of each block, iterating until nothing changes
+	liveIn := map[*cfg.Block]map[*Variable]bool{}
+	liveOut := func(b *cfg.Block) map[*Variable]bool {
+		live := map[*Variable]bool{}
+		for _, edge := range b.Succs {
+			for v := range liveIn[edge.To] {
+				live[v] = true
+			}
+		}
+		return live
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(g.Blocks) - 1; i >= 0; i-- {
+			b := g.Blocks[i]
+			live := liveOut(b)
+			evs := events[b]
+			for j := len(evs) - 1; j >= 0; j-- {
+				if evs[j].write {
+					delete(live, evs[j].v)
+				} else {
+					live[evs[j].v] = true
+				}
+			}
+			// Live sets only ever grow, so comparing their sizes is enough
+			if len(live) != len(liveIn[b]) {
+				changed = true
+			}
+			liveIn[b] = live
+		}
+	}
+
+	for _, b := range g.Blocks {
+		if !b.Reachable {
+			continue
+		}
+		live := liveOut(b)
+		evs := events[b]
+		for j := len(evs) - 1; j >= 0; j-- {
+			ev := evs[j]
+			if ev.write && ev.report && !live[ev.v] {
+				u.diags.addUnnecessary(ast.Range(ev.ident), CodeUnusedAssignment, fmt.Sprintf("Value assigned to '%s' is never read", ev.v.Name))
+			}
+			live[ev.v] = !ev.write
+		}
+	}
+}
+
+// events returns the reads and writes of local variables performed by a node of a control-flow graph, in the order
+// that they happen. The bodies of nested functions are not included.
+func (u *unusedChecker) events(node ast.Node) []event {
+	events := []event{}
+	reads := func(node ast.Node) {
+		if node == nil {
+			return
+		}
+		ast.WalkSemantic(node, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.FunctionExpression:
+				return false
+			case *ast.Identifier:
+				if v := u.a.Bindings[node]; v != nil && !u.a.writes[node] {
+					events = append(events, event{v: v, ident: node})
+				}
+			}
+			return true
+		})
+	}
+	write := func(ident *ast.Identifier, report bool) {
+		if v := u.a.Bindings[ident]; v != nil {
+			events = append(events, event{v: v, ident: ident, write: true, report: report})
+		}
+	}
+	switch node := node.(type) {
+	case *ast.AssignmentStatement:
+		reads(&node.Exps)
+		for _, pair := range node.Vars.Pairs {
+			if ident, ok := pair.Node.(*ast.Identifier); ok {
+				write(ident, true)
+			} else {
+				reads(pair.Node)
+			}
+		}
+	case *ast.ForInStatement:
+		reads(&node.Exps)
+		for _, pair := range node.Names.Pairs {
+			write(pair.Node, false)
+		}
+	case *ast.ForStatement:
+		reads(node.Start.Node)
+		reads(node.Finish.Node)
+		if node.Step != nil {
+			reads(node.Step.Node)
+		}
+		write(node.Name, false)
+	case *ast.FunctionStatement:
+		if ident, ok := node.Name.(*ast.Identifier); ok {
+			write(ident, true)
+		} else {
+			reads(node.Name)
+		}
+	case *ast.LocalStatement:
+		// A declaration without a value does not store anything
+		if node.Exps != nil {
+			reads(node.Exps)
+			for _, pair := range node.Names.Pairs {
+				write(pair.Node, true)
+			}
+		}
+	default:
+		reads(node)
+	}
+	return events
+}
+
+func (u *unusedChecker) unusedLabels() {
+	for _, body := range u.bodies {
+		for _, label := range u.labels[body.node] {
+			if label.Name == nil || u.gotos[body.node][label.Name.Token.Literal] {
+				continue
+			}
+			u.diags.addUnnecessary(ast.Range(label), CodeUnusedLabel, fmt.Sprintf("Unused label '%s'", label.Name.Token.Literal))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnused(t *testing.T) {
+	tests := []struct {
+		label    string
+		input    string
+		expected []string
+	}{
+		{"used", "local x = 1 print(x)", nil},
+		{"unused local", "local x, _y = 1, 2", []string{"Unused local 'x'"}},
+		{"written only", "local x = 1 x = 2", []string{"Unused local 'x'"}},
+		{"parameters", "local function f(a, _b, c) return c end f()", []string{"Unused parameter 'a'"}},
+		{"self", "local t = {} function t:f() end", nil},
+		{"explicit self", "local t = {} function t.f(self) end return t", nil},
+		{"local function", "local function f() end local g = function() end", []string{"Unused local function 'f'", "Unused local function 'g'"}},
+		{"recursive function", "local function f(n) return f(n - 1) end", []string{"Unused local function 'f'"}},
+		{"loop variable", "for i = 1, 10 do end for _, v in pairs({}) do print(v) end", []string{"Unused local 'i'"}},
+		{"overwritten", "local x = 1 x = 2 print(x)", []string{"Value assigned to 'x' is never read"}},
+		{"read before overwrite", "local x = 1 print(x) x = 2 print(x)", nil},
+		{"overwritten in one branch", "local x = 1 if c then x = 2 end print(x)", nil},
+		{"overwritten in every branch", "local x = 1 if c then x = 2 else x = 3 end print(x)", []string{"Value assigned to 'x' is never read"}},
+		{"declaration without value", "local x if c then x = 1 else x = 2 end print(x)", nil},
+		{"loop", "local x = 0 while c do print(x) x = x + 1 end", nil},
+		{"last write", "local x = 1 print(x) x = 2", []string{"Value assigned to 'x' is never read"}},
+		{"captured", "local x = 1 local function f() return x end x = 2 return f", nil},
+		{"labels", "::used:: if c then goto used end ::unused::", []string{"Unused label 'unused'"}},
+		{"label in nested function", "if c then goto skip end local f = function() ::skip:: end ::skip:: return f", []string{"Unused label 'skip'"}},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			env, file := analyze(t, test.input)
+			unused := []string{}
+			for _, diag := range env.Diagnostics(file) {
+				if isUnnecessary(diag) {
+					unused = append(unused, diag.Message)
+				}
+			}
+			if test.expected == nil {
+				test.expected = []string{}
+			}
+			assert.Equal(t, test.expected, unused)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"github.com/raiguard/luapls/lua/ast"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// View gives read access to an environment while it is locked, such as to a Linter. It must not be used after the
+// function that it was passed to has returned.
+type View struct {
+	e *Environment
+}
+
+// View calls fn with a view of the environment, which is locked until fn returns.
+func (e *Environment) View(fn func(v *View)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fn(&View{e})
+}
+
+// File returns the file with the given URI, or nil if it has not been added.
+func (v *View) File(uri protocol.URI) *ast.File {
+	return v.e.Files[uri]
+}
+
+// IsLibrary returns whether the file at the given URI is a library file.
+func (v *View) IsLibrary(uri protocol.URI) bool {
+	return v.e.isLibrary(uri)
+}
+
+// IsAllowedGlobal returns whether the given global is in the configured list of allowed globals.
+func (v *View) IsAllowedGlobal(name string) bool {
+	return v.e.isAllowedGlobal(name)
+}
+
+// IsStdlibGlobal returns whether the given global is defined by the standard library.
+func (v *View) IsStdlibGlobal(name string) bool {
+	return v.e.isStdlibGlobal(name)
+}
+
+// Severity returns the configured severity of the diagnostics with the given code, if it overrides their default.
+func (v *View) Severity(code string) (protocol.DiagnosticSeverity, bool) {
+	severity, ok := v.e.Severities[code]
+	return severity, ok
+}
+
+// The checks below may resolve types and parse pending files, so unlike the other methods of a view, they must not be
+// called from multiple goroutines at once.
+
+// CheckRequires reports modules that could not be resolved and requires that form a cycle.
+func (v *View) CheckRequires(a *Analysis) []ast.Diagnostic {
+	return v.e.checkRequires(a)
+}
+
+// CheckGlobals reports undefined globals and, with strict globals, assignments to undeclared globals.
+func (v *View) CheckGlobals(a *Analysis) []ast.Diagnostic {
+	return v.e.checkGlobals(a)
+}
+
+// CheckUnused reports unused variables, parameters and labels, and values that are never read.
+func (v *View) CheckUnused(a *Analysis) []ast.Diagnostic {
+	return v.e.checkUnused(a)
+}
+
+// CheckFlow reports unreachable code, invalid jumps and functions that may not return their declared values.
+func (v *View) CheckFlow(a *Analysis) []ast.Diagnostic {
+	return v.e.checkFlow(a)
+}
+
+// CheckShadowing reports shadowed locals and redefined local functions.
+func (v *View) CheckShadowing(a *Analysis) []ast.Diagnostic {
+	return v.e.checkShadowing(a)
+}
+
+// CheckTypes reports values that do not match the types declared by annotations, and unknown fields of classes.
+func (v *View) CheckTypes(a *Analysis) []ast.Diagnostic {
+	return v.e.checkTypes(a)
+}
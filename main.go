@@ -2,20 +2,28 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/raiguard/luapls/cache"
+	"github.com/raiguard/luapls/config"
 	"github.com/raiguard/luapls/lsp"
 	"github.com/raiguard/luapls/lua/ast"
 	"github.com/raiguard/luapls/lua/lexer"
+	"github.com/raiguard/luapls/lua/lint"
 	"github.com/raiguard/luapls/lua/parser"
 	"github.com/raiguard/luapls/lua/token"
 	"github.com/raiguard/luapls/lua/types"
 	"github.com/raiguard/luapls/repl"
-	"github.com/tliron/kutil/util"
+	"github.com/raiguard/luapls/report"
+	"github.com/raiguard/luapls/util"
+	kutil "github.com/tliron/kutil/util"
 )
 
 func main() {
@@ -53,12 +61,16 @@ func main() {
 	case "repl":
 		repl.Run()
 	case "check":
-		check()
+		check(args[2:])
+	case "lint":
+		lintFiles(args[2:])
+	case "cache":
+		cacheCommand(args[2:])
 	default:
 		fmt.Fprintf(os.Stderr, "%s: unrecognized subcommand\n", task)
 	}
 
-	util.Exit(0)
+	kutil.Exit(0)
 }
 
 func lexFile(filename string) {
@@ -136,24 +148,166 @@ func readOrMakeSpecs(path string) []testSpec {
 	return specs
 }
 
-func check() {
+// check reports the diagnostics of the given files, or the Lua files in the given directories, which default to the
+// working directory. It exits with 1 if a diagnostic is more severe than allowed by --max-severity.
+func check(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: "+strings.Join(report.Formats, ", "))
+	maxSeverity := flags.String("max-severity", "warning", "most severe diagnostic that does not fail the check: error, warning, information, hint or off")
+	configPath := flags.String("config", "", "configuration file to use instead of "+strings.Join(config.FileNames, " or "))
+	flags.Parse(args)
+	if !slices.Contains(report.Formats, *format) {
+		fmt.Fprintf(os.Stderr, "Unknown format '%s'\n", *format)
+		os.Exit(2)
+	}
+	threshold, ok := types.ParseSeverity(*maxSeverity)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown severity '%s'\n", *maxSeverity)
+		os.Exit(2)
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
 	env := types.NewEnvironment()
-	if env == nil {
-		panic("Failed to initialize environment")
+	env.RootPath = "."
+	applyConfig(env, *configPath)
+	env.AddLibraries()
+	files, err := addPaths(env, paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, err := range env.DiscoveryErrors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	env.CheckPhase1()
+	env.CheckPhase2()
+
+	cwd, _ := os.Getwd()
+	diagnostics := []report.Diagnostic{}
+	seen := map[*ast.File]bool{}
+	for _, file := range files {
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+		diagnostics = append(diagnostics, report.FromFile(file, env.Report(file), cwd)...)
+	}
+	report.Sort(diagnostics)
+	if err := report.Write(os.Stdout, *format, diagnostics); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, diag := range diagnostics {
+		if threshold == types.SeverityOff || diag.Severity < threshold {
+			kutil.Exit(1)
+		}
+	}
+}
+
+// addPaths adds the given files, and the Lua files in the given directories, to the environment and returns them. The
+// paths only choose which files are reported, so modules are still resolved relative to the roots of the environment.
+func addPaths(env *types.Environment, paths []string) ([]*ast.File, error) {
+	files := []*ast.File{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			files = append(files, env.AddDirectory(path)...)
+			continue
+		}
+		uri, err := util.PathToURI(path)
+		if err != nil {
+			return nil, err
+		}
+		if file := env.AddFile(uri); file != nil {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// lintFiles runs the lint rules with the given names, or every rule if none are given, over the files in the current
+// directory.
+func lintFiles(names []string) {
+	for _, name := range names {
+		if lint.Lookup(name) == nil {
+			fmt.Fprintf(os.Stderr, "Unknown lint rule '%s'\n", name)
+			os.Exit(1)
+		}
 	}
+	env := types.NewEnvironment()
 	env.RootPath = "."
+	applyConfig(env, "")
 	env.Init()
-	fmt.Println("TYPES:")
-	for typName := range env.Types {
-		fmt.Printf("    %s\n", typName)
-	}
-	fmt.Println("DIAGNOSTICS:")
-	for uri, file := range env.Files {
-		if len(file.Diagnostics) > 0 {
-			fmt.Printf("    %s\n", uri)
-			for _, diag := range file.Diagnostics {
-				fmt.Printf("        %s: %s\n", diag.Range.String(), diag.Message)
-			}
+	files := []*ast.File{}
+	for _, file := range env.Files {
+		if !env.IsLibrary(file.URI) {
+			files = append(files, file)
+		}
+	}
+	slices.SortFunc(files, func(a, b *ast.File) int { return strings.Compare(string(a.URI), string(b.URI)) })
+	// Files are analyzed up front, because analysis is not safe to run in parallel
+	analyses := []*types.Analysis{}
+	for _, file := range files {
+		analyses = append(analyses, env.Analysis(file))
+	}
+	// The configuration sets up the linter with the configured severities
+	linter := env.Linter.(*lint.Linter)
+	linter.Only = names
+	cwd, _ := os.Getwd()
+	reported := []report.Diagnostic{}
+	for i, diagnostics := range linter.LintAll(env, analyses) {
+		// Suppressions that target other diagnostics are not used by the linter
+		diagnostics = slices.DeleteFunc(env.Suppress(files[i], diagnostics), func(diag ast.Diagnostic) bool {
+			return diag.Code == types.CodeUnusedSuppression
+		})
+		reported = append(reported, report.FromFile(files[i], diagnostics, cwd)...)
+	}
+	report.Write(os.Stdout, "text", reported)
+	if len(reported) > 0 {
+		kutil.Exit(1)
+	}
+}
+
+// cacheCommand manages the cache of the language server. The only subcommand is `clean`, which removes it.
+func cacheCommand(args []string) {
+	if len(args) != 1 || args[0] != "clean" {
+		fmt.Fprintln(os.Stderr, "Usage: luapls cache clean")
+		os.Exit(2)
+	}
+	dir, err := cache.Dir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := cache.Clean(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed %s\n", dir)
+}
+
+// applyConfig applies the configuration file at the given path, or the project configuration file in the root
+// directory if the path is empty.
+func applyConfig(env *types.Environment, path string) {
+	if path == "" {
+		path = config.Find(env.RootPath)
+	}
+	cfg := config.Config{}
+	if path != "" {
+		var err error
+		cfg, err = config.Load(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read %s: %s\n", path, err)
+			os.Exit(2)
 		}
 	}
+	for _, warning := range cfg.Apply(env) {
+		fmt.Fprintln(os.Stderr, warning)
+	}
 }
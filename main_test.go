@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/raiguard/luapls/lua/lint"
+	"github.com/raiguard/luapls/lua/types"
+	"github.com/raiguard/luapls/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddPaths(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"demos/demo.lua":  "local demo2 = require('demos.demo2')\nprint(demo2.x)",
+		"demos/demo2.lua": "return {x = 1}",
+		"other.lua":       "print(1)",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	env := types.NewEnvironment()
+	env.RootPath = dir
+	env.Linter = &lint.Linter{}
+	files, err := addPaths(env, []string{filepath.Join(dir, "demos")})
+	require.NoError(t, err)
+	env.CheckPhase1()
+	env.CheckPhase2()
+
+	reported := map[string][]string{}
+	for _, file := range files {
+		path, err := util.URIToPath(file.URI)
+		require.NoError(t, err)
+		rel, err := filepath.Rel(dir, path)
+		require.NoError(t, err)
+		messages := []string{}
+		for _, diag := range env.Report(file) {
+			messages = append(messages, diag.Message)
+		}
+		reported[filepath.ToSlash(rel)] = messages
+	}
+	assert.Equal(t, map[string][]string{"demos/demo.lua": {}, "demos/demo2.lua": {}}, reported)
+}
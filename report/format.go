@@ -0,0 +1,245 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// writeText writes one diagnostic per line in the `path:line:column: severity: message [code]` format that most
+// editors and terminals recognize.
+func writeText(w io.Writer, diagnostics []Diagnostic) error {
+	for _, diag := range diagnostics {
+		line := fmt.Sprintf("%s:%d:%d: %s: %s", diag.Path, diag.Line, diag.Column, SeverityName(diag.Severity), diag.Message)
+		if diag.Code != "" {
+			line += fmt.Sprintf(" [%s]", diag.Code)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type jsonDiagnostic struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+}
+
+// writeJSON writes the diagnostics as a JSON array.
+func writeJSON(w io.Writer, diagnostics []Diagnostic) error {
+	output := []jsonDiagnostic{}
+	for _, diag := range diagnostics {
+		output = append(output, jsonDiagnostic{
+			Path:      diag.Path,
+			Line:      diag.Line,
+			Column:    diag.Column,
+			EndLine:   diag.EndLine,
+			EndColumn: diag.EndColumn,
+			Severity:  SeverityName(diag.Severity),
+			Code:      diag.Code,
+			Message:   diag.Message,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// writeSARIF writes the diagnostics as a SARIF 2.1.0 log, which code scanning services can import.
+func writeSARIF(w io.Writer, diagnostics []Diagnostic) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "luapls", Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	seen := map[string]bool{}
+	for _, diag := range diagnostics {
+		if diag.Code != "" && !seen[diag.Code] {
+			seen[diag.Code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: diag.Code})
+		}
+		level := "note"
+		switch diag.Severity {
+		case protocol.DiagnosticSeverityError:
+			level = "error"
+		case protocol.DiagnosticSeverityWarning:
+			level = "warning"
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  diag.Code,
+			Level:   level,
+			Message: sarifMessage{Text: diag.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: diag.Path},
+				Region: sarifRegion{
+					StartLine:   diag.Line,
+					StartColumn: diag.Column,
+					EndLine:     diag.EndLine,
+					EndColumn:   diag.EndColumn,
+				},
+			}}},
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+type checkstyleOutput struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr,omitempty"`
+}
+
+// writeCheckstyle writes the diagnostics in the Checkstyle XML format. The diagnostics of each file must be adjacent.
+func writeCheckstyle(w io.Writer, diagnostics []Diagnostic) error {
+	output := checkstyleOutput{Version: "4.3"}
+	for _, diag := range diagnostics {
+		if len(output.Files) == 0 || output.Files[len(output.Files)-1].Name != diag.Path {
+			output.Files = append(output.Files, checkstyleFile{Name: diag.Path})
+		}
+		severity := "info"
+		switch diag.Severity {
+		case protocol.DiagnosticSeverityError:
+			severity = "error"
+		case protocol.DiagnosticSeverityWarning:
+			severity = "warning"
+		}
+		source := ""
+		if diag.Code != "" {
+			source = "luapls." + diag.Code
+		}
+		file := &output.Files[len(output.Files)-1]
+		file.Errors = append(file.Errors, checkstyleError{
+			Line:     diag.Line,
+			Column:   diag.Column,
+			Severity: severity,
+			Message:  diag.Message,
+			Source:   source,
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(output); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeGitHub writes the diagnostics as GitHub Actions workflow commands, which annotate the lines of a pull request.
+func writeGitHub(w io.Writer, diagnostics []Diagnostic) error {
+	for _, diag := range diagnostics {
+		command := "notice"
+		switch diag.Severity {
+		case protocol.DiagnosticSeverityError:
+			command = "error"
+		case protocol.DiagnosticSeverityWarning:
+			command = "warning"
+		}
+		properties := fmt.Sprintf("file=%s,line=%d,col=%d,endLine=%d,endColumn=%d",
+			escapeProperty(diag.Path), diag.Line, diag.Column, diag.EndLine, diag.EndColumn)
+		if diag.Code != "" {
+			properties += ",title=" + escapeProperty(diag.Code)
+		}
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", command, properties, escapeData(diag.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,100 @@
+// Package report writes diagnostics in formats that other tools can consume, such as CI systems.
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/util"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Formats are the names of the supported output formats.
+var Formats = []string{"text", "json", "sarif", "checkstyle", "github"}
+
+// Diagnostic is a diagnostic of a single file, with one-based line and column numbers.
+type Diagnostic struct {
+	Path      string // Relative to the working directory when possible, with forward slashes
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+	Severity  protocol.DiagnosticSeverity
+	Code      string
+	Message   string
+}
+
+// FromFile converts the diagnostics of the file, making its path relative to the given base directory.
+func FromFile(file *ast.File, diagnostics []ast.Diagnostic, base string) []Diagnostic {
+	path, err := util.URIToPath(file.URI)
+	if err != nil {
+		path = file.URI
+	} else if rel, err := filepath.Rel(base, path); err == nil {
+		path = rel
+	}
+	path = filepath.ToSlash(path)
+	converted := []Diagnostic{}
+	for _, diag := range diagnostics {
+		rng := file.LineBreaks.ToProtocolRange(diag.Range)
+		converted = append(converted, Diagnostic{
+			Path:      path,
+			Line:      int(rng.Start.Line) + 1,
+			Column:    int(rng.Start.Character) + 1,
+			EndLine:   int(rng.End.Line) + 1,
+			EndColumn: int(rng.End.Character) + 1,
+			Severity:  diag.Severity,
+			Code:      diag.Code,
+			Message:   diag.Message,
+		})
+	}
+	return converted
+}
+
+// Sort orders the diagnostics by path and position.
+func Sort(diagnostics []Diagnostic) {
+	slices.SortStableFunc(diagnostics, func(a, b Diagnostic) int {
+		if a.Path != b.Path {
+			return cmp.Compare(a.Path, b.Path)
+		}
+		if a.Line != b.Line {
+			return cmp.Compare(a.Line, b.Line)
+		}
+		return cmp.Compare(a.Column, b.Column)
+	})
+}
+
+// Write writes the diagnostics in the given format.
+func Write(w io.Writer, format string, diagnostics []Diagnostic) error {
+	switch format {
+	case "text":
+		return writeText(w, diagnostics)
+	case "json":
+		return writeJSON(w, diagnostics)
+	case "sarif":
+		return writeSARIF(w, diagnostics)
+	case "checkstyle":
+		return writeCheckstyle(w, diagnostics)
+	case "github":
+		return writeGitHub(w, diagnostics)
+	}
+	return fmt.Errorf("Unknown format '%s'", format)
+}
+
+// SeverityName returns the lowercase name of the severity, as accepted by types.ParseSeverity.
+func SeverityName(severity protocol.DiagnosticSeverity) string {
+	switch severity {
+	case protocol.DiagnosticSeverityError:
+		return "error"
+	case protocol.DiagnosticSeverityWarning:
+		return "warning"
+	case protocol.DiagnosticSeverityInformation:
+		return "information"
+	case protocol.DiagnosticSeverityHint:
+		return "hint"
+	}
+	return "unknown"
+}
@@ -0,0 +1,124 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/raiguard/luapls/lua/ast"
+	"github.com/raiguard/luapls/lua/parser"
+	"github.com/raiguard/luapls/lua/token"
+	"github.com/raiguard/luapls/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+var diagnostics = []Diagnostic{
+	{Path: "src/a.lua", Line: 2, Column: 7, EndLine: 2, EndColumn: 8, Severity: protocol.DiagnosticSeverityError, Code: "undefined-global", Message: "Undefined global 'x'"},
+	{Path: "src/a.lua", Line: 3, Column: 1, EndLine: 3, EndColumn: 4, Severity: protocol.DiagnosticSeverityHint, Message: "50%, done: yes\nnext"},
+}
+
+func TestFromFile(t *testing.T) {
+	file := util.Ptr(parser.New("local a\nlocal b").ParseFile())
+	file.URI = "file:///project/src/a.lua"
+	diags := FromFile(file, []ast.Diagnostic{{
+		Message:  "Unused local 'b'",
+		Range:    token.Range{Start: 14, End: 15},
+		Severity: protocol.DiagnosticSeverityHint,
+		Code:     "unused-local",
+	}}, "/project")
+	assert.Equal(t, []Diagnostic{{
+		Path:      "src/a.lua",
+		Line:      2,
+		Column:    7,
+		EndLine:   2,
+		EndColumn: 8,
+		Severity:  protocol.DiagnosticSeverityHint,
+		Code:      "unused-local",
+		Message:   "Unused local 'b'",
+	}}, diags)
+}
+
+func TestSort(t *testing.T) {
+	diags := []Diagnostic{
+		{Path: "b.lua", Line: 1, Column: 1},
+		{Path: "a.lua", Line: 2, Column: 1},
+		{Path: "a.lua", Line: 1, Column: 5},
+		{Path: "a.lua", Line: 1, Column: 2},
+	}
+	Sort(diags)
+	assert.Equal(t, []Diagnostic{
+		{Path: "a.lua", Line: 1, Column: 2},
+		{Path: "a.lua", Line: 1, Column: 5},
+		{Path: "a.lua", Line: 2, Column: 1},
+		{Path: "b.lua", Line: 1, Column: 1},
+	}, diags)
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"text", `src/a.lua:2:7: error: Undefined global 'x' [undefined-global]
+src/a.lua:3:1: hint: 50%, done: yes
+next
+`},
+		{"json", `[
+  {
+    "path": "src/a.lua",
+    "line": 2,
+    "column": 7,
+    "endLine": 2,
+    "endColumn": 8,
+    "severity": "error",
+    "code": "undefined-global",
+    "message": "Undefined global 'x'"
+  },
+  {
+    "path": "src/a.lua",
+    "line": 3,
+    "column": 1,
+    "endLine": 3,
+    "endColumn": 4,
+    "severity": "hint",
+    "message": "50%, done: yes\nnext"
+  }
+]
+`},
+		{"checkstyle", `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="src/a.lua">
+    <error line="2" column="7" severity="error" message="Undefined global &#39;x&#39;" source="luapls.undefined-global"></error>
+    <error line="3" column="1" severity="info" message="50%, done: yes&#xA;next"></error>
+  </file>
+</checkstyle>
+`},
+		{"github", `::error file=src/a.lua,line=2,col=7,endLine=2,endColumn=8,title=undefined-global::Undefined global 'x'
+::notice file=src/a.lua,line=3,col=1,endLine=3,endColumn=4::50%25, done: yes%0Anext
+`},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, test.format, diagnostics))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "sarif", diagnostics))
+	output := buf.String()
+	assert.Contains(t, output, `"version": "2.1.0"`)
+	assert.Contains(t, output, `"id": "undefined-global"`)
+	assert.Contains(t, output, `"level": "error"`)
+	assert.Contains(t, output, `"level": "note"`)
+	assert.Contains(t, output, `"uri": "src/a.lua"`)
+	assert.Contains(t, output, `"startLine": 2`)
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	assert.Error(t, Write(&bytes.Buffer{}, "yaml", diagnostics))
+}